@@ -13,8 +13,10 @@ import (
 	"shift-app/internal/handler"
 	"shift-app/internal/llm"
 	"shift-app/internal/middleware"
+	"shift-app/internal/model"
 	"shift-app/internal/repository"
 	"shift-app/internal/service"
+	"shift-app/internal/solver"
 	"shift-app/internal/validator"
 )
 
@@ -41,18 +43,42 @@ func main() {
 	patternRepo := repository.NewShiftPatternRepository(pool)
 	entryRepo := repository.NewShiftEntryRepository(pool)
 	jobRepo := repository.NewGenerationJobRepository(pool)
+	attemptRepo := repository.NewGenerationAttemptRepository(pool)
+	calendarRepo := repository.NewBusinessCalendarRepository(pool)
 
-	// LLM & Validator
-	gen := llm.NewGenerator(cfg.AnthropicAPIKey, pool)
-	val := validator.NewShiftValidator(pool)
+	// Generators & Validator
+	provider, err := llm.NewProvider(cfg)
+	if err != nil {
+		log.Fatalf("Unable to configure LLM provider: %v", err)
+	}
+	llmGen := llm.NewGenerator(provider, pool, calendarRepo)
+	localSolver := solver.NewSolver(pool)
+	generators := map[string]service.ShiftGenerator{
+		model.GeneratorLLM:    llmGen,
+		model.GeneratorLocal:  localSolver,
+		model.GeneratorHybrid: llmGen,
+	}
+	val := validator.NewShiftValidator(pool, calendarRepo, cfg.ScoreWeights)
 
 	// Services
 	staffSvc := service.NewStaffService(staffRepo)
 	settingSvc := service.NewStaffMonthlySettingService(settingRepo)
 	requestSvc := service.NewShiftRequestService(requestRepo)
 	constraintSvc := service.NewConstraintService(constraintRepo)
+	calendarSvc := service.NewBusinessCalendarService(calendarRepo)
 	dashboardSvc := service.NewDashboardService(staffRepo, settingRepo, requestRepo, constraintRepo, patternRepo, entryRepo, jobRepo)
-	shiftSvc := service.NewShiftService(patternRepo, entryRepo, jobRepo, staffRepo, gen, val)
+	shiftSvc := service.NewShiftService(patternRepo, entryRepo, jobRepo, attemptRepo, staffRepo, generators, localSolver, val)
+
+	// Generation workers; with WORKER_CONCURRENCY=0 jobs are left to cmd/worker
+	if cfg.WorkerConcurrency > 0 {
+		workers := service.NewGenerationWorkerPool(shiftSvc, service.WorkerOptions{
+			Concurrency:  cfg.WorkerConcurrency,
+			PollInterval: cfg.WorkerPollInterval,
+			StaleAfter:   cfg.JobStaleAfter,
+			ResumeStale:  cfg.ResumeStaleJobs,
+		})
+		go workers.Run(context.Background())
+	}
 
 	// Echo
 	e := echo.New()
@@ -84,6 +110,9 @@ func main() {
 	constraintHandler := handler.NewConstraintHandler(constraintSvc)
 	constraintHandler.RegisterRoutes(api)
 
+	calendarHandler := handler.NewBusinessCalendarHandler(calendarSvc)
+	calendarHandler.RegisterRoutes(api)
+
 	dashboardHandler := handler.NewDashboardHandler(dashboardSvc)
 	dashboardHandler.RegisterRoutes(api)
 
@@ -0,0 +1,75 @@
+// Command worker runs shift generation jobs from the generation_jobs queue
+// without serving the API. Run the API server with WORKER_CONCURRENCY=0 to
+// leave all generation to workers, or alongside its own pool to add capacity.
+package main
+
+import (
+	"context"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"shift-app/internal/config"
+	"shift-app/internal/llm"
+	"shift-app/internal/model"
+	"shift-app/internal/repository"
+	"shift-app/internal/service"
+	"shift-app/internal/solver"
+	"shift-app/internal/validator"
+)
+
+func main() {
+	cfg := config.Load()
+	if cfg.WorkerConcurrency == 0 {
+		cfg.WorkerConcurrency = 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// DB connection
+	pool, err := pgxpool.New(context.Background(), cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	if err := pool.Ping(context.Background()); err != nil {
+		log.Fatalf("Unable to ping database: %v", err)
+	}
+	log.Println("Connected to database")
+
+	// Repositories
+	staffRepo := repository.NewStaffRepository(pool)
+	patternRepo := repository.NewShiftPatternRepository(pool)
+	entryRepo := repository.NewShiftEntryRepository(pool)
+	jobRepo := repository.NewGenerationJobRepository(pool)
+	attemptRepo := repository.NewGenerationAttemptRepository(pool)
+	calendarRepo := repository.NewBusinessCalendarRepository(pool)
+
+	// Generators & Validator
+	provider, err := llm.NewProvider(cfg)
+	if err != nil {
+		log.Fatalf("Unable to configure LLM provider: %v", err)
+	}
+	llmGen := llm.NewGenerator(provider, pool, calendarRepo)
+	localSolver := solver.NewSolver(pool)
+	generators := map[string]service.ShiftGenerator{
+		model.GeneratorLLM:    llmGen,
+		model.GeneratorLocal:  localSolver,
+		model.GeneratorHybrid: llmGen,
+	}
+	val := validator.NewShiftValidator(pool, calendarRepo, cfg.ScoreWeights)
+
+	shiftSvc := service.NewShiftService(patternRepo, entryRepo, jobRepo, attemptRepo, staffRepo, generators, localSolver, val)
+
+	// Run until SIGINT/SIGTERM; running jobs are handed back to the queue
+	service.NewGenerationWorkerPool(shiftSvc, service.WorkerOptions{
+		Concurrency:  cfg.WorkerConcurrency,
+		PollInterval: cfg.WorkerPollInterval,
+		StaleAfter:   cfg.JobStaleAfter,
+		ResumeStale:  cfg.ResumeStaleJobs,
+	}).Run(ctx)
+}
@@ -1,11 +1,44 @@
 package config
 
-import "os"
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"shift-app/internal/model"
+)
 
 type Config struct {
-	Port           string
-	DatabaseURL    string
+	Port            string
+	DatabaseURL     string
 	AnthropicAPIKey string
+
+	// LLMProvider is "anthropic", "openai" (any OpenAI-compatible endpoint)
+	// or "replay" (recorded fixtures, no external service)
+	LLMProvider    string
+	LLMModel       string
+	LLMTemperature float64
+	LLMMaxTokens   int
+	OpenAIBaseURL  string
+	OpenAIAPIKey   string
+	LLMReplayDir   string
+	// LLMRecordDir, when set, saves every LLM reply there as a replay fixture
+	LLMRecordDir string
+
+	// WorkerConcurrency is the number of generation jobs a process runs at
+	// once; 0 leaves the queue to cmd/worker
+	WorkerConcurrency  int
+	WorkerPollInterval time.Duration
+	// JobStaleAfter is how long a processing job may go without a heartbeat
+	// before it counts as orphaned
+	JobStaleAfter time.Duration
+	// ResumeStaleJobs requeues orphaned jobs; when false they are marked failed
+	ResumeStaleJobs bool
+
+	// ScoreWeights weigh the components of a pattern's score unless the
+	// generation job sets its own
+	ScoreWeights model.ObjectiveWeights
 }
 
 func Load() *Config {
@@ -20,8 +53,84 @@ func Load() *Config {
 	}
 
 	return &Config{
-		Port:           port,
-		DatabaseURL:    dbURL,
+		Port:            port,
+		DatabaseURL:     dbURL,
 		AnthropicAPIKey: os.Getenv("ANTHROPIC_API_KEY"),
+		LLMProvider:     getEnv("LLM_PROVIDER", "anthropic"),
+		LLMModel:        getEnv("LLM_MODEL", "claude-sonnet-4-5-20250929"),
+		LLMTemperature:  getEnvFloat("LLM_TEMPERATURE", 0.7),
+		LLMMaxTokens:    getEnvInt("LLM_MAX_TOKENS", 16384, 1),
+		OpenAIBaseURL:   os.Getenv("OPENAI_BASE_URL"),
+		OpenAIAPIKey:    os.Getenv("OPENAI_API_KEY"),
+		LLMReplayDir:    os.Getenv("LLM_REPLAY_DIR"),
+		LLMRecordDir:    os.Getenv("LLM_RECORD_DIR"),
+
+		WorkerConcurrency:  getEnvInt("WORKER_CONCURRENCY", 2, 0),
+		WorkerPollInterval: getEnvDuration("WORKER_POLL_INTERVAL", 2*time.Second),
+		JobStaleAfter:      getEnvDuration("JOB_STALE_AFTER", time.Minute),
+		ResumeStaleJobs:    getEnvBool("RESUME_STALE_JOBS", true),
+
+		ScoreWeights: getEnvWeights(),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return v
+	}
+	return fallback
+}
+
+// getEnvWeights reads the score weights. Weights that aren't valid
+// together, such as all zero, fall back to the defaults.
+func getEnvWeights() model.ObjectiveWeights {
+	weights := model.ObjectiveWeights{
+		HoursFit:        getEnvWeight("SCORE_WEIGHT_HOURS_FIT", model.DefaultObjectiveWeights.HoursFit),
+		Preference:      getEnvWeight("SCORE_WEIGHT_PREFERENCE", model.DefaultObjectiveWeights.Preference),
+		SoftConstraints: getEnvWeight("SCORE_WEIGHT_SOFT_CONSTRAINTS", model.DefaultObjectiveWeights.SoftConstraints),
+		Fairness:        getEnvWeight("SCORE_WEIGHT_FAIRNESS", model.DefaultObjectiveWeights.Fairness),
+		Cost:            getEnvWeight("SCORE_WEIGHT_COST", model.DefaultObjectiveWeights.Cost),
+	}
+	if err := weights.Validate(); err != nil {
+		log.Printf("Invalid SCORE_WEIGHT_* settings, using the defaults: %v", err)
+		return model.DefaultObjectiveWeights
+	}
+	return weights
+}
+
+// getEnvWeight reads a score weight, which can't be negative
+func getEnvWeight(key string, fallback float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && v >= 0 {
+		return v
+	}
+	return fallback
+}
+
+// getEnvInt reads an integer of at least min
+func getEnvInt(key string, fallback, min int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v >= min {
+		return v
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if v, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(key)); err == nil && v > 0 {
+		return v
 	}
+	return fallback
 }
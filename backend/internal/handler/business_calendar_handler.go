@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"shift-app/internal/model"
+	"shift-app/internal/service"
+)
+
+type BusinessCalendarHandler struct {
+	svc *service.BusinessCalendarService
+}
+
+func NewBusinessCalendarHandler(svc *service.BusinessCalendarService) *BusinessCalendarHandler {
+	return &BusinessCalendarHandler{svc: svc}
+}
+
+func (h *BusinessCalendarHandler) RegisterRoutes(g *echo.Group) {
+	g.GET("/business-calendar", h.GetMonth)
+	g.GET("/business-calendar/hours", h.ListHours)
+	g.PUT("/business-calendar/hours", h.UpdateHours)
+	g.GET("/business-calendar/special-days", h.ListSpecialDays)
+	g.PUT("/business-calendar/special-days/:date", h.UpsertSpecialDay)
+	g.DELETE("/business-calendar/special-days/:date", h.DeleteSpecialDay)
+}
+
+func (h *BusinessCalendarHandler) GetMonth(c echo.Context) error {
+	yearMonth := c.QueryParam("year_month")
+	if yearMonth == "" {
+		return errorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "year_month は必須です")
+	}
+
+	days, err := h.svc.GetMonth(c.Request().Context(), yearMonth)
+	if err != nil {
+		return errorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"year_month": yearMonth,
+		"days":       days,
+	})
+}
+
+func (h *BusinessCalendarHandler) ListHours(c echo.Context) error {
+	hours, err := h.svc.ListHours(c.Request().Context())
+	if err != nil {
+		return internalError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"hours": hours,
+	})
+}
+
+func (h *BusinessCalendarHandler) UpdateHours(c echo.Context) error {
+	var req model.UpdateBusinessHoursRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "リクエストの形式が不正です")
+	}
+
+	hours, err := h.svc.UpdateHours(c.Request().Context(), req)
+	if err != nil {
+		return errorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"hours": hours,
+	})
+}
+
+func (h *BusinessCalendarHandler) ListSpecialDays(c echo.Context) error {
+	yearMonth := c.QueryParam("year_month")
+	if yearMonth == "" {
+		return errorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", "year_month は必須です")
+	}
+
+	days, err := h.svc.ListSpecialDays(c.Request().Context(), yearMonth)
+	if err != nil {
+		return internalError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"special_days": days,
+	})
+}
+
+func (h *BusinessCalendarHandler) UpsertSpecialDay(c echo.Context) error {
+	date := c.Param("date")
+	var req model.UpsertBusinessSpecialDayRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "リクエストの形式が不正です")
+	}
+
+	day, err := h.svc.UpsertSpecialDay(c.Request().Context(), date, req)
+	if err != nil {
+		return errorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+	}
+	return c.JSON(http.StatusOK, day)
+}
+
+func (h *BusinessCalendarHandler) DeleteSpecialDay(c echo.Context) error {
+	date := c.Param("date")
+	if err := h.svc.DeleteSpecialDay(c.Request().Context(), date); err != nil {
+		return internalError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
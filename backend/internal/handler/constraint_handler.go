@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -19,6 +20,7 @@ func NewConstraintHandler(svc *service.ConstraintService) *ConstraintHandler {
 
 func (h *ConstraintHandler) RegisterRoutes(g *echo.Group) {
 	g.GET("/constraints", h.List)
+	g.GET("/constraints/schema", h.Schema)
 	g.POST("/constraints", h.Create)
 	g.PUT("/constraints/:id", h.Update)
 	g.DELETE("/constraints/:id", h.Delete)
@@ -38,6 +40,12 @@ func (h *ConstraintHandler) List(c echo.Context) error {
 	})
 }
 
+func (h *ConstraintHandler) Schema(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"schemas": h.svc.ConfigSchemas(),
+	})
+}
+
 func (h *ConstraintHandler) Create(c echo.Context) error {
 	var req model.CreateConstraintRequest
 	if err := c.Bind(&req); err != nil {
@@ -46,6 +54,10 @@ func (h *ConstraintHandler) Create(c echo.Context) error {
 
 	constraint, err := h.svc.Create(c.Request().Context(), req)
 	if err != nil {
+		var verr *service.ValidationError
+		if errors.As(err, &verr) {
+			return validationError(c, verr.Details)
+		}
 		return errorResponse(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
 	}
 	return c.JSON(http.StatusCreated, constraint)
@@ -60,6 +72,10 @@ func (h *ConstraintHandler) Update(c echo.Context) error {
 
 	constraint, err := h.svc.Update(c.Request().Context(), id, req)
 	if err != nil {
+		var verr *service.ValidationError
+		if errors.As(err, &verr) {
+			return validationError(c, verr.Details)
+		}
 		return internalError(c, err)
 	}
 	if constraint == nil {
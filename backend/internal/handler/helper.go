@@ -1,12 +1,16 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
 
 	"shift-app/internal/model"
+	"shift-app/internal/service"
 )
 
 func errorResponse(c echo.Context, status int, code string, message string) error {
@@ -26,6 +30,24 @@ func validationError(c echo.Context, details []model.ErrorDetail) error {
 	return c.JSON(http.StatusBadRequest, resp)
 }
 
+// hardViolationsError lists the hard violations that keep a pattern from
+// being finalized, one detail per violation
+func hardViolationsError(c echo.Context, err *service.HardViolationsError) error {
+	resp := model.ErrorResponse{}
+	resp.Error.Code = "HARD_CONSTRAINT_VIOLATIONS"
+	resp.Error.Message = err.Error()
+	for _, v := range err.Violations {
+		resp.Error.Details = append(resp.Error.Details, model.ErrorDetail{Field: v.Constraint, Message: v.Message})
+	}
+	return c.JSON(http.StatusBadRequest, resp)
+}
+
+// statusConflict answers a request the lifecycle status of a pattern
+// doesn't allow
+func statusConflict(c echo.Context, err *service.StatusError) error {
+	return errorResponse(c, http.StatusConflict, err.Code, err.Message)
+}
+
 func notFound(c echo.Context, resource string) error {
 	return errorResponse(c, http.StatusNotFound, "NOT_FOUND", resource+"が見つかりません")
 }
@@ -35,6 +57,33 @@ func internalError(c echo.Context, err error) error {
 	return errorResponse(c, http.StatusInternalServerError, "INTERNAL_ERROR", "内部エラーが発生しました")
 }
 
+// serviceError answers the typed errors of the service layer and treats
+// anything else as an internal error
+func serviceError(c echo.Context, err error) error {
+	var verr *service.ValidationError
+	if errors.As(err, &verr) {
+		return validationError(c, verr.Details)
+	}
+	var serr *service.StatusError
+	if errors.As(err, &serr) {
+		return statusConflict(c, serr)
+	}
+	return internalError(c, err)
+}
+
+// writeEvent writes one Server-Sent Event with a JSON payload and flushes it
+func writeEvent(res *echo.Response, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
+
 func parseBoolParam(value string) *bool {
 	if value == "" {
 		return nil
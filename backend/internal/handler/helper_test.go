@@ -2,6 +2,8 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -9,6 +11,7 @@ import (
 	"github.com/labstack/echo/v4"
 
 	"shift-app/internal/model"
+	"shift-app/internal/service"
 )
 
 func TestParseBoolParam(t *testing.T) {
@@ -179,5 +182,61 @@ func TestInternalError(t *testing.T) {
 	}
 }
 
+func TestWriteEvent(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	event := model.JobEvent{Type: model.JobEventProgress, JobID: "job-1", Status: "processing", Progress: 40}
+	if err := writeEvent(c.Response(), event.Type, event); err != nil {
+		t.Fatalf("writeEvent returned error: %v", err)
+	}
+
+	want := "event: progress\ndata: {\"type\":\"progress\",\"job_id\":\"job-1\",\"status\":\"processing\",\"progress\":40}\n\n"
+	if rec.Body.String() != want {
+		t.Errorf("body = %q, want %q", rec.Body.String(), want)
+	}
+	if !rec.Flushed {
+		t.Error("event was not flushed")
+	}
+}
+
 func boolPtr(b bool) *bool     { return &b }
 func strPtr(s string) *string  { return &s }
+
+func TestServiceError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		want     string
+	}{
+		{"validation error", &service.ValidationError{Details: []model.ErrorDetail{{Field: "reason", Message: "理由が長すぎます"}}}, http.StatusBadRequest, "VALIDATION_ERROR"},
+		{"wrapped status error", fmt.Errorf("cancel: %w", &service.StatusError{Code: service.CodeInvalidJobStatus, Message: "終了済み"}), http.StatusConflict, service.CodeInvalidJobStatus},
+		{"other error", errors.New("connection refused"), http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if err := serviceError(c, tt.err); err != nil {
+				t.Fatalf("serviceError returned error: %v", err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d", rec.Code, tt.wantCode)
+			}
+			var resp model.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+			if resp.Error.Code != tt.want {
+				t.Errorf("error code = %q, want %q", resp.Error.Code, tt.want)
+			}
+		})
+	}
+}
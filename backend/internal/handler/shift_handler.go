@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
@@ -20,10 +24,21 @@ func NewShiftHandler(svc *service.ShiftService) *ShiftHandler {
 func (h *ShiftHandler) RegisterRoutes(g *echo.Group) {
 	g.POST("/shifts/generate", h.Generate)
 	g.GET("/shifts/generate/:job_id", h.GetJobStatus)
+	g.GET("/shifts/generate/:job_id/events", h.StreamJobEvents)
+	g.GET("/shifts/generate/:job_id/attempts", h.ListAttempts)
+	g.POST("/shifts/generate/:job_id/cancel", h.CancelGeneration)
+	g.POST("/shifts/generate/:job_id/resume", h.ResumeGeneration)
 	g.GET("/shifts/patterns", h.ListPatterns)
 	g.GET("/shifts/patterns/:id", h.GetPatternDetail)
 	g.PUT("/shifts/patterns/:id/select", h.SelectPattern)
 	g.PUT("/shifts/patterns/:id/finalize", h.FinalizePattern)
+	g.PUT("/shifts/patterns/:id/unfinalize", h.UnfinalizePattern)
+	g.PUT("/shifts/patterns/:id/publish", h.PublishPattern)
+	g.PUT("/shifts/patterns/:id/revise", h.RevisePattern)
+	g.PUT("/shifts/patterns/:id/archive", h.ArchivePattern)
+	g.GET("/shifts/patterns/:id/history", h.ListStatusHistory)
+	g.POST("/shifts/patterns/:id/validate", h.ValidatePattern)
+	g.POST("/shifts/patterns/:id/regenerate", h.RegeneratePattern)
 	g.POST("/shifts/entries", h.CreateEntry)
 	g.PUT("/shifts/entries/:id", h.UpdateEntry)
 	g.DELETE("/shifts/entries/:id", h.DeleteEntry)
@@ -58,6 +73,89 @@ func (h *ShiftHandler) GetJobStatus(c echo.Context) error {
 	return c.JSON(http.StatusOK, job)
 }
 
+// StreamJobEvents streams the progress of a job as Server-Sent Events until
+// the job finishes or the client disconnects
+func (h *ShiftHandler) StreamJobEvents(c echo.Context) error {
+	jobID := c.Param("job_id")
+	events, err := h.svc.WatchJob(c.Request().Context(), jobID)
+	if err != nil {
+		return internalError(c, err)
+	}
+	if events == nil {
+		return notFound(c, "ジョブ")
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	// Comments keep idle connections from being closed by proxies
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := writeEvent(res, e.Type, e); err != nil {
+				return nil
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+func (h *ShiftHandler) CancelGeneration(c echo.Context) error {
+	jobID := c.Param("job_id")
+	job, err := h.svc.CancelGeneration(c.Request().Context(), jobID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	if job == nil {
+		return notFound(c, "ジョブ")
+	}
+	return c.JSON(http.StatusOK, job)
+}
+
+func (h *ShiftHandler) ResumeGeneration(c echo.Context) error {
+	jobID := c.Param("job_id")
+	job, err := h.svc.ResumeGeneration(c.Request().Context(), jobID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	if job == nil {
+		return notFound(c, "ジョブ")
+	}
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"job_id":  job.ID,
+		"status":  job.Status,
+		"message": "シフト生成を再開しました",
+	})
+}
+
+func (h *ShiftHandler) ListAttempts(c echo.Context) error {
+	jobID := c.Param("job_id")
+	attempts, err := h.svc.ListAttempts(c.Request().Context(), jobID)
+	if err != nil {
+		return internalError(c, err)
+	}
+	if attempts == nil {
+		return notFound(c, "ジョブ")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"attempts": attempts,
+	})
+}
+
 func (h *ShiftHandler) ListPatterns(c echo.Context) error {
 	yearMonth := c.QueryParam("year_month")
 	if yearMonth == "" {
@@ -88,10 +186,37 @@ func (h *ShiftHandler) GetPatternDetail(c echo.Context) error {
 }
 
 func (h *ShiftHandler) SelectPattern(c echo.Context) error {
+	return h.changeStatus(c, h.svc.SelectPattern)
+}
+
+func (h *ShiftHandler) UnfinalizePattern(c echo.Context) error {
+	return h.changeStatus(c, h.svc.UnfinalizePattern)
+}
+
+func (h *ShiftHandler) PublishPattern(c echo.Context) error {
+	return h.changeStatus(c, h.svc.PublishPattern)
+}
+
+func (h *ShiftHandler) RevisePattern(c echo.Context) error {
+	return h.changeStatus(c, h.svc.RevisePattern)
+}
+
+func (h *ShiftHandler) ArchivePattern(c echo.Context) error {
+	return h.changeStatus(c, h.svc.ArchivePattern)
+}
+
+// changeStatus runs a pattern lifecycle change with the optional
+// PatternTransitionRequest body
+func (h *ShiftHandler) changeStatus(c echo.Context, change func(context.Context, string, model.PatternTransitionRequest) (*model.ShiftPattern, error)) error {
 	id := c.Param("id")
-	pattern, err := h.svc.SelectPattern(c.Request().Context(), id)
+	var req model.PatternTransitionRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "リクエストの形式が不正です")
+	}
+
+	pattern, err := change(c.Request().Context(), id, req)
 	if err != nil {
-		return internalError(c, err)
+		return serviceError(c, err)
 	}
 	if pattern == nil {
 		return notFound(c, "パターン")
@@ -104,12 +229,35 @@ func (h *ShiftHandler) SelectPattern(c echo.Context) error {
 	})
 }
 
-func (h *ShiftHandler) FinalizePattern(c echo.Context) error {
+func (h *ShiftHandler) ListStatusHistory(c echo.Context) error {
 	id := c.Param("id")
-	pattern, err := h.svc.FinalizePattern(c.Request().Context(), id)
+	history, err := h.svc.ListStatusHistory(c.Request().Context(), id)
 	if err != nil {
 		return internalError(c, err)
 	}
+	if history == nil {
+		return notFound(c, "パターン")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"history": history,
+	})
+}
+
+func (h *ShiftHandler) FinalizePattern(c echo.Context) error {
+	id := c.Param("id")
+	var req model.FinalizePatternRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "リクエストの形式が不正です")
+	}
+
+	pattern, finalization, err := h.svc.FinalizePattern(c.Request().Context(), id, req)
+	if err != nil {
+		var verr *service.HardViolationsError
+		if errors.As(err, &verr) {
+			return hardViolationsError(c, verr)
+		}
+		return serviceError(c, err)
+	}
 	if pattern == nil {
 		return notFound(c, "パターン")
 	}
@@ -117,7 +265,45 @@ func (h *ShiftHandler) FinalizePattern(c echo.Context) error {
 		"pattern": map[string]interface{}{
 			"id":     pattern.ID,
 			"status": pattern.Status,
+			"score":  pattern.Score,
 		},
+		"finalization": finalization,
+	})
+}
+
+func (h *ShiftHandler) RegeneratePattern(c echo.Context) error {
+	id := c.Param("id")
+	var req model.RegeneratePatternRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "リクエストの形式が不正です")
+	}
+
+	job, err := h.svc.RegeneratePattern(c.Request().Context(), id, req)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	if job == nil {
+		return notFound(c, "パターン")
+	}
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"job_id":  job.ID,
+		"status":  job.Status,
+		"message": "パターンの再生成を開始しました",
+	})
+}
+
+func (h *ShiftHandler) ValidatePattern(c echo.Context) error {
+	id := c.Param("id")
+	pattern, validation, err := h.svc.ValidatePattern(c.Request().Context(), id)
+	if err != nil {
+		return internalError(c, err)
+	}
+	if pattern == nil {
+		return notFound(c, "パターン")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"pattern":    pattern,
+		"validation": validation,
 	})
 }
 
@@ -127,11 +313,18 @@ func (h *ShiftHandler) CreateEntry(c echo.Context) error {
 		return errorResponse(c, http.StatusBadRequest, "INVALID_REQUEST", "リクエストの形式が不正です")
 	}
 
-	entry, err := h.svc.CreateEntry(c.Request().Context(), req)
+	entry, validation, err := h.svc.CreateEntry(c.Request().Context(), req)
 	if err != nil {
+		var serr *service.StatusError
+		if errors.As(err, &serr) {
+			return statusConflict(c, serr)
+		}
 		return internalError(c, err)
 	}
-	return c.JSON(http.StatusCreated, entry)
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"entry":      entry,
+		"validation": validation,
+	})
 }
 
 func (h *ShiftHandler) UpdateEntry(c echo.Context) error {
@@ -143,6 +336,10 @@ func (h *ShiftHandler) UpdateEntry(c echo.Context) error {
 
 	entry, validation, err := h.svc.UpdateEntry(c.Request().Context(), id, req)
 	if err != nil {
+		var serr *service.StatusError
+		if errors.As(err, &serr) {
+			return statusConflict(c, serr)
+		}
 		return internalError(c, err)
 	}
 	if entry == nil {
@@ -156,8 +353,18 @@ func (h *ShiftHandler) UpdateEntry(c echo.Context) error {
 
 func (h *ShiftHandler) DeleteEntry(c echo.Context) error {
 	id := c.Param("id")
-	if err := h.svc.DeleteEntry(c.Request().Context(), id); err != nil {
+	validation, err := h.svc.DeleteEntry(c.Request().Context(), id)
+	if err != nil {
+		var serr *service.StatusError
+		if errors.As(err, &serr) {
+			return statusConflict(c, serr)
+		}
 		return internalError(c, err)
 	}
-	return c.NoContent(http.StatusNoContent)
+	if validation == nil {
+		return notFound(c, "エントリ")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"validation": validation,
+	})
 }
@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// AnthropicProvider calls the Claude Messages API
+type AnthropicProvider struct {
+	client *anthropic.Client
+	opts   ProviderOptions
+}
+
+func NewAnthropicProvider(apiKey string, opts ProviderOptions) *AnthropicProvider {
+	client := anthropic.NewClient(
+		option.WithAPIKey(apiKey),
+	)
+	return &AnthropicProvider{
+		client: &client,
+		opts:   opts,
+	}
+}
+
+func (p *AnthropicProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	tool := anthropicTool(req.Tool)
+	message, err := p.client.Messages.New(ctx, anthropic.MessageNewParams{
+		Model:       p.opts.Model,
+		MaxTokens:   int64(p.opts.MaxTokens),
+		Temperature: anthropic.Float(p.opts.Temperature),
+		System: []anthropic.TextBlockParam{
+			{Text: req.System},
+		},
+		Messages: anthropicMessages(req.Turns),
+		Tools:    []anthropic.ToolUnionParam{{OfTool: &tool}},
+		ToolChoice: anthropic.ToolChoiceUnionParam{
+			OfToolChoiceTool: &anthropic.ToolChoiceToolParam{Name: req.Tool.Name},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Claude API エラー: %w", err)
+	}
+
+	completion := &Completion{
+		Truncated:    message.StopReason == anthropic.MessageStopReasonMaxTokens,
+		InputTokens:  int(message.Usage.InputTokens),
+		OutputTokens: int(message.Usage.OutputTokens),
+	}
+	for _, block := range message.Content {
+		if block.Type == "tool_use" && block.Name == req.Tool.Name {
+			completion.ToolCall = &ToolCall{ID: block.ID, Name: block.Name, Input: block.Input}
+			break
+		}
+	}
+	return completion, nil
+}
+
+// anthropicTool splits a JSON Schema into the SDK's typed properties and the
+// remaining keys
+func anthropicTool(t Tool) anthropic.ToolParam {
+	tool := anthropic.ToolParam{
+		Name:        t.Name,
+		Description: anthropic.String(t.Description),
+	}
+	extra := make(map[string]interface{})
+	for key, v := range t.InputSchema {
+		switch key {
+		case "type":
+		case "properties":
+			tool.InputSchema.Properties = v
+		default:
+			extra[key] = v
+		}
+	}
+	// Keys without a typed field must go through WithExtraFields; the
+	// ExtraFields struct field isn't marshaled by this SDK version
+	tool.InputSchema.WithExtraFields(extra)
+	return tool
+}
+
+func anthropicMessages(turns []Turn) []anthropic.MessageParam {
+	messages := make([]anthropic.MessageParam, 0, len(turns))
+	for _, t := range turns {
+		var blocks []anthropic.ContentBlockParamUnion
+		if t.Text != "" {
+			blocks = append(blocks, anthropic.NewTextBlock(t.Text))
+		}
+		if t.ToolCall != nil {
+			blocks = append(blocks, anthropic.ContentBlockParamOfRequestToolUseBlock(t.ToolCall.ID, t.ToolCall.Input, t.ToolCall.Name))
+		}
+		if t.ToolResult != nil {
+			blocks = append(blocks, anthropic.NewToolResultBlock(t.ToolResult.CallID, t.ToolResult.Content, t.ToolResult.IsError))
+		}
+		if t.Role == "assistant" {
+			messages = append(messages, anthropic.NewAssistantMessage(blocks...))
+		} else {
+			messages = append(messages, anthropic.NewUserMessage(blocks...))
+		}
+	}
+	return messages
+}
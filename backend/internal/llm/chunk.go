@@ -0,0 +1,269 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"shift-app/internal/model"
+)
+
+// maxStaffDaysPerRequest is the largest staff × days a single request covers.
+// Beyond it the month is generated week by week so one reply stays well
+// inside the max token limit (about 10 staff for a 30-day month).
+const maxStaffDaysPerRequest = 300
+
+// chunkInfo is one week of a chunked generation. Carry holds, per staff ID,
+// what the previous weeks already decided.
+type chunkInfo struct {
+	Index int
+	Total int
+	Days  []model.BusinessDay
+	Carry map[string]carryState
+}
+
+// carryState is the tail of a staff's schedule at the end of the previous
+// chunk
+type carryState struct {
+	// Consecutive is the run of worked days ending on the previous chunk's
+	// last day (0 when that day was off)
+	Consecutive int
+	// LastEnd is the end of the latest shift in minutes on the DayIndex
+	// axis, or -1 when the staff hasn't worked yet
+	LastEnd       int
+	WorkedMinutes int
+}
+
+func (c *chunkInfo) first() string { return c.Days[0].Date }
+func (c *chunkInfo) last() string  { return c.Days[len(c.Days)-1].Date }
+
+func (c *chunkInfo) period() string {
+	return fmt.Sprintf("%s〜%s", c.first(), c.last())
+}
+
+// splitWeeks cuts the month into Monday-to-Sunday weeks; the first and last
+// may be shorter
+func splitWeeks(days []model.BusinessDay) [][]model.BusinessDay {
+	var weeks [][]model.BusinessDay
+	var current []model.BusinessDay
+	for _, d := range days {
+		current = append(current, d)
+		if time.Weekday(d.Weekday) == time.Sunday {
+			weeks = append(weeks, current)
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		weeks = append(weeks, current)
+	}
+	return weeks
+}
+
+// carryOver summarizes entries (all before lastDate) into the state the next
+// chunk starts from
+func carryOver(entries []model.LLMShiftEntry, staffIDs []string, lastDate string) map[string]carryState {
+	lastIdx, _ := model.DayIndex(lastDate)
+	worked := make(map[string]map[int]bool)
+	carry := make(map[string]carryState, len(staffIDs))
+	for _, id := range staffIDs {
+		carry[id] = carryState{LastEnd: -1}
+		worked[id] = make(map[int]bool)
+	}
+
+	for _, e := range entries {
+		st, ok := carry[e.StaffID]
+		di, valid := model.DayIndex(e.Date)
+		if !ok || !valid {
+			continue
+		}
+		_, end := model.ShiftSpan(e.StartTime, e.EndTime)
+		st.LastEnd = max(st.LastEnd, di*model.MinutesPerDay+end)
+		st.WorkedMinutes += model.WorkMinutes(e.StartTime, e.EndTime, e.BreakMinutes)
+		carry[e.StaffID] = st
+		worked[e.StaffID][di] = true
+	}
+
+	for id, st := range carry {
+		for d := lastIdx; worked[id][d]; d-- {
+			st.Consecutive++
+		}
+		carry[id] = st
+	}
+	return carry
+}
+
+// boundaryRules reads the hard limits that span chunk boundaries. Zero means
+// the constraint isn't set.
+func boundaryRules(constraints []constraintInfo) (maxConsecutive, minRestMinutes int) {
+	for _, c := range constraints {
+		if c.Type != "hard" {
+			continue
+		}
+		switch c.Category {
+		case "max_consecutive_days":
+			var cfg model.MaxConsecutiveDaysConfig
+			if json.Unmarshal(c.Config, &cfg) == nil && cfg.MaxDays != nil && (maxConsecutive == 0 || *cfg.MaxDays < maxConsecutive) {
+				maxConsecutive = *cfg.MaxDays
+			}
+		case "rest_hours":
+			var cfg model.RestHoursConfig
+			if json.Unmarshal(c.Config, &cfg) == nil && cfg.MinHours != nil {
+				minRestMinutes = max(minRestMinutes, cfg.MinRestMinutes())
+			}
+		}
+	}
+	return maxConsecutive, minRestMinutes
+}
+
+// checkChunk reports entries outside the chunk and entries that break
+// consecutive days or rest hours together with the carried state
+func checkChunk(resp *model.LLMResponse, chunk *chunkInfo, maxConsecutive, minRestMinutes int) []model.ErrorDetail {
+	inChunk := make(map[string]bool, len(chunk.Days))
+	for _, d := range chunk.Days {
+		inChunk[d.Date] = true
+	}
+	firstIdx, _ := model.DayIndex(chunk.first())
+
+	var details []model.ErrorDetail
+	worked := make(map[string]map[int]bool)
+	earliest := make(map[string]int) // staff ID -> index of the earliest entry
+	for i, e := range resp.Entries {
+		if !inChunk[e.Date] {
+			details = append(details, model.ErrorDetail{
+				Field:   fmt.Sprintf("entries[%d].date", i),
+				Message: fmt.Sprintf("対象期間（%s）外の日付です", chunk.period()),
+			})
+			continue
+		}
+		di, _ := model.DayIndex(e.Date)
+		if worked[e.StaffID] == nil {
+			worked[e.StaffID] = make(map[int]bool)
+		}
+		worked[e.StaffID][di] = true
+		if j, ok := earliest[e.StaffID]; !ok || entryStart(e) < entryStart(resp.Entries[j]) {
+			earliest[e.StaffID] = i
+		}
+	}
+
+	staffIDs := make([]string, 0, len(earliest))
+	for id := range earliest {
+		staffIDs = append(staffIDs, id)
+	}
+	sort.Strings(staffIDs)
+
+	for _, id := range staffIDs {
+		st, ok := chunk.Carry[id]
+		if !ok {
+			continue
+		}
+		i := earliest[id]
+		if minRestMinutes > 0 && st.LastEnd >= 0 {
+			if rest := entryStart(resp.Entries[i]) - st.LastEnd; rest < minRestMinutes {
+				details = append(details, model.ErrorDetail{
+					Field:   fmt.Sprintf("entries[%d]", i),
+					Message: fmt.Sprintf("前の期間の最終勤務（%s 終了）からの休息が%.1f時間で、%.1f時間未満です", formatAbsolute(st.LastEnd), float64(rest)/60, float64(minRestMinutes)/60),
+				})
+			}
+		}
+		if maxConsecutive > 0 && st.Consecutive > 0 {
+			run := st.Consecutive
+			for d := firstIdx; worked[id][d]; d++ {
+				run++
+			}
+			if run > maxConsecutive {
+				details = append(details, model.ErrorDetail{
+					Field:   fmt.Sprintf("entries[%d]", i),
+					Message: fmt.Sprintf("前の期間から%d日連続勤務しており、この期間の勤務を含めると%d日連続で上限%d日を超えます", st.Consecutive, run, maxConsecutive),
+				})
+			}
+		}
+	}
+
+	if len(details) > maxSchemaDetails {
+		details = details[:maxSchemaDetails]
+	}
+	return details
+}
+
+// entryStart is the start of an entry in minutes on the DayIndex axis
+func entryStart(e model.LLMShiftEntry) int {
+	di, _ := model.DayIndex(e.Date)
+	return di*model.MinutesPerDay + model.ClockMinutes(e.StartTime)
+}
+
+func formatAbsolute(minutes int) string {
+	day := time.Unix(int64(minutes/model.MinutesPerDay)*86400, 0).UTC()
+	return day.Format("2006-01-02") + " " + model.FormatClock(minutes)
+}
+
+// boundaryFeedback renders checkChunk errors as a tool_result for the model
+func boundaryFeedback(chunk *chunkInfo, details []model.ErrorDetail) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("提出内容が対象期間（%s）または前の期間からの引き継ぎ条件を満たしていません。以下を修正して、もう一度 %s を呼び出してください。\n", chunk.period(), submitToolName))
+	for _, d := range details {
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", d.Field, d.Message))
+	}
+	return sb.String()
+}
+
+// buildCarrySection tells the model where each staff stands after the
+// previous chunks
+func buildCarrySection(chunk *chunkInfo, staffs []staffInfo) string {
+	var sb strings.Builder
+	prev, _ := model.DayIndex(chunk.first())
+	sb.WriteString(fmt.Sprintf("## 前の期間からの引き継ぎ（%s まで確定済み）\n", time.Unix(int64(prev-1)*86400, 0).UTC().Format("2006-01-02")))
+	for _, s := range staffs {
+		st := chunk.Carry[s.ID]
+		if st.LastEnd < 0 {
+			sb.WriteString(fmt.Sprintf("- %s: 勤務なし\n", s.Name))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("- %s: これまで %.1fh, 直前まで%d日連続勤務, 最終勤務終了 %s\n",
+			s.Name, float64(st.WorkedMinutes)/60, st.Consecutive, formatAbsolute(st.LastEnd)))
+	}
+	sb.WriteString("月間労働時間の希望は、これまでの勤務時間を含めた月合計で判断すること。連続勤務日数と勤務間の休息も引き継ぎから数えること。\n")
+	return sb.String()
+}
+
+// filterRequests keeps the requests dated inside the chunk
+func filterRequests(requests []requestInfo, chunk *chunkInfo) []requestInfo {
+	var result []requestInfo
+	for _, r := range requests {
+		if r.Date >= chunk.first() && r.Date <= chunk.last() {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// stitch joins the chunk responses into one month. Entries outside their
+// chunk are dropped; violations reported in several chunks are kept once.
+func stitch(chunks []*chunkInfo, responses []*model.LLMResponse) *model.LLMResponse {
+	result := &model.LLMResponse{
+		Entries:              []model.LLMShiftEntry{},
+		ConstraintViolations: []model.ConstraintViolation{},
+	}
+	var reasoning []string
+	seen := make(map[model.ConstraintViolation]bool)
+	for i, resp := range responses {
+		chunk := chunks[i]
+		for _, e := range resp.Entries {
+			if e.Date >= chunk.first() && e.Date <= chunk.last() {
+				result.Entries = append(result.Entries, e)
+			}
+		}
+		for _, cv := range resp.ConstraintViolations {
+			if !seen[cv] {
+				seen[cv] = true
+				result.ConstraintViolations = append(result.ConstraintViolations, cv)
+			}
+		}
+		if r := strings.TrimSpace(resp.Reasoning); r != "" {
+			reasoning = append(reasoning, fmt.Sprintf("【%s】%s", chunk.period(), r))
+		}
+	}
+	result.Reasoning = strings.Join(reasoning, "\n")
+	return result
+}
@@ -0,0 +1,162 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"shift-app/internal/model"
+)
+
+func TestSplitWeeks(t *testing.T) {
+	// 2025-02-01 is a Saturday: 1-2, 3-9, 10-16, 17-23, 24-28
+	weeks := splitWeeks(model.NewBusinessCalendar(nil, nil).Month("2025-02"))
+
+	want := [][2]string{
+		{"2025-02-01", "2025-02-02"},
+		{"2025-02-03", "2025-02-09"},
+		{"2025-02-10", "2025-02-16"},
+		{"2025-02-17", "2025-02-23"},
+		{"2025-02-24", "2025-02-28"},
+	}
+	if len(weeks) != len(want) {
+		t.Fatalf("got %d weeks, want %d", len(weeks), len(want))
+	}
+	for i, w := range weeks {
+		if w[0].Date != want[i][0] || w[len(w)-1].Date != want[i][1] {
+			t.Errorf("week %d = %s..%s, want %s..%s", i, w[0].Date, w[len(w)-1].Date, want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestCarryOver(t *testing.T) {
+	entries := []model.LLMShiftEntry{
+		{StaffID: "s1", Date: "2025-02-07", StartTime: "09:00", EndTime: "17:00", BreakMinutes: 60},
+		{StaffID: "s1", Date: "2025-02-08", StartTime: "09:00", EndTime: "17:00", BreakMinutes: 60},
+		{StaffID: "s1", Date: "2025-02-09", StartTime: "22:00", EndTime: "06:00", BreakMinutes: 60},
+		{StaffID: "s2", Date: "2025-02-07", StartTime: "09:00", EndTime: "13:00", BreakMinutes: 0},
+	}
+	carry := carryOver(entries, []string{"s1", "s2", "s3"}, "2025-02-09")
+
+	if got := carry["s1"]; got.Consecutive != 3 || got.WorkedMinutes != 21*60 || formatAbsolute(got.LastEnd) != "2025-02-10 06:00" {
+		t.Errorf("s1 = %+v (last end %s)", got, formatAbsolute(got.LastEnd))
+	}
+	if got := carry["s2"]; got.Consecutive != 0 || got.WorkedMinutes != 4*60 {
+		t.Errorf("s2 = %+v", got)
+	}
+	if got := carry["s3"]; got.LastEnd != -1 || got.Consecutive != 0 {
+		t.Errorf("s3 = %+v", got)
+	}
+}
+
+func TestCheckChunk(t *testing.T) {
+	week := model.NewBusinessCalendar(nil, nil).Month("2025-02")[9:16] // 02-10..02-16
+	lastEnd, _ := model.DayIndex("2025-02-10")
+	chunk := &chunkInfo{
+		Index: 2,
+		Total: 5,
+		Days:  week,
+		Carry: map[string]carryState{
+			// ended 02-10 06:00 after 3 days in a row
+			"s1": {Consecutive: 3, LastEnd: lastEnd*model.MinutesPerDay + 6*60},
+			"s2": {LastEnd: -1},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		entries    []model.LLMShiftEntry
+		wantFields []string
+	}{
+		{
+			name: "ok",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-02-10", StartTime: "17:00", EndTime: "22:00"},
+				{StaffID: "s2", Date: "2025-02-10", StartTime: "06:00", EndTime: "14:00"},
+			},
+		},
+		{
+			name: "outside chunk",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s2", Date: "2025-02-17", StartTime: "09:00", EndTime: "17:00"},
+			},
+			wantFields: []string{"entries[0].date"},
+		},
+		{
+			name: "rest too short across boundary",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-02-11", StartTime: "09:00", EndTime: "17:00"},
+				{StaffID: "s1", Date: "2025-02-10", StartTime: "09:00", EndTime: "17:00"},
+			},
+			wantFields: []string{"entries[1]"},
+		},
+		{
+			name: "consecutive days across boundary",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-02-10", StartTime: "17:00", EndTime: "22:00"},
+				{StaffID: "s1", Date: "2025-02-11", StartTime: "17:00", EndTime: "22:00"},
+				{StaffID: "s1", Date: "2025-02-12", StartTime: "17:00", EndTime: "22:00"},
+			},
+			wantFields: []string{"entries[0]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := checkChunk(&model.LLMResponse{Entries: tt.entries}, chunk, 5, 8*60)
+			if len(details) != len(tt.wantFields) {
+				t.Fatalf("got %+v, want fields %v", details, tt.wantFields)
+			}
+			for i, d := range details {
+				if d.Field != tt.wantFields[i] {
+					t.Errorf("field[%d] = %q, want %q", i, d.Field, tt.wantFields[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStitch(t *testing.T) {
+	days := model.NewBusinessCalendar(nil, nil).Month("2025-02")
+	chunks := []*chunkInfo{{Days: days[0:2]}, {Days: days[2:9]}}
+	cv := model.ConstraintViolation{ConstraintName: "c", Type: "soft", Message: "m"}
+	responses := []*model.LLMResponse{
+		{Reasoning: "週末", Entries: []model.LLMShiftEntry{{StaffID: "s1", Date: "2025-02-01"}}, ConstraintViolations: []model.ConstraintViolation{cv}},
+		{Reasoning: "平日", Entries: []model.LLMShiftEntry{{StaffID: "s1", Date: "2025-02-03"}, {StaffID: "s1", Date: "2025-02-20"}}, ConstraintViolations: []model.ConstraintViolation{cv}},
+	}
+
+	got := stitch(chunks, responses)
+	if len(got.Entries) != 2 || len(got.ConstraintViolations) != 1 {
+		t.Errorf("unexpected stitch: %+v", got)
+	}
+	if want := "【2025-02-01〜2025-02-02】週末\n【2025-02-03〜2025-02-09】平日"; got.Reasoning != want {
+		t.Errorf("reasoning = %q, want %q", got.Reasoning, want)
+	}
+}
+
+func TestSubmitSchedule_BoundaryFeedback(t *testing.T) {
+	outside := `{"input": {"reasoning": "r", "entries": [
+		{"staff_id": "s1", "date": "2025-02-20", "start_time": "09:00", "end_time": "17:00", "break_minutes": 60}
+	], "constraint_violations": []}}`
+	provider, err := NewReplayProvider(writeFixtures(t, outside, `{"input": `+validSubmission+`}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := &chunkInfo{Days: model.NewBusinessCalendar(nil, nil).Month("2025-02")[2:9]}
+
+	var feedback []string
+	result, err := submitSchedule(context.Background(), provider, "system", "user", func(r *model.LLMResponse) string {
+		if details := checkChunk(r, chunk, 0, 0); len(details) > 0 {
+			raw, _ := json.Marshal(details)
+			feedback = append(feedback, string(raw))
+			return boundaryFeedback(chunk, details)
+		}
+		return ""
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(feedback) != 1 || result.Entries[0].Date != "2025-02-03" {
+		t.Errorf("boundary problem should be fixed in the second turn: feedback=%v result=%+v", feedback, result)
+	}
+}
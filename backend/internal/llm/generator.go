@@ -4,36 +4,41 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"time"
 
-	"github.com/anthropics/anthropic-sdk-go"
-	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"shift-app/internal/model"
 )
 
-const (
-	defaultModel     = "claude-sonnet-4-5-20250929"
-	defaultMaxTokens = 16384
-)
+// maxSchemaFixTurns is how many times a submission that fails the schema
+// is sent back to the model within one Generate call
+const maxSchemaFixTurns = 2
+
+// CalendarSource loads the business calendar of a month
+type CalendarSource interface {
+	GetCalendar(ctx context.Context, yearMonth string) (*model.BusinessCalendar, error)
+}
 
 type Generator struct {
-	client *anthropic.Client
-	db     *pgxpool.Pool
+	provider Provider
+	db       *pgxpool.Pool
+	calendar CalendarSource
 }
 
-func NewGenerator(apiKey string, db *pgxpool.Pool) *Generator {
-	client := anthropic.NewClient(
-		option.WithAPIKey(apiKey),
-	)
+func NewGenerator(provider Provider, db *pgxpool.Pool, calendar CalendarSource) *Generator {
 	return &Generator{
-		client: &client,
-		db:     db,
+		provider: provider,
+		db:       db,
+		calendar: calendar,
 	}
 }
 
-func (g *Generator) Generate(ctx context.Context, yearMonth string, patternCount int, patternIdx int, previousPatterns []model.LLMResponse, lastViolations []model.Violation) (*model.LLMResponse, error) {
+func (g *Generator) Generate(ctx context.Context, params model.GenerationParams) (*model.LLMResponse, error) {
+	yearMonth := params.YearMonth
 	// Collect data
 	staffs, err := g.getStaffs(ctx)
 	if err != nil {
@@ -55,66 +60,109 @@ func (g *Generator) Generate(ctx context.Context, yearMonth string, patternCount
 		return nil, fmt.Errorf("制約条件取得エラー: %w", err)
 	}
 
-	systemPrompt := buildSystemPrompt()
-	userPrompt := buildUserPrompt(yearMonth, staffs, monthlySettings, shiftRequests, constraints, patternIdx, previousPatterns, lastViolations)
-
-	message, err := g.client.Messages.New(ctx, anthropic.MessageNewParams{
-		Model:       defaultModel,
-		MaxTokens:   defaultMaxTokens,
-		Temperature: anthropic.Float(0.7),
-		System: []anthropic.TextBlockParam{
-			{Text: systemPrompt},
-		},
-		Messages: []anthropic.MessageParam{
-			anthropic.NewUserMessage(anthropic.NewTextBlock(userPrompt)),
-		},
-	})
+	calendar, err := g.calendar.GetCalendar(ctx, yearMonth)
 	if err != nil {
-		return nil, fmt.Errorf("Claude API エラー: %w", err)
+		return nil, fmt.Errorf("営業カレンダー取得エラー: %w", err)
 	}
 
-	// Extract text content
-	var responseText string
-	for _, block := range message.Content {
-		if block.Type == "text" {
-			responseText = block.Text
-			break
-		}
+	provider := g.provider
+	if params.Trace != nil {
+		provider = &tracingProvider{provider: provider, trace: params.Trace}
 	}
 
-	// Try to parse JSON from response
-	responseText = extractJSON(responseText)
-
-	var result model.LLMResponse
-	if err := json.Unmarshal([]byte(responseText), &result); err != nil {
-		return nil, fmt.Errorf("レスポンスのJSONパース失敗: %w\nResponse: %s", err, responseText)
+	systemPrompt := buildSystemPrompt()
+	days := calendar.Month(yearMonth)
+	var scope *scopeInfo
+	sizeStaffs := staffs
+	if params.Scope != nil {
+		// Only the scope is generated; the locked entries are context
+		scope = &scopeInfo{Scope: params.Scope, Locked: params.LockedEntries}
+		days = scopeDays(days, params.Scope)
+		sizeStaffs = scopeStaffs(staffs, params.Scope)
+	}
+	if len(sizeStaffs)*len(days) <= maxStaffDaysPerRequest {
+		userPrompt := buildUserPrompt(yearMonth, days, staffs, monthlySettings, shiftRequests, constraints, params, nil, scope)
+		return submitSchedule(ctx, provider, systemPrompt, userPrompt, nil)
+	}
+
+	// Too large for one reply: generate week by week, carrying each staff's
+	// tail (hours so far, consecutive days, last shift end) into the next week
+	staffIDs := make([]string, len(staffs))
+	for i, s := range staffs {
+		staffIDs[i] = s.ID
+	}
+	maxConsecutive, minRest := boundaryRules(constraints)
+	weeks := splitWeeks(days)
+	chunks := make([]*chunkInfo, len(weeks))
+	responses := make([]*model.LLMResponse, len(weeks))
+	var entries []model.LLMShiftEntry
+	for i, week := range weeks {
+		chunk := &chunkInfo{Index: i, Total: len(weeks), Days: week}
+		if i > 0 {
+			chunk.Carry = carryOver(entries, staffIDs, chunks[i-1].last())
+		}
+		userPrompt := buildUserPrompt(yearMonth, week, staffs, monthlySettings, filterRequests(shiftRequests, chunk), constraints, params, chunk, scope)
+		resp, err := submitSchedule(ctx, provider, systemPrompt, userPrompt, func(r *model.LLMResponse) string {
+			if details := checkChunk(r, chunk, maxConsecutive, minRest); len(details) > 0 {
+				return boundaryFeedback(chunk, details)
+			}
+			return ""
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s の生成エラー: %w", chunk.period(), err)
+		}
+		chunks[i], responses[i] = chunk, resp
+		entries = append(entries, stitch(chunks[i:i+1], responses[i:i+1]).Entries...)
 	}
-
-	return &result, nil
+	return stitch(chunks, responses), nil
 }
 
-func extractJSON(text string) string {
-	// Try to find JSON block in markdown code fences
-	if idx := strings.Index(text, "```json"); idx != -1 {
-		start := idx + 7
-		if end := strings.Index(text[start:], "```"); end != -1 {
-			return strings.TrimSpace(text[start : start+end])
+// submitSchedule asks for a schedule through the submit tool so it arrives as
+// structured JSON. Schema errors are returned to the model in the same
+// conversation so they don't cost a full regeneration. check, when set, runs
+// on schema-valid submissions and returns feedback for problems the model
+// should fix the same way; if they remain after the last turn the
+// submission is accepted anyway and left to the validator.
+func submitSchedule(ctx context.Context, provider Provider, systemPrompt, userPrompt string, check func(*model.LLMResponse) string) (*model.LLMResponse, error) {
+	req := CompletionRequest{
+		System: systemPrompt,
+		Turns:  []Turn{{Role: "user", Text: userPrompt}},
+		Tool:   submitTool(),
+	}
+	var details []model.ErrorDetail
+	for turn := 0; turn <= maxSchemaFixTurns; turn++ {
+		completion, err := provider.Complete(ctx, req)
+		if err != nil {
+			return nil, err
 		}
-	}
-	if idx := strings.Index(text, "```"); idx != -1 {
-		start := idx + 3
-		if end := strings.Index(text[start:], "```"); end != -1 {
-			return strings.TrimSpace(text[start : start+end])
+
+		call := completion.ToolCall
+		if call == nil {
+			if completion.Truncated {
+				return nil, &model.OutputSchemaError{Details: []model.ErrorDetail{{Field: "input", Message: "出力が最大トークン数で途切れました。エントリを簡潔にしてください"}}}
+			}
+			return nil, &model.OutputSchemaError{Details: []model.ErrorDetail{{Field: "input", Message: submitToolName + " が呼び出されませんでした"}}}
 		}
-	}
-	// Try to find raw JSON
-	if idx := strings.Index(text, "{"); idx != -1 {
-		lastIdx := strings.LastIndex(text, "}")
-		if lastIdx > idx {
-			return text[idx : lastIdx+1]
+
+		var result *model.LLMResponse
+		var feedback string
+		result, details = parseSubmission(call.Input)
+		if len(details) > 0 {
+			feedback = schemaFeedback(details)
+		} else {
+			if check == nil || turn == maxSchemaFixTurns {
+				return result, nil
+			}
+			if feedback = check(result); feedback == "" {
+				return result, nil
+			}
 		}
+		req.Turns = append(req.Turns,
+			Turn{Role: "assistant", ToolCall: call},
+			Turn{Role: "user", ToolResult: &ToolResult{CallID: call.ID, Content: feedback, IsError: true}},
+		)
 	}
-	return text
+	return nil, &model.OutputSchemaError{Details: details}
 }
 
 func buildSystemPrompt() string {
@@ -123,14 +171,16 @@ func buildSystemPrompt() string {
 最適なシフトスケジュールを作成してください。
 
 ## 出力ルール
-- 必ず指定されたJSON形式で出力してください
+- 作成したシフトは必ず submit_shift_schedule ツールで提出してください（テキストでJSONを書かないこと）
+- ツールが入力エラーを返した場合は、指摘された項目を修正して再度ツールを呼び出してください
 - 全ての日付について、各スタッフの勤務/休みを決定してください
 - ハード制約は必ず遵守してください
 - ソフト制約はできる限り尊重し、守れない場合は理由を説明してください
 - 6時間以上の勤務には60分の休憩を自動付与してください
+- 日付をまたぐ勤務（例: 22:00〜翌6:00）は date に開始日を、end_time に翌日の終了時刻("06:00")を指定してください
 - スタッフの月間労働時間が希望に近づくよう調整してください
 
-## 出力JSON形式
+## 提出内容（submit_shift_schedule の入力）
 {
   "reasoning": "このパターンの特徴と判断理由の説明",
   "entries": [
@@ -152,14 +202,24 @@ func buildSystemPrompt() string {
 }`
 }
 
-func buildUserPrompt(yearMonth string, staffs []staffInfo, settings []settingInfo, requests []requestInfo, constraints []constraintInfo, patternIdx int, previous []model.LLMResponse, lastViolations []model.Violation) string {
+// buildUserPrompt renders the data and constraints for a whole month, or for
+// one week of it when chunk is set (days and requests then cover that week).
+// params adds the pattern's strategy, the manager's guidance and the
+// violations of the previous attempt.
+func buildUserPrompt(yearMonth string, days []model.BusinessDay, staffs []staffInfo, settings []settingInfo, requests []requestInfo, constraints []constraintInfo, params model.GenerationParams, chunk *chunkInfo, scope *scopeInfo) string {
 	var sb strings.Builder
 
-	sb.WriteString(fmt.Sprintf("以下の条件で %s のシフトを作成してください。\n\n", yearMonth))
+	period := yearMonth
+	if chunk != nil {
+		period = chunk.period()
+		sb.WriteString(fmt.Sprintf("%s のシフトを週ごとに分けて作成しています（%d/%d）。以下の条件で %s の分だけを作成してください。\n\n", yearMonth, chunk.Index+1, chunk.Total, period))
+	} else {
+		sb.WriteString(fmt.Sprintf("以下の条件で %s のシフトを作成してください。\n\n", yearMonth))
+	}
 
 	sb.WriteString("## 店舗営業情報\n")
-	sb.WriteString("- 営業時間: 9:00〜22:00\n")
-	sb.WriteString(fmt.Sprintf("- 対象期間: %s の全日\n\n", yearMonth))
+	sb.WriteString(buildBusinessHoursSection(period, days))
+	sb.WriteString("\n")
 
 	sb.WriteString("## スタッフ情報\n")
 	for _, s := range staffs {
@@ -177,6 +237,11 @@ func buildUserPrompt(yearMonth string, staffs []staffInfo, settings []settingInf
 	}
 	sb.WriteString("\n")
 
+	if chunk != nil && chunk.Index > 0 {
+		sb.WriteString(buildCarrySection(chunk, staffs))
+		sb.WriteString("\n")
+	}
+
 	sb.WriteString("## シフト希望\n")
 	staffRequests := make(map[string][]requestInfo)
 	for _, r := range requests {
@@ -203,8 +268,14 @@ func buildUserPrompt(yearMonth string, staffs []staffInfo, settings []settingInf
 	}
 	sb.WriteString("\n")
 
+	staffNames := make(map[string]string, len(staffs))
+	for _, s := range staffs {
+		staffNames[s.ID] = s.Name
+	}
+
 	var hardConstraints, softConstraints []constraintInfo
 	for _, c := range constraints {
+		c.Description = buildConstraintDescription(c, yearMonth, staffNames)
 		if c.Type == "hard" {
 			hardConstraints = append(hardConstraints, c)
 		} else {
@@ -225,29 +296,144 @@ func buildUserPrompt(yearMonth string, staffs []staffInfo, settings []settingInf
 	}
 	sb.WriteString("\n")
 
-	if patternIdx > 0 {
+	if scope != nil {
+		sb.WriteString(buildScopeSection(scope, staffs))
+		sb.WriteString("\n")
+	}
+
+	if hint, ok := strategyHints[params.Strategy]; ok {
+		sb.WriteString("## 作成方針\n")
+		sb.WriteString(hint)
+		sb.WriteString("\n\n")
+	} else if params.PatternIdx > 0 {
 		sb.WriteString("## 追加指示\n")
 		sb.WriteString("前のパターンとは異なるアプローチで作成してください。\n")
 		sb.WriteString("例えば、週末のシフト配分を変える、早番/遅番の割り当てを変える等。\n\n")
 	}
 
-	if len(lastViolations) > 0 {
+	if params.Weights != nil {
+		sb.WriteString(buildWeightsSection(*params.Weights))
+		sb.WriteString("\n")
+	}
+
+	if params.Instruction != "" {
+		sb.WriteString("## 管理者からの指示\n")
+		sb.WriteString("ハード制約に反しない範囲で、次の指示をできるだけ反映してください。\n")
+		sb.WriteString(params.Instruction)
+		sb.WriteString("\n\n")
+	}
+
+	if len(params.LastViolations) > 0 {
 		sb.WriteString("## ⚠️ 前回の生成結果で以下の制約違反が検出されました。必ず修正してください。\n")
-		for _, v := range lastViolations {
+		for _, v := range params.LastViolations {
 			detail := v.Message
 			if v.Date != "" {
 				detail += fmt.Sprintf(" (日付: %s)", v.Date)
 			}
+			if v.StaffID != "" {
+				detail += fmt.Sprintf(" (スタッフid: %s)", v.StaffID)
+			}
 			sb.WriteString(fmt.Sprintf("- [%s] %s: %s\n", v.Type, v.Constraint, detail))
 		}
-		sb.WriteString("\n上記の違反を全て解消した上で、JSON形式で出力してください。")
+		sb.WriteString("\n上記の違反を全て解消した上で、submit_shift_schedule ツールで提出してください。")
 	} else {
-		sb.WriteString("上記のJSON形式で出力してください。")
+		sb.WriteString("submit_shift_schedule ツールで提出してください。")
 	}
 
 	return sb.String()
 }
 
+// strategyHints tells each pattern of a parallel job which approach to take,
+// so patterns generated at the same time still differ
+var strategyHints = map[string]string{
+	model.StrategyEvenDistribution: "スタッフ間で勤務時間・出勤日数ができるだけ均等になるように割り当ててください。特定のスタッフに負担が偏らないことを優先します。",
+	model.StrategyWeekendHeavy:     "週末（土日）と祝日に人員を厚く配置し、平日は必要最小限の人数で回してください。",
+	model.StrategyCostMinimizing:   "必要人数・営業時間を満たす範囲で総勤務時間をできるだけ少なくしてください。月間労働時間の希望は下限寄りで構いません。",
+	model.StrategyPreferenceFirst:  "シフト希望（◎・○）をできるだけ多く叶えることを優先してください。",
+	model.StrategyFixedRotation:    "各スタッフの出勤曜日・時間帯をできるだけ固定し、毎週同じ規則的なパターンにしてください。",
+}
+
+// buildWeightsSection renders the score weights as percentages so the model
+// knows what the patterns are scored on. Components weighted 0 are left out.
+func buildWeightsSection(w model.ObjectiveWeights) string {
+	total := w.Total()
+	if total <= 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("## 重視する観点\n")
+	sb.WriteString("シフトは次の比重で評価されます。比重の大きい観点を優先してください。\n")
+	for _, o := range objectiveHints {
+		if weight := w.Of(o.component); weight > 0 {
+			sb.WriteString(fmt.Sprintf("- %s: %.0f%%\n", o.hint, weight/total*100))
+		}
+	}
+	return sb.String()
+}
+
+// objectiveHints describes each score component to the model
+var objectiveHints = []struct {
+	component string
+	hint      string
+}{
+	{model.ScoreHoursFit, "月間労働時間（各スタッフを希望の範囲に収める）"},
+	{model.ScorePreference, "希望の充足（◎・○の日に勤務を割り当てる）"},
+	{model.ScoreSoftConstraints, "ソフト制約（優先度の高いものから守る）"},
+	{model.ScoreFairness, "公平性（週末の出勤回数をスタッフ間で揃える）"},
+	{model.ScoreCost, "人件費（総勤務時間を月間労働時間の希望の下限に近づける）"},
+}
+
+// buildBusinessHoursSection renders regular hours per weekday, closed dates
+// and special-hours dates. Shifts must stay inside these hours. period labels
+// the days (a year-month or a date range).
+func buildBusinessHoursSection(period string, days []model.BusinessDay) string {
+	var sb strings.Builder
+
+	// Regular hours: take the first non-special day of each weekday
+	regular := make(map[int]string)
+	for _, d := range days {
+		if d.IsSpecial {
+			continue
+		}
+		if _, ok := regular[d.Weekday]; ok {
+			continue
+		}
+		if d.IsClosed {
+			regular[d.Weekday] = "定休日"
+		} else {
+			regular[d.Weekday] = fmt.Sprintf("%s〜%s", d.OpenTime, d.CloseTime)
+		}
+	}
+	var regularParts []string
+	for _, wd := range []int{1, 2, 3, 4, 5, 6, 0} {
+		if hours, ok := regular[wd]; ok {
+			regularParts = append(regularParts, fmt.Sprintf("%s %s", model.WeekdayLabel(time.Weekday(wd)), hours))
+		}
+	}
+	sb.WriteString(fmt.Sprintf("- 営業時間: %s\n", strings.Join(regularParts, ", ")))
+
+	var closed, special []string
+	for _, d := range days {
+		label := fmt.Sprintf("%s(%s)", d.Date, model.WeekdayLabel(time.Weekday(d.Weekday)))
+		if d.Note != "" {
+			label += " " + d.Note
+		}
+		if d.IsClosed {
+			closed = append(closed, label)
+		} else if d.IsSpecial {
+			special = append(special, fmt.Sprintf("%s %s〜%s", label, d.OpenTime, d.CloseTime))
+		}
+	}
+	if len(closed) > 0 {
+		sb.WriteString(fmt.Sprintf("- 休業日（シフトを入れないこと）: %s\n", strings.Join(closed, ", ")))
+	}
+	if len(special) > 0 {
+		sb.WriteString(fmt.Sprintf("- 特別営業時間: %s\n", strings.Join(special, ", ")))
+	}
+	sb.WriteString(fmt.Sprintf("- 対象期間: %s の営業日。シフトは各日の営業時間内に収めること\n", period))
+	return sb.String()
+}
+
 type staffInfo struct {
 	ID             string
 	Name           string
@@ -273,7 +459,9 @@ type requestInfo struct {
 type constraintInfo struct {
 	Name        string
 	Type        string
+	Category    string
 	Priority    int
+	Config      []byte
 	Description string
 }
 
@@ -344,7 +532,7 @@ func (g *Generator) getShiftRequests(ctx context.Context, yearMonth string) ([]r
 
 func (g *Generator) getConstraints(ctx context.Context) ([]constraintInfo, error) {
 	rows, err := g.db.Query(ctx,
-		`SELECT name, type, COALESCE(priority, 0), config FROM constraints WHERE is_active = true ORDER BY type, priority DESC`)
+		`SELECT name, type, category, COALESCE(priority, 0), config FROM constraints WHERE is_active = true ORDER BY type, priority DESC`)
 	if err != nil {
 		return nil, err
 	}
@@ -353,32 +541,228 @@ func (g *Generator) getConstraints(ctx context.Context) ([]constraintInfo, error
 	var result []constraintInfo
 	for rows.Next() {
 		var c constraintInfo
-		var configJSON []byte
-		if err := rows.Scan(&c.Name, &c.Type, &c.Priority, &configJSON); err != nil {
+		if err := rows.Scan(&c.Name, &c.Type, &c.Category, &c.Priority, &c.Config); err != nil {
 			return nil, err
 		}
-		c.Description = buildConstraintDescription(c.Name, configJSON)
 		result = append(result, c)
 	}
 	return result, rows.Err()
 }
 
-func buildConstraintDescription(name string, configJSON []byte) string {
+func buildConstraintDescription(c constraintInfo, yearMonth string, staffNames map[string]string) string {
 	var config map[string]interface{}
-	if err := json.Unmarshal(configJSON, &config); err != nil {
+	if err := json.Unmarshal(c.Config, &config); err != nil {
+		return c.Name
+	}
+
+	switch c.Category {
+	case "fixed_day_off":
+		return describeFixedDayOff(c.Name, config, yearMonth, staffNames)
+	case "staff_compatibility":
+		return describeStaffCompatibility(c.Name, config, staffNames)
+	case "coverage":
+		return describeCoverage(c.Name, c.Config)
+	case "request_window":
+		return describeRequestWindow(c.Name, c.Config)
+	case "monthly_hours":
+		return describeMonthlyHours(c.Name, c.Config, staffNames)
+	}
+
+	typed, details := model.DecodeConstraintConfig(c.Category, c.Config)
+	if len(details) > 0 {
+		return c.Name
+	}
+	parts := []string{c.Name}
+	switch cfg := typed.(type) {
+	case *model.MaxConsecutiveDaysConfig:
+		parts = append(parts, fmt.Sprintf("(最大%d日)", *cfg.MaxDays))
+	case *model.RestHoursConfig:
+		parts = append(parts, fmt.Sprintf("(最低%g時間)", *cfg.MinHours))
+	case *model.MaxStaffConfig:
+		parts = append(parts, fmt.Sprintf("(最大%d人)", *cfg.MaxCount))
+	case *model.MinStaffConfig:
+		if cfg.MinCount != nil {
+			parts = append(parts, fmt.Sprintf("(最低%d人)", *cfg.MinCount))
+		}
+		for _, tr := range cfg.TimeRanges {
+			parts = append(parts, fmt.Sprintf("(%s〜%s は%d人以上)", tr.Start, tr.End, tr.MinCount))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// describeRequestWindow renders a request_window constraint with the request
+// marks it applies to
+func describeRequestWindow(name string, raw json.RawMessage) string {
+	typed, details := model.DecodeConstraintConfig("request_window", raw)
+	if len(details) > 0 {
+		return name
+	}
+	cfg := typed.(*model.RequestWindowConfig)
+	var marks []string
+	if cfg.Applies("preferred") {
+		marks = append(marks, "◎")
+	}
+	if cfg.Applies("available") {
+		marks = append(marks, "○")
+	}
+	return fmt.Sprintf("%s: シフト希望（%s）に時間の指定がある日は、その時間内で勤務させる", name, strings.Join(marks, "・"))
+}
+
+// describeMonthlyHours renders a monthly_hours constraint with its overrides
+// per employment type and per staff, which the validator enforces as well
+func describeMonthlyHours(name string, raw json.RawMessage, staffNames map[string]string) string {
+	typed, details := model.DecodeConstraintConfig("monthly_hours", raw)
+	if len(details) > 0 {
 		return name
 	}
+	cfg := typed.(*model.MonthlyHoursConfig)
+	limit := func(l model.HourLimitConfig) string {
+		var parts []string
+		if l.MinHours != nil {
+			parts = append(parts, fmt.Sprintf("最低%g時間", *l.MinHours))
+		}
+		if l.MaxHours != nil {
+			parts = append(parts, fmt.Sprintf("最大%g時間", *l.MaxHours))
+		}
+		return strings.Join(parts, "・")
+	}
 
-	parts := []string{name}
-	if maxDays, ok := config["max_days"]; ok {
-		parts = append(parts, fmt.Sprintf("(最大%v日)", maxDays))
+	var sections []string
+	if all := limit(cfg.HourLimitConfig); all != "" {
+		sections = append(sections, "全員 "+all)
 	}
-	if minHours, ok := config["min_hours"]; ok {
-		parts = append(parts, fmt.Sprintf("(最低%v時間)", minHours))
+	for _, employmentType := range slices.Sorted(maps.Keys(cfg.ByEmploymentType)) {
+		sections = append(sections, fmt.Sprintf("雇用形態 %s は%s", employmentType, limit(cfg.ByEmploymentType[employmentType])))
 	}
-	if maxCount, ok := config["max_count"]; ok {
-		parts = append(parts, fmt.Sprintf("(最大%v人)", maxCount))
+	for _, staffID := range slices.Sorted(maps.Keys(cfg.ByStaff)) {
+		staffName := staffNames[staffID]
+		if staffName == "" {
+			staffName = staffID
+		}
+		sections = append(sections, fmt.Sprintf("%s(id: %s) は%s", staffName, staffID, limit(cfg.ByStaff[staffID])))
 	}
+	desc := fmt.Sprintf("%s: 月間労働時間（休憩を除く）を次の範囲にすること: %s", name, strings.Join(sections, " / "))
+	if len(cfg.ByEmploymentType) > 0 || len(cfg.ByStaff) > 0 {
+		desc += "（スタッフ別、雇用形態別、全員の順に優先）"
+	}
+	return desc
+}
 
-	return strings.Join(parts, " ")
+// describeFixedDayOff renders a fixed_day_off constraint with the concrete
+// dates it covers in the target month, so the LLM doesn't have to work out
+// weekdays itself.
+func describeFixedDayOff(name string, config map[string]interface{}, yearMonth string, staffNames map[string]string) string {
+	staffID, _ := config["staff_id"].(string)
+	staffName := staffNames[staffID]
+	if staffName == "" {
+		staffName = staffID
+	}
+
+	weekdays := make(map[time.Weekday]bool)
+	var labels []string
+	for _, wd := range parseWeekdays(config) {
+		weekdays[wd] = true
+		labels = append(labels, model.WeekdayLabel(wd)+"曜")
+	}
+
+	exceptions := make(map[string]bool)
+	for _, d := range model.StringList(config["except_dates"]) {
+		exceptions[d] = true
+	}
+
+	var dates []string
+	if first, err := time.Parse("2006-01", yearMonth); err == nil {
+		for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+			date := d.Format("2006-01-02")
+			if weekdays[d.Weekday()] && !exceptions[date] {
+				dates = append(dates, date)
+			}
+		}
+	}
+
+	desc := fmt.Sprintf("%s: %s(id: %s) は毎週%sが固定休。次の日には絶対にシフトを入れないこと: %s",
+		name, staffName, staffID, strings.Join(labels, "・"), strings.Join(dates, ", "))
+	if len(exceptions) > 0 {
+		desc += fmt.Sprintf("（例外として出勤可: %s）", strings.Join(model.StringList(config["except_dates"]), ", "))
+	}
+	return desc
+}
+
+// describeStaffCompatibility spells out the staff pairing rule with names and
+// ids so the LLM knows exactly who may or must work together.
+func describeStaffCompatibility(name string, config map[string]interface{}, staffNames map[string]string) string {
+	label := func(ids []string) string {
+		parts := make([]string, 0, len(ids))
+		for _, id := range ids {
+			n := staffNames[id]
+			if n == "" {
+				n = id
+			}
+			parts = append(parts, fmt.Sprintf("%s(id: %s)", n, id))
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	rule, _ := config["rule"].(string)
+	switch rule {
+	case "avoid_together":
+		staffs := label(model.StringList(config["staff_ids"]))
+		if scope, _ := config["scope"].(string); scope == "overlap" {
+			return fmt.Sprintf("%s: %s のうち2人以上の勤務時間帯を重ねないこと（同日勤務は可）", name, staffs)
+		}
+		return fmt.Sprintf("%s: %s のうち2人以上を同じ日に勤務させないこと", name, staffs)
+	case "prefer_together":
+		staffs := label(model.StringList(config["staff_ids"]))
+		if scope, _ := config["scope"].(string); scope == "overlap" {
+			return fmt.Sprintf("%s: %s はできるだけ同じ日の重なる時間帯に勤務させること（努力目標）", name, staffs)
+		}
+		return fmt.Sprintf("%s: %s はできるだけ同じ日に勤務させること（努力目標）", name, staffs)
+	case "mentor_required":
+		return fmt.Sprintf("%s: %s が勤務する時間帯には、必ず %s のいずれかが同じ時間帯に勤務していること",
+			name, label(model.StringList(config["trainee_ids"])), label(model.StringList(config["mentor_ids"])))
+	}
+	return name
+}
+
+// describeCoverage lists the required headcount per time range. Breaks don't
+// count as coverage, so the LLM is told to stagger them.
+func describeCoverage(name string, configJSON []byte) string {
+	var cfg model.CoverageConfig
+	if err := json.Unmarshal(configJSON, &cfg); err != nil {
+		return name
+	}
+	render := func(label string, reqs []model.CoverageRequirement) string {
+		parts := make([]string, 0, len(reqs))
+		for _, r := range reqs {
+			parts = append(parts, fmt.Sprintf("%s〜%s は%d人以上", r.Start, r.End, r.MinCount))
+		}
+		return fmt.Sprintf("%s: %s", label, strings.Join(parts, ", "))
+	}
+	var sections []string
+	if len(cfg.Weekday) > 0 {
+		sections = append(sections, render("平日", cfg.Weekday))
+	}
+	if len(cfg.Weekend) > 0 {
+		sections = append(sections, render("土日", cfg.Weekend))
+	}
+	return fmt.Sprintf("%s: 各時間帯に実際に勤務している人数（休憩中は含まない）を次の人数以上にすること。休憩は時間をずらして取らせること。%s",
+		name, strings.Join(sections, " / "))
+}
+
+// parseWeekdays reads "weekdays" (0=日〜6=土), falling back to the legacy
+// single "day_of_week" key.
+func parseWeekdays(config map[string]interface{}) []time.Weekday {
+	var result []time.Weekday
+	if list, ok := config["weekdays"].([]interface{}); ok {
+		for _, v := range list {
+			if f, ok := v.(float64); ok && f >= 0 && f <= 6 {
+				result = append(result, time.Weekday(int(f)))
+			}
+		}
+	} else if f, ok := config["day_of_week"].(float64); ok && f >= 0 && f <= 6 {
+		result = append(result, time.Weekday(int(f)))
+	}
+	return result
 }
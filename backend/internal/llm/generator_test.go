@@ -0,0 +1,156 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"shift-app/internal/model"
+)
+
+func TestStrategyHints(t *testing.T) {
+	for _, strategy := range model.PatternStrategies {
+		if strategyHints[strategy] == "" {
+			t.Errorf("strategy %q has no hint", strategy)
+		}
+	}
+}
+
+func TestBuildUserPromptStrategy(t *testing.T) {
+	tests := []struct {
+		name       string
+		patternIdx int
+		strategy   string
+		want       string
+		notWant    string
+	}{
+		{"first sequential pattern", 0, "", "", "## 追加指示"},
+		{"later sequential pattern", 1, "", "前のパターンとは異なるアプローチ", "## 作成方針"},
+		{"parallel pattern", 1, model.StrategyWeekendHeavy, strategyHints[model.StrategyWeekendHeavy], "## 追加指示"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := model.GenerationParams{PatternIdx: tt.patternIdx, Strategy: tt.strategy}
+			prompt := buildUserPrompt("2025-01", nil, nil, nil, nil, nil, params, nil, nil)
+			if tt.want != "" && !strings.Contains(prompt, tt.want) {
+				t.Errorf("prompt does not contain %q", tt.want)
+			}
+			if strings.Contains(prompt, tt.notWant) {
+				t.Errorf("prompt contains %q", tt.notWant)
+			}
+		})
+	}
+}
+
+func TestBuildUserPromptGuidance(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  model.GenerationParams
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "no guidance",
+			params:  model.GenerationParams{},
+			notWant: []string{"## 管理者からの指示", "## 重視する観点"},
+		},
+		{
+			name:   "instruction",
+			params: model.GenerationParams{Instruction: "佐藤さんの土日出勤を増やしてください"},
+			want:   []string{"## 管理者からの指示", "佐藤さんの土日出勤を増やしてください"},
+		},
+		{
+			name:    "weights as percentages",
+			params:  model.GenerationParams{Weights: &model.ObjectiveWeights{Fairness: 2, Cost: 1, Preference: 1}},
+			want:    []string{"## 重視する観点", "公平性（週末の出勤回数をスタッフ間で揃える）: 50%", "人件費（総勤務時間を月間労働時間の希望の下限に近づける）: 25%", "希望の充足（◎・○の日に勤務を割り当てる）: 25%"},
+			notWant: []string{"ソフト制約（優先度の高いものから守る）"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt := buildUserPrompt("2025-01", nil, nil, nil, nil, nil, tt.params, nil, nil)
+			for _, want := range tt.want {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("prompt does not contain %q", want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(prompt, notWant) {
+					t.Errorf("prompt contains %q", notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestDescribeMonthlyHours(t *testing.T) {
+	config := `{"max_hours": 160, "by_employment_type": {"part_time": {"max_hours": 80}}, "by_staff": {"s1": {"min_hours": 40, "max_hours": 60}}}`
+	desc := buildConstraintDescription(constraintInfo{Name: "月間上限", Category: "monthly_hours", Config: []byte(config)}, "2025-01", map[string]string{"s1": "田中"})
+
+	for _, want := range []string{
+		"全員 最大160時間",
+		"雇用形態 part_time は最大80時間",
+		"田中(id: s1) は最低40時間・最大60時間",
+		"スタッフ別、雇用形態別、全員の順に優先",
+	} {
+		if !strings.Contains(desc, want) {
+			t.Errorf("description does not contain %q: %s", want, desc)
+		}
+	}
+}
+
+func TestBuildScopeSection(t *testing.T) {
+	staffs := []staffInfo{{ID: "s1", Name: "田中"}, {ID: "s2", Name: "鈴木"}}
+	info := &scopeInfo{
+		Scope: &model.RegenerationScope{StartDate: "2025-02-10", EndDate: "2025-02-16", StaffIDs: []string{"s1"}},
+		Locked: []model.LLMShiftEntry{
+			{StaffID: "s1", Date: "2025-02-03", StartTime: "09:00", EndTime: "18:00", BreakMinutes: 60},
+			{StaffID: "s1", Date: "2025-02-09", StartTime: "13:00", EndTime: "22:00", BreakMinutes: 60},
+			{StaffID: "s2", Date: "2025-02-12", StartTime: "09:00", EndTime: "18:00", BreakMinutes: 60},
+		},
+	}
+
+	section := buildScopeSection(info, staffs)
+	for _, want := range []string{
+		"- 期間: 2025-02-10〜2025-02-16",
+		"- スタッフ: 田中(id: s1)",
+		"- 2025-02-09 田中(id: s1) 13:00-22:00 休憩60分",
+		"- 2025-02-12 鈴木(id: s2) 09:00-18:00 休憩60分",
+		"- 田中: 16.0h",
+	} {
+		if !strings.Contains(section, want) {
+			t.Errorf("section does not contain %q:\n%s", want, section)
+		}
+	}
+	// Locked entries far from the scope only count toward hours
+	if strings.Contains(section, "2025-02-03") {
+		t.Errorf("section lists an entry outside the scope's neighborhood:\n%s", section)
+	}
+	if strings.Contains(section, "- 鈴木: ") {
+		t.Errorf("section lists hours of staff outside the scope:\n%s", section)
+	}
+}
+
+func TestBuildConstraintDescriptionTyped(t *testing.T) {
+	tests := []struct {
+		category string
+		config   string
+		want     string
+	}{
+		{"max_consecutive_days", `{"max_days": 5}`, "制約 (最大5日)"},
+		{"rest_hours", `{"min_hours": 11.5}`, "制約 (最低11.5時間)"},
+		{"max_staff", `{"max_count": 4}`, "制約 (最大4人)"},
+		{"min_staff", `{"min_count": 2, "time_ranges": [{"start": "17:00", "end": "21:00", "min_count": 3}]}`, "制約 (最低2人) (17:00〜21:00 は3人以上)"},
+		{"min_staff", `{}`, "制約"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			desc := buildConstraintDescription(constraintInfo{Name: "制約", Category: tt.category, Config: []byte(tt.config)}, "2025-01", nil)
+			if desc != tt.want {
+				t.Errorf("got %q, want %q", desc, tt.want)
+			}
+		})
+	}
+}
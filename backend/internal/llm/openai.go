@@ -0,0 +1,154 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIProvider calls an OpenAI-compatible Chat Completions endpoint with
+// function calling (OpenAI, vLLM, Ollama, LM Studio, ...)
+type OpenAIProvider struct {
+	baseURL string
+	apiKey  string
+	opts    ProviderOptions
+	client  *http.Client
+}
+
+func NewOpenAIProvider(baseURL, apiKey string, opts ProviderOptions) *OpenAIProvider {
+	return &OpenAIProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		opts:    opts,
+		client:  &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content,omitempty"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIRequest struct {
+	Model       string                   `json:"model"`
+	Messages    []openAIMessage          `json:"messages"`
+	Tools       []map[string]interface{} `json:"tools"`
+	ToolChoice  map[string]interface{}   `json:"tool_choice"`
+	Temperature float64                  `json:"temperature"`
+	MaxTokens   int                      `json:"max_tokens"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (p *OpenAIProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	body, err := json.Marshal(openAIRequest{
+		Model:    p.opts.Model,
+		Messages: openAIMessages(req.System, req.Turns),
+		Tools: []map[string]interface{}{{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        req.Tool.Name,
+				"description": req.Tool.Description,
+				"parameters":  req.Tool.InputSchema,
+			},
+		}},
+		ToolChoice: map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": req.Tool.Name},
+		},
+		Temperature: p.opts.Temperature,
+		MaxTokens:   p.opts.MaxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	res, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("LLM API エラー: %w", err)
+	}
+	defer res.Body.Close()
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("LLM API エラー: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("LLM API エラー: status %d: %s", res.StatusCode, strings.TrimSpace(string(raw)))
+	}
+
+	var decoded openAIResponse
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, fmt.Errorf("LLM API レスポンス解析エラー: %w", err)
+	}
+	if len(decoded.Choices) == 0 {
+		return nil, fmt.Errorf("LLM API レスポンスに choices がありません")
+	}
+
+	choice := decoded.Choices[0]
+	completion := &Completion{
+		Truncated:    choice.FinishReason == "length",
+		InputTokens:  decoded.Usage.PromptTokens,
+		OutputTokens: decoded.Usage.CompletionTokens,
+	}
+	for _, call := range choice.Message.ToolCalls {
+		if call.Function.Name == req.Tool.Name {
+			completion.ToolCall = &ToolCall{ID: call.ID, Name: call.Function.Name, Input: json.RawMessage(call.Function.Arguments)}
+			break
+		}
+	}
+	return completion, nil
+}
+
+func openAIMessages(system string, turns []Turn) []openAIMessage {
+	messages := []openAIMessage{{Role: "system", Content: system}}
+	for _, t := range turns {
+		switch {
+		case t.ToolResult != nil:
+			// Tool results are a separate role; the is_error flag has no
+			// equivalent, so the content alone carries the feedback
+			messages = append(messages, openAIMessage{Role: "tool", ToolCallID: t.ToolResult.CallID, Content: t.ToolResult.Content})
+		case t.ToolCall != nil:
+			call := openAIToolCall{ID: t.ToolCall.ID, Type: "function"}
+			call.Function.Name = t.ToolCall.Name
+			call.Function.Arguments = string(t.ToolCall.Input)
+			messages = append(messages, openAIMessage{Role: "assistant", Content: t.Text, ToolCalls: []openAIToolCall{call}})
+		default:
+			messages = append(messages, openAIMessage{Role: t.Role, Content: t.Text})
+		}
+	}
+	return messages
+}
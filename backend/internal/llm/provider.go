@@ -0,0 +1,144 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"shift-app/internal/config"
+	"shift-app/internal/model"
+)
+
+// Provider sends a conversation to an LLM and returns its reply. The tool in
+// the request is always forced, so a well-behaved reply is a single call of it.
+type Provider interface {
+	Complete(ctx context.Context, req CompletionRequest) (*Completion, error)
+}
+
+// ProviderOptions are the sampling settings shared by every provider
+type ProviderOptions struct {
+	Model       string
+	Temperature float64
+	MaxTokens   int
+}
+
+// Tool is a provider-neutral function definition. InputSchema is a complete
+// JSON Schema object.
+type Tool struct {
+	Name        string
+	Description string
+	InputSchema map[string]interface{}
+}
+
+// ToolCall is a tool invocation made by the model
+type ToolCall struct {
+	ID    string
+	Name  string
+	Input json.RawMessage
+}
+
+// ToolResult answers a ToolCall in the next user turn
+type ToolResult struct {
+	CallID  string
+	Content string
+	IsError bool
+}
+
+// Turn is one message of a conversation. A user turn has Text or
+// ToolResult; an assistant turn has ToolCall.
+type Turn struct {
+	Role       string // "user" or "assistant"
+	Text       string
+	ToolCall   *ToolCall
+	ToolResult *ToolResult
+}
+
+type CompletionRequest struct {
+	System string
+	Turns  []Turn
+	Tool   Tool
+}
+
+type Completion struct {
+	// ToolCall is nil when the model answered without calling the tool
+	ToolCall *ToolCall
+	// Truncated reports that the reply hit the max token limit
+	Truncated    bool
+	InputTokens  int
+	OutputTokens int
+}
+
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderOpenAI    = "openai"
+	ProviderReplay    = "replay"
+)
+
+// NewProvider builds the provider selected by cfg.LLMProvider. When
+// cfg.LLMRecordDir is set, every reply is also saved there as a fixture for
+// the replay provider.
+func NewProvider(cfg *config.Config) (Provider, error) {
+	opts := ProviderOptions{
+		Model:       cfg.LLMModel,
+		Temperature: cfg.LLMTemperature,
+		MaxTokens:   cfg.LLMMaxTokens,
+	}
+
+	var p Provider
+	switch cfg.LLMProvider {
+	case ProviderAnthropic:
+		p = NewAnthropicProvider(cfg.AnthropicAPIKey, opts)
+	case ProviderOpenAI:
+		if cfg.OpenAIBaseURL == "" {
+			return nil, fmt.Errorf("LLM_PROVIDER=%s には OPENAI_BASE_URL が必要です", ProviderOpenAI)
+		}
+		p = NewOpenAIProvider(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey, opts)
+	case ProviderReplay:
+		replay, err := NewReplayProvider(cfg.LLMReplayDir)
+		if err != nil {
+			return nil, err
+		}
+		p = replay
+	default:
+		return nil, fmt.Errorf("未対応の LLM_PROVIDER です: %q", cfg.LLMProvider)
+	}
+
+	if cfg.LLMRecordDir != "" {
+		p = NewRecordingProvider(p, cfg.LLMRecordDir)
+	}
+	return p, nil
+}
+
+// tracingProvider records every call into a model.GenerationTrace
+type tracingProvider struct {
+	provider Provider
+	trace    *model.GenerationTrace
+}
+
+func (p *tracingProvider) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	call := model.LLMCall{}
+	if n := len(req.Turns); n > 0 {
+		if last := req.Turns[n-1]; last.ToolResult != nil {
+			call.Prompt = last.ToolResult.Content
+		} else {
+			call.Prompt = last.Text
+		}
+	}
+	p.trace.SystemPrompt = req.System
+
+	started := time.Now()
+	completion, err := p.provider.Complete(ctx, req)
+	call.LatencyMs = int(time.Since(started).Milliseconds())
+	if err != nil {
+		call.Error = err.Error()
+	} else {
+		call.Truncated = completion.Truncated
+		call.InputTokens, call.OutputTokens = completion.InputTokens, completion.OutputTokens
+		if completion.ToolCall != nil {
+			call.Response = completion.ToolCall.Input
+		}
+	}
+	p.trace.Calls = append(p.trace.Calls, call)
+	return completion, err
+}
@@ -0,0 +1,193 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"shift-app/internal/model"
+)
+
+const validSubmission = `{"reasoning": "r", "entries": [
+	{"staff_id": "s1", "date": "2025-02-03", "start_time": "09:00", "end_time": "17:00", "break_minutes": 60}
+], "constraint_violations": []}`
+
+func writeFixtures(t *testing.T, fixtures ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i, f := range fixtures {
+		name := filepath.Join(dir, string(rune('a'+i))+".json")
+		if err := os.WriteFile(name, []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestSubmitSchedule_Replay(t *testing.T) {
+	tests := []struct {
+		name        string
+		fixtures    []string
+		wantEntries int
+		wantField   string
+	}{
+		{
+			name:        "valid on first reply",
+			fixtures:    []string{`{"input": ` + validSubmission + `}`},
+			wantEntries: 1,
+		},
+		{
+			name:        "schema error fixed in the same conversation",
+			fixtures:    []string{`{"input": {"entries": []}}`, `{"input": ` + validSubmission + `}`},
+			wantEntries: 1,
+		},
+		{
+			name:      "schema error never fixed",
+			fixtures:  []string{`{"input": {"entries": []}}`},
+			wantField: "reasoning",
+		},
+		{
+			name:      "truncated without tool call",
+			fixtures:  []string{`{"input": null, "truncated": true}`},
+			wantField: "input",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewReplayProvider(writeFixtures(t, tt.fixtures...))
+			if err != nil {
+				t.Fatalf("NewReplayProvider: %v", err)
+			}
+
+			result, err := submitSchedule(context.Background(), provider, "system", "user", nil)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(result.Entries) != tt.wantEntries {
+					t.Errorf("got %d entries, want %d", len(result.Entries), tt.wantEntries)
+				}
+				return
+			}
+			var schemaErr *model.OutputSchemaError
+			if !errors.As(err, &schemaErr) || schemaErr.Details[0].Field != tt.wantField {
+				t.Errorf("got error %v, want schema error on %q", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestNewReplayProvider_EmptyDir(t *testing.T) {
+	if _, err := NewReplayProvider(t.TempDir()); err == nil {
+		t.Error("expected error for a directory without fixtures")
+	}
+}
+
+func TestRecordingProvider(t *testing.T) {
+	replay, err := NewReplayProvider(writeFixtures(t, `{"input": `+validSubmission+`}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if _, err := submitSchedule(context.Background(), NewRecordingProvider(replay, dir), "system", "user", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// What was recorded must replay to the same result
+	again, err := NewReplayProvider(dir)
+	if err != nil {
+		t.Fatalf("recorded fixtures not readable: %v", err)
+	}
+	result, err := submitSchedule(context.Background(), again, "system", "user", nil)
+	if err != nil || len(result.Entries) != 1 {
+		t.Errorf("replay of recording: result=%+v err=%v", result, err)
+	}
+}
+
+func TestOpenAIProvider(t *testing.T) {
+	var got openAIRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("unexpected request: %s auth=%q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		args, _ := json.Marshal(validSubmission)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"finish_reason": "tool_calls", "message": {"role": "assistant", "tool_calls": [
+			{"id": "call_1", "type": "function", "function": {"name": "` + submitToolName + `", "arguments": ` + string(args) + `}}
+		]}}]}`))
+	}))
+	defer srv.Close()
+
+	provider := NewOpenAIProvider(srv.URL+"/v1/", "key", ProviderOptions{Model: "local-model", Temperature: 0.2, MaxTokens: 100})
+	result, err := submitSchedule(context.Background(), provider, "system", "user", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Entries) != 1 {
+		t.Errorf("got %d entries, want 1", len(result.Entries))
+	}
+	if got.Model != "local-model" || got.MaxTokens != 100 || len(got.Messages) != 2 || got.Messages[0].Role != "system" {
+		t.Errorf("unexpected request: %+v", got)
+	}
+	if len(got.Tools) != 1 || got.ToolChoice["type"] != "function" {
+		t.Errorf("tool not forced: tools=%v choice=%v", got.Tools, got.ToolChoice)
+	}
+}
+
+func TestAnthropicTool(t *testing.T) {
+	raw, err := json.Marshal(anthropicTool(submitTool()))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var tool struct {
+		Name        string `json:"name"`
+		InputSchema struct {
+			Type       string                     `json:"type"`
+			Required   []string                   `json:"required"`
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"input_schema"`
+	}
+	if err := json.Unmarshal(raw, &tool); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if tool.Name != submitToolName || tool.InputSchema.Type != "object" || len(tool.InputSchema.Required) != 3 || len(tool.InputSchema.Properties) != 3 {
+		t.Errorf("unexpected tool definition: %s", raw)
+	}
+}
+
+func TestTracingProvider(t *testing.T) {
+	replay, err := NewReplayProvider(writeFixtures(t,
+		`{"input": {"entries": []}, "input_tokens": 100, "output_tokens": 20}`,
+		`{"input": `+validSubmission+`, "input_tokens": 150, "output_tokens": 80}`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := &model.GenerationTrace{}
+	if _, err := submitSchedule(context.Background(), &tracingProvider{provider: replay, trace: trace}, "system", "user", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if trace.SystemPrompt != "system" || len(trace.Calls) != 2 {
+		t.Fatalf("unexpected trace: %+v", trace)
+	}
+	if trace.Calls[0].Prompt != "user" || string(trace.Calls[0].Response) != `{"entries": []}` {
+		t.Errorf("first call = %+v", trace.Calls[0])
+	}
+	// The second turn is the schema feedback
+	if trace.Calls[1].Prompt != schemaFeedback([]model.ErrorDetail{{Field: "reasoning", Message: "必須です"}, {Field: "constraint_violations", Message: "必須です"}}) {
+		t.Errorf("second prompt = %q", trace.Calls[1].Prompt)
+	}
+	if in, out := trace.Tokens(); in != 250 || out != 100 {
+		t.Errorf("tokens = %d/%d, want 250/100", in, out)
+	}
+}
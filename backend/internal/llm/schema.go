@@ -0,0 +1,199 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"shift-app/internal/model"
+)
+
+// submitToolName is the tool the model must call to hand in a schedule
+const submitToolName = "submit_shift_schedule"
+
+// maxSchemaDetails caps the schema errors reported back to the model
+const maxSchemaDetails = 20
+
+var (
+	datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	timePattern = regexp.MustCompile(`^([01]\d|2[0-4]):[0-5]\d$`)
+)
+
+// submitTool describes model.LLMResponse as the input schema of a tool, so
+// the reply arrives as a JSON object instead of free text
+func submitTool() Tool {
+	return Tool{
+		Name:        submitToolName,
+		Description: "作成したシフトスケジュールを提出します。シフトは必ずこのツールで提出してください。",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"reasoning": map[string]interface{}{
+					"type":        "string",
+					"description": "このパターンの特徴と判断理由の説明",
+				},
+				"entries": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"staff_id":      map[string]interface{}{"type": "string"},
+							"date":          map[string]interface{}{"type": "string", "pattern": datePattern.String(), "description": "勤務開始日 (YYYY-MM-DD)"},
+							"start_time":    map[string]interface{}{"type": "string", "pattern": timePattern.String(), "description": "HH:MM"},
+							"end_time":      map[string]interface{}{"type": "string", "pattern": timePattern.String(), "description": "HH:MM。start_time 以前なら翌日"},
+							"break_minutes": map[string]interface{}{"type": "integer", "minimum": 0},
+						},
+						"required":             []string{"staff_id", "date", "start_time", "end_time", "break_minutes"},
+						"additionalProperties": false,
+					},
+				},
+				"constraint_violations": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"constraint_name": map[string]interface{}{"type": "string"},
+							"type":            map[string]interface{}{"type": "string", "enum": []string{"hard", "soft"}},
+							"message":         map[string]interface{}{"type": "string"},
+						},
+						"required":             []string{"constraint_name", "type", "message"},
+						"additionalProperties": false,
+					},
+				},
+			},
+			"required":             []string{"reasoning", "entries", "constraint_violations"},
+			"additionalProperties": false,
+		},
+	}
+}
+
+// parseSubmission checks a tool input against the submitTool schema and
+// decodes it. All problems found (up to maxSchemaDetails) are returned with
+// JSON-path-like fields such as "entries[3].start_time".
+func parseSubmission(input json.RawMessage) (*model.LLMResponse, []model.ErrorDetail) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(input, &root); err != nil || root == nil {
+		return nil, []model.ErrorDetail{{Field: "input", Message: "JSONオブジェクトではありません"}}
+	}
+
+	var details []model.ErrorDetail
+	add := func(field, format string, args ...interface{}) {
+		details = append(details, model.ErrorDetail{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	checkKeys(root, "", []string{"reasoning", "entries", "constraint_violations"}, add)
+	if v, ok := root["reasoning"]; ok {
+		if _, isStr := v.(string); !isStr {
+			add("reasoning", "文字列で指定してください")
+		}
+	}
+
+	if v, ok := root["entries"]; ok {
+		entries, isArr := v.([]interface{})
+		if !isArr {
+			add("entries", "配列で指定してください")
+		}
+		for i, raw := range entries {
+			field := fmt.Sprintf("entries[%d]", i)
+			entry, isObj := raw.(map[string]interface{})
+			if !isObj {
+				add(field, "オブジェクトで指定してください")
+				continue
+			}
+			checkKeys(entry, field+".", []string{"staff_id", "date", "start_time", "end_time", "break_minutes"}, add)
+			if s, isStr := entry["staff_id"].(string); entry["staff_id"] != nil && (!isStr || s == "") {
+				add(field+".staff_id", "空でない文字列で指定してください")
+			}
+			checkPattern(entry, field, "date", datePattern, "YYYY-MM-DD", add)
+			checkPattern(entry, field, "start_time", timePattern, "HH:MM", add)
+			checkPattern(entry, field, "end_time", timePattern, "HH:MM", add)
+			if v, ok := entry["break_minutes"]; ok {
+				if n, isNum := v.(float64); !isNum || n < 0 || n != float64(int(n)) {
+					add(field+".break_minutes", "0以上の整数で指定してください")
+				}
+			}
+		}
+	}
+
+	if v, ok := root["constraint_violations"]; ok {
+		violations, isArr := v.([]interface{})
+		if !isArr {
+			add("constraint_violations", "配列で指定してください")
+		}
+		for i, raw := range violations {
+			field := fmt.Sprintf("constraint_violations[%d]", i)
+			cv, isObj := raw.(map[string]interface{})
+			if !isObj {
+				add(field, "オブジェクトで指定してください")
+				continue
+			}
+			checkKeys(cv, field+".", []string{"constraint_name", "type", "message"}, add)
+			if t, ok := cv["type"]; ok && t != "hard" && t != "soft" {
+				add(field+".type", "hard または soft で指定してください")
+			}
+		}
+	}
+
+	if len(details) > maxSchemaDetails {
+		details = details[:maxSchemaDetails]
+	}
+	if len(details) > 0 {
+		return nil, details
+	}
+
+	var result model.LLMResponse
+	if err := json.Unmarshal(input, &result); err != nil {
+		return nil, []model.ErrorDetail{{Field: "input", Message: err.Error()}}
+	}
+	if result.ConstraintViolations == nil {
+		result.ConstraintViolations = []model.ConstraintViolation{}
+	}
+	return &result, nil
+}
+
+// checkKeys reports missing required keys and any key not in required
+func checkKeys(obj map[string]interface{}, prefix string, required []string, add func(string, string, ...interface{})) {
+	known := make(map[string]bool, len(required))
+	for _, key := range required {
+		known[key] = true
+		if _, ok := obj[key]; !ok {
+			add(prefix+key, "必須です")
+		}
+	}
+	for _, key := range sortedKeys(obj) {
+		if !known[key] {
+			add(prefix+key, "未対応の項目です")
+		}
+	}
+}
+
+func checkPattern(obj map[string]interface{}, prefix, key string, pattern *regexp.Regexp, format string, add func(string, string, ...interface{})) {
+	v, ok := obj[key]
+	if !ok {
+		return
+	}
+	if s, isStr := v.(string); !isStr || !pattern.MatchString(s) {
+		add(prefix+"."+key, "%s 形式の文字列で指定してください", format)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// schemaFeedback renders schema errors as a tool_result for the model
+func schemaFeedback(details []model.ErrorDetail) string {
+	var sb strings.Builder
+	sb.WriteString("提出内容がスキーマに一致しません。以下を修正して、もう一度 " + submitToolName + " を呼び出してください。\n")
+	for _, d := range details {
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", d.Field, d.Message))
+	}
+	return sb.String()
+}
@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseSubmission(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantFields []string
+	}{
+		{
+			name: "valid",
+			input: `{"reasoning": "r", "entries": [
+				{"staff_id": "s1", "date": "2025-02-03", "start_time": "09:00", "end_time": "17:00", "break_minutes": 60},
+				{"staff_id": "s2", "date": "2025-02-03", "start_time": "22:00", "end_time": "06:00", "break_minutes": 60}
+			], "constraint_violations": [{"constraint_name": "c", "type": "soft", "message": "m"}]}`,
+		},
+		{
+			name:       "not an object",
+			input:      `[1, 2]`,
+			wantFields: []string{"input"},
+		},
+		{
+			name:       "missing top-level keys",
+			input:      `{"entries": []}`,
+			wantFields: []string{"reasoning", "constraint_violations"},
+		},
+		{
+			name: "bad entry fields",
+			input: `{"reasoning": "r", "entries": [
+				{"staff_id": "", "date": "2025/02/03", "start_time": "9:00", "end_time": "17:00", "break_minutes": -1, "note": "x"}
+			], "constraint_violations": []}`,
+			wantFields: []string{"entries[0].note", "entries[0].staff_id", "entries[0].date", "entries[0].start_time", "entries[0].break_minutes"},
+		},
+		{
+			name: "missing entry field",
+			input: `{"reasoning": "r", "entries": [
+				{"staff_id": "s1", "date": "2025-02-03", "start_time": "09:00", "break_minutes": 0}
+			], "constraint_violations": []}`,
+			wantFields: []string{"entries[0].end_time"},
+		},
+		{
+			name:       "invalid violation type",
+			input:      `{"reasoning": "r", "entries": [], "constraint_violations": [{"constraint_name": "c", "type": "warn", "message": "m"}]}`,
+			wantFields: []string{"constraint_violations[0].type"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, details := parseSubmission(json.RawMessage(tt.input))
+
+			var got []string
+			for _, d := range details {
+				got = append(got, d.Field)
+			}
+			if len(got) != len(tt.wantFields) {
+				t.Fatalf("got fields %v, want %v", got, tt.wantFields)
+			}
+			for i := range got {
+				if got[i] != tt.wantFields[i] {
+					t.Errorf("field[%d] = %q, want %q", i, got[i], tt.wantFields[i])
+				}
+			}
+			if len(tt.wantFields) == 0 && (result == nil || len(result.Entries) != 2) {
+				t.Errorf("expected decoded result with 2 entries, got %+v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"shift-app/internal/model"
+)
+
+// scopeInfo describes a partial regeneration: only entries inside Scope are
+// asked for, around the Locked entries of the existing pattern
+type scopeInfo struct {
+	Scope  *model.RegenerationScope
+	Locked []model.LLMShiftEntry
+}
+
+// scopeDays keeps the days inside the scope's date range
+func scopeDays(days []model.BusinessDay, scope *model.RegenerationScope) []model.BusinessDay {
+	var result []model.BusinessDay
+	for _, d := range days {
+		if scope.ContainsDate(d.Date) {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// scopeStaffs keeps the staff inside the scope
+func scopeStaffs(staffs []staffInfo, scope *model.RegenerationScope) []staffInfo {
+	if len(scope.StaffIDs) == 0 {
+		return staffs
+	}
+	var result []staffInfo
+	for _, s := range staffs {
+		if slices.Contains(scope.StaffIDs, s.ID) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// nearScope reports whether a locked entry on date matters to shifts in the
+// scope: inside its date range or the day before or after it, where rest
+// hours and consecutive days cross the boundary
+func nearScope(scope *model.RegenerationScope, date string) bool {
+	day, ok := model.DayIndex(date)
+	if !ok {
+		return false
+	}
+	if start, ok := model.DayIndex(scope.StartDate); ok && day < start-1 {
+		return false
+	}
+	if end, ok := model.DayIndex(scope.EndDate); ok && day > end+1 {
+		return false
+	}
+	return true
+}
+
+// buildScopeSection tells the model which part of the pattern to redo and
+// which shifts it must work around
+func buildScopeSection(info *scopeInfo, staffs []staffInfo) string {
+	staffNames := make(map[string]string, len(staffs))
+	for _, s := range staffs {
+		staffNames[s.ID] = s.Name
+	}
+	scope := info.Scope
+
+	var sb strings.Builder
+	sb.WriteString("## 再生成の範囲\n")
+	sb.WriteString("既存のシフトの一部だけを作り直します。提出するのは次の範囲のエントリのみとし、範囲外のエントリは含めないでください。\n")
+	period := "月全体"
+	if scope.StartDate != "" || scope.EndDate != "" {
+		period = fmt.Sprintf("%s〜%s", scope.StartDate, scope.EndDate)
+	}
+	sb.WriteString(fmt.Sprintf("- 期間: %s\n", period))
+	if len(scope.StaffIDs) == 0 {
+		sb.WriteString("- スタッフ: 全員\n")
+	} else {
+		names := make([]string, 0, len(scope.StaffIDs))
+		for _, id := range scope.StaffIDs {
+			names = append(names, fmt.Sprintf("%s(id: %s)", staffNames[id], id))
+		}
+		sb.WriteString(fmt.Sprintf("- スタッフ: %s\n", strings.Join(names, ", ")))
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString("## 固定シフト（変更不可）\n")
+	sb.WriteString("以下は残すシフトです。同じスタッフ・同じ日に重ねず、1日の人数・連続勤務日数・勤務間の休息の計算に含めてください。\n")
+	var near []model.LLMShiftEntry
+	for _, e := range info.Locked {
+		if nearScope(scope, e.Date) {
+			near = append(near, e)
+		}
+	}
+	sort.SliceStable(near, func(i, j int) bool { return near[i].Date < near[j].Date })
+	if len(near) == 0 {
+		sb.WriteString("- なし\n")
+	}
+	for _, e := range near {
+		sb.WriteString(fmt.Sprintf("- %s %s(id: %s) %s-%s 休憩%d分\n", e.Date, staffNames[e.StaffID], e.StaffID, e.StartTime, e.EndTime, e.BreakMinutes))
+	}
+
+	// Hours of the locked shifts count toward the monthly hour targets
+	minutes := make(map[string]int)
+	for _, e := range info.Locked {
+		minutes[e.StaffID] += model.WorkMinutes(e.StartTime, e.EndTime, e.BreakMinutes)
+	}
+	sb.WriteString("\n固定シフトの勤務時間（月間労働時間の希望は、これを含めた月合計で判断すること）:\n")
+	for _, s := range scopeStaffs(staffs, scope) {
+		sb.WriteString(fmt.Sprintf("- %s: %.1fh\n", s.Name, float64(minutes[s.ID])/60))
+	}
+	return sb.String()
+}
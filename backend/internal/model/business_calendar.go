@@ -0,0 +1,135 @@
+package model
+
+import (
+	"time"
+)
+
+// BusinessHours represents the business_hours table (regular hours per weekday)
+type BusinessHours struct {
+	Weekday   int     `json:"weekday"`
+	OpenTime  *string `json:"open_time"`
+	CloseTime *string `json:"close_time"`
+	IsClosed  bool    `json:"is_closed"`
+}
+
+// BusinessSpecialDay represents the business_special_days table
+type BusinessSpecialDay struct {
+	Date      string    `json:"date"`
+	IsClosed  bool      `json:"is_closed"`
+	OpenTime  *string   `json:"open_time"`
+	CloseTime *string   `json:"close_time"`
+	Note      *string   `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UpdateBusinessHoursRequest is the request body for PUT /business-calendar/hours
+type UpdateBusinessHoursRequest struct {
+	Hours []BusinessHours `json:"hours"`
+}
+
+// UpsertBusinessSpecialDayRequest is the request body for PUT /business-calendar/special-days/:date
+type UpsertBusinessSpecialDayRequest struct {
+	IsClosed  bool    `json:"is_closed"`
+	OpenTime  *string `json:"open_time"`
+	CloseTime *string `json:"close_time"`
+	Note      *string `json:"note"`
+}
+
+// BusinessDay is the resolved opening hours for a single date
+type BusinessDay struct {
+	Date      string `json:"date"`
+	Weekday   int    `json:"weekday"`
+	IsClosed  bool   `json:"is_closed"`
+	OpenTime  string `json:"open_time,omitempty"`
+	CloseTime string `json:"close_time,omitempty"`
+	IsSpecial bool   `json:"is_special"`
+	Note      string `json:"note,omitempty"`
+}
+
+// weekdayLabels are the weekdays as shown in messages and prompts
+var weekdayLabels = [...]string{"日", "月", "火", "水", "木", "金", "土"}
+
+// WeekdayLabel returns the one-character Japanese label of a weekday
+func WeekdayLabel(wd time.Weekday) string {
+	return weekdayLabels[wd]
+}
+
+// Default business hours used for weekdays that have no row
+const (
+	DefaultOpenTime  = "09:00"
+	DefaultCloseTime = "22:00"
+)
+
+// BusinessCalendar resolves opening hours for dates from the regular
+// weekday hours and the special days that override them.
+type BusinessCalendar struct {
+	Regular     map[time.Weekday]BusinessHours
+	SpecialDays map[string]BusinessSpecialDay
+}
+
+// NewBusinessCalendar builds a calendar from rows loaded from the DB
+func NewBusinessCalendar(regular []BusinessHours, special []BusinessSpecialDay) *BusinessCalendar {
+	cal := &BusinessCalendar{
+		Regular:     make(map[time.Weekday]BusinessHours),
+		SpecialDays: make(map[string]BusinessSpecialDay),
+	}
+	for _, h := range regular {
+		cal.Regular[time.Weekday(h.Weekday)] = h
+	}
+	for _, d := range special {
+		cal.SpecialDays[d.Date] = d
+	}
+	return cal
+}
+
+// Day returns the resolved business hours for date (YYYY-MM-DD)
+func (cal *BusinessCalendar) Day(date time.Time) BusinessDay {
+	ds := date.Format("2006-01-02")
+	day := BusinessDay{
+		Date:      ds,
+		Weekday:   int(date.Weekday()),
+		OpenTime:  DefaultOpenTime,
+		CloseTime: DefaultCloseTime,
+	}
+
+	if h, ok := cal.Regular[date.Weekday()]; ok {
+		day.IsClosed = h.IsClosed
+		if h.OpenTime != nil {
+			day.OpenTime = *h.OpenTime
+		}
+		if h.CloseTime != nil {
+			day.CloseTime = *h.CloseTime
+		}
+	}
+	if sd, ok := cal.SpecialDays[ds]; ok {
+		day.IsSpecial = true
+		day.IsClosed = sd.IsClosed
+		if sd.OpenTime != nil {
+			day.OpenTime = *sd.OpenTime
+		}
+		if sd.CloseTime != nil {
+			day.CloseTime = *sd.CloseTime
+		}
+		if sd.Note != nil {
+			day.Note = *sd.Note
+		}
+	}
+	if day.IsClosed {
+		day.OpenTime, day.CloseTime = "", ""
+	}
+	return day
+}
+
+// Month returns the resolved business hours for every day of yearMonth
+func (cal *BusinessCalendar) Month(yearMonth string) []BusinessDay {
+	first, err := time.Parse("2006-01", yearMonth)
+	if err != nil {
+		return nil
+	}
+	var days []BusinessDay
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		days = append(days, cal.Day(d))
+	}
+	return days
+}
@@ -0,0 +1,546 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- Typed constraint configs (one per category) ---
+
+// MinStaffConfig is the config for category "min_staff"
+type MinStaffConfig struct {
+	MinCount   *int             `json:"min_count"`
+	TimeRanges []TimeRangeCount `json:"time_ranges,omitempty"`
+}
+
+// TimeRangeCount is a headcount requirement for a time range within a day
+type TimeRangeCount struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	MinCount int    `json:"min_count"`
+}
+
+// MaxStaffConfig is the config for category "max_staff"
+type MaxStaffConfig struct {
+	MaxCount *int `json:"max_count"`
+}
+
+// MaxConsecutiveDaysConfig is the config for category "max_consecutive_days"
+type MaxConsecutiveDaysConfig struct {
+	MaxDays *int `json:"max_days"`
+}
+
+// RestHoursConfig is the config for category "rest_hours"
+type RestHoursConfig struct {
+	MinHours *float64 `json:"min_hours"`
+}
+
+// HourLimitConfig is a min/max monthly hour pair; nil means not set
+type HourLimitConfig struct {
+	MinHours *float64 `json:"min_hours,omitempty"`
+	MaxHours *float64 `json:"max_hours,omitempty"`
+}
+
+// MonthlyHoursConfig is the config for category "monthly_hours"
+type MonthlyHoursConfig struct {
+	HourLimitConfig
+	ByEmploymentType map[string]HourLimitConfig `json:"by_employment_type,omitempty"`
+	ByStaff          map[string]HourLimitConfig `json:"by_staff,omitempty"`
+}
+
+// FixedDayOffConfig is the config for category "fixed_day_off"
+type FixedDayOffConfig struct {
+	StaffID     string   `json:"staff_id"`
+	Weekdays    []int    `json:"weekdays,omitempty"`
+	DayOfWeek   *int     `json:"day_of_week,omitempty"`
+	ExceptDates []string `json:"except_dates,omitempty"`
+}
+
+// StaffCompatibilityConfig is the config for category "staff_compatibility"
+type StaffCompatibilityConfig struct {
+	Rule       string   `json:"rule"`
+	StaffIDs   []string `json:"staff_ids,omitempty"`
+	Scope      string   `json:"scope,omitempty"`
+	TraineeIDs []string `json:"trainee_ids,omitempty"`
+	MentorIDs  []string `json:"mentor_ids,omitempty"`
+}
+
+// CoverageConfig is the config for category "coverage". Each day is split
+// into slots of SlotMinutes and every slot inside a requirement's range must
+// be covered by at least MinCount working staff.
+type CoverageConfig struct {
+	SlotMinutes int                   `json:"slot_minutes,omitempty"`
+	Weekday     []CoverageRequirement `json:"weekday,omitempty"`
+	Weekend     []CoverageRequirement `json:"weekend,omitempty"`
+}
+
+// CoverageRequirement is the required headcount for a time range of the day
+type CoverageRequirement struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	MinCount int    `json:"min_count"`
+}
+
+// RequestWindowConfig is the config for category "request_window". On a day
+// a staff has a preferred or available request with start/end times, their
+// shift must stay inside those times.
+type RequestWindowConfig struct {
+	// RequestTypes limits the check to these request types; empty checks
+	// both preferred and available
+	RequestTypes []string `json:"request_types,omitempty"`
+}
+
+// Applies reports whether requests of requestType are checked
+func (c *RequestWindowConfig) Applies(requestType string) bool {
+	if len(c.RequestTypes) == 0 {
+		return requestType == "preferred" || requestType == "available"
+	}
+	for _, t := range c.RequestTypes {
+		if t == requestType {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultCoverageSlotMinutes is used when slot_minutes is omitted
+const DefaultCoverageSlotMinutes = 30
+
+// ConstraintCategories lists every accepted constraint category
+var ConstraintCategories = []string{
+	"min_staff", "max_staff", "max_consecutive_days",
+	"monthly_hours", "fixed_day_off", "staff_compatibility", "rest_hours",
+	"coverage", "request_window",
+}
+
+// DecodeConstraintConfig strictly decodes raw into the typed config for
+// category and validates it. Unknown keys, wrong types and missing required
+// keys are reported as field-level ErrorDetails under "config.*".
+func DecodeConstraintConfig(category string, raw json.RawMessage) (interface{}, []ErrorDetail) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		raw = json.RawMessage("{}")
+	}
+
+	var cfg interface {
+		validate() []ErrorDetail
+	}
+	switch category {
+	case "min_staff":
+		cfg = &MinStaffConfig{}
+	case "max_staff":
+		cfg = &MaxStaffConfig{}
+	case "max_consecutive_days":
+		cfg = &MaxConsecutiveDaysConfig{}
+	case "rest_hours":
+		cfg = &RestHoursConfig{}
+	case "monthly_hours":
+		cfg = &MonthlyHoursConfig{}
+	case "fixed_day_off":
+		cfg = &FixedDayOffConfig{}
+	case "staff_compatibility":
+		cfg = &StaffCompatibilityConfig{}
+	case "coverage":
+		cfg = &CoverageConfig{}
+	case "request_window":
+		cfg = &RequestWindowConfig{}
+	default:
+		return nil, []ErrorDetail{{Field: "category", Message: "無効な category です"}}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, []ErrorDetail{decodeErrorDetail(err)}
+	}
+	if details := cfg.validate(); len(details) > 0 {
+		return nil, details
+	}
+	return cfg, nil
+}
+
+func decodeErrorDetail(err error) ErrorDetail {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return ErrorDetail{
+			Field:   "config." + typeErr.Field,
+			Message: fmt.Sprintf("config.%s の型が不正です（%s が必要です）", typeErr.Field, typeErr.Type.String()),
+		}
+	}
+	if msg := err.Error(); strings.HasPrefix(msg, "json: unknown field ") {
+		field := strings.Trim(strings.TrimPrefix(msg, "json: unknown field "), `"`)
+		return ErrorDetail{Field: "config." + field, Message: fmt.Sprintf("config.%s は未対応の項目です", field)}
+	}
+	return ErrorDetail{Field: "config", Message: "config はJSONオブジェクトで指定してください"}
+}
+
+func requiredDetail(field string) ErrorDetail {
+	return ErrorDetail{Field: "config." + field, Message: fmt.Sprintf("config.%s は必須です", field)}
+}
+
+func (c *MinStaffConfig) validate() []ErrorDetail {
+	var details []ErrorDetail
+	if c.MinCount == nil && len(c.TimeRanges) == 0 {
+		details = append(details, requiredDetail("min_count"))
+	}
+	if c.MinCount != nil && *c.MinCount < 0 {
+		details = append(details, ErrorDetail{Field: "config.min_count", Message: "config.min_count は0以上で指定してください"})
+	}
+	for i, tr := range c.TimeRanges {
+		field := fmt.Sprintf("config.time_ranges[%d]", i)
+		if !isHHMM(tr.Start) || !isHHMM(tr.End) || tr.Start >= tr.End {
+			details = append(details, ErrorDetail{Field: field, Message: field + " の start/end は HH:MM 形式で start < end にしてください"})
+		}
+		if tr.MinCount < 0 {
+			details = append(details, ErrorDetail{Field: field + ".min_count", Message: field + ".min_count は0以上で指定してください"})
+		}
+	}
+	return details
+}
+
+func (c *MaxStaffConfig) validate() []ErrorDetail {
+	if c.MaxCount == nil {
+		return []ErrorDetail{requiredDetail("max_count")}
+	}
+	if *c.MaxCount < 1 {
+		return []ErrorDetail{{Field: "config.max_count", Message: "config.max_count は1以上で指定してください"}}
+	}
+	return nil
+}
+
+func (c *MaxConsecutiveDaysConfig) validate() []ErrorDetail {
+	if c.MaxDays == nil {
+		return []ErrorDetail{requiredDetail("max_days")}
+	}
+	if *c.MaxDays < 1 || *c.MaxDays > 31 {
+		return []ErrorDetail{{Field: "config.max_days", Message: "config.max_days は1〜31で指定してください"}}
+	}
+	return nil
+}
+
+func (c *RestHoursConfig) validate() []ErrorDetail {
+	if c.MinHours == nil {
+		return []ErrorDetail{requiredDetail("min_hours")}
+	}
+	if *c.MinHours < 0 || *c.MinHours > 24 {
+		return []ErrorDetail{{Field: "config.min_hours", Message: "config.min_hours は0〜24で指定してください"}}
+	}
+	return nil
+}
+
+// MinRestMinutes returns min_hours in whole minutes, rounded up so a
+// fractional hour is never shortened
+func (c *RestHoursConfig) MinRestMinutes() int {
+	return int(math.Ceil(*c.MinHours * 60))
+}
+
+func (l HourLimitConfig) validateAt(prefix string) []ErrorDetail {
+	var details []ErrorDetail
+	if l.MinHours != nil && *l.MinHours < 0 {
+		details = append(details, ErrorDetail{Field: prefix + "min_hours", Message: prefix + "min_hours は0以上で指定してください"})
+	}
+	if l.MaxHours != nil && (*l.MaxHours < 0 || *l.MaxHours > 744) {
+		details = append(details, ErrorDetail{Field: prefix + "max_hours", Message: prefix + "max_hours は0〜744で指定してください"})
+	}
+	if l.MinHours != nil && l.MaxHours != nil && *l.MinHours > *l.MaxHours {
+		details = append(details, ErrorDetail{Field: prefix + "min_hours", Message: prefix + "min_hours は max_hours 以下にしてください"})
+	}
+	return details
+}
+
+func (c *MonthlyHoursConfig) validate() []ErrorDetail {
+	details := c.HourLimitConfig.validateAt("config.")
+	for _, key := range sortedKeys(c.ByEmploymentType) {
+		details = append(details, c.ByEmploymentType[key].validateAt(fmt.Sprintf("config.by_employment_type.%s.", key))...)
+	}
+	for _, key := range sortedKeys(c.ByStaff) {
+		details = append(details, c.ByStaff[key].validateAt(fmt.Sprintf("config.by_staff.%s.", key))...)
+	}
+	if c.MinHours == nil && c.MaxHours == nil && len(c.ByEmploymentType) == 0 && len(c.ByStaff) == 0 {
+		details = append(details, ErrorDetail{Field: "config", Message: "config には min_hours, max_hours, by_employment_type, by_staff のいずれかが必要です"})
+	}
+	return details
+}
+
+func (c *FixedDayOffConfig) validate() []ErrorDetail {
+	var details []ErrorDetail
+	if c.StaffID == "" {
+		details = append(details, requiredDetail("staff_id"))
+	}
+	if len(c.Weekdays) == 0 && c.DayOfWeek == nil {
+		details = append(details, requiredDetail("weekdays"))
+	}
+	for i, wd := range c.Weekdays {
+		if wd < 0 || wd > 6 {
+			details = append(details, ErrorDetail{Field: fmt.Sprintf("config.weekdays[%d]", i), Message: "曜日は0(日)〜6(土)で指定してください"})
+		}
+	}
+	if c.DayOfWeek != nil && (*c.DayOfWeek < 0 || *c.DayOfWeek > 6) {
+		details = append(details, ErrorDetail{Field: "config.day_of_week", Message: "曜日は0(日)〜6(土)で指定してください"})
+	}
+	for i, d := range c.ExceptDates {
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			details = append(details, ErrorDetail{Field: fmt.Sprintf("config.except_dates[%d]", i), Message: "日付は YYYY-MM-DD 形式で指定してください"})
+		}
+	}
+	return details
+}
+
+func (c *StaffCompatibilityConfig) validate() []ErrorDetail {
+	var details []ErrorDetail
+	switch c.Rule {
+	case "avoid_together", "prefer_together":
+		if len(c.StaffIDs) < 2 {
+			details = append(details, ErrorDetail{Field: "config.staff_ids", Message: "config.staff_ids は2人以上指定してください"})
+		}
+		if c.Scope != "" && c.Scope != "same_day" && c.Scope != "overlap" {
+			details = append(details, ErrorDetail{Field: "config.scope", Message: "config.scope は same_day, overlap のいずれかで指定してください"})
+		}
+	case "mentor_required":
+		if len(c.TraineeIDs) == 0 {
+			details = append(details, requiredDetail("trainee_ids"))
+		}
+		if len(c.MentorIDs) == 0 {
+			details = append(details, requiredDetail("mentor_ids"))
+		}
+	case "":
+		details = append(details, requiredDetail("rule"))
+	default:
+		details = append(details, ErrorDetail{Field: "config.rule", Message: "config.rule は avoid_together, prefer_together, mentor_required のいずれかで指定してください"})
+	}
+	return details
+}
+
+func (c *CoverageConfig) validate() []ErrorDetail {
+	var details []ErrorDetail
+	if c.SlotMinutes == 0 {
+		c.SlotMinutes = DefaultCoverageSlotMinutes
+	}
+	if c.SlotMinutes < 5 || c.SlotMinutes > 240 || (24*60)%c.SlotMinutes != 0 {
+		details = append(details, ErrorDetail{Field: "config.slot_minutes", Message: "config.slot_minutes は5〜240の1日(1440分)を割り切れる値で指定してください"})
+	}
+	if len(c.Weekday) == 0 && len(c.Weekend) == 0 {
+		details = append(details, ErrorDetail{Field: "config", Message: "config には weekday, weekend のいずれかが必要です"})
+	}
+	for key, reqs := range map[string][]CoverageRequirement{"weekday": c.Weekday, "weekend": c.Weekend} {
+		for i, r := range reqs {
+			field := fmt.Sprintf("config.%s[%d]", key, i)
+			// end <= start wraps past midnight (e.g. 22:00-02:00)
+			if !isHHMM(r.Start) || !isHHMM(r.End) || r.Start == r.End {
+				details = append(details, ErrorDetail{Field: field, Message: field + " の start/end は HH:MM 形式で異なる時刻にしてください"})
+			}
+			if r.MinCount < 1 {
+				details = append(details, ErrorDetail{Field: field + ".min_count", Message: field + ".min_count は1以上で指定してください"})
+			}
+		}
+	}
+	sort.Slice(details, func(i, j int) bool { return details[i].Field < details[j].Field })
+	return details
+}
+
+func (c *RequestWindowConfig) validate() []ErrorDetail {
+	var details []ErrorDetail
+	for i, t := range c.RequestTypes {
+		if t != "preferred" && t != "available" {
+			details = append(details, ErrorDetail{Field: fmt.Sprintf("config.request_types[%d]", i), Message: "config.request_types は preferred, available のいずれかで指定してください"})
+		}
+	}
+	return details
+}
+
+func sortedKeys(m map[string]HourLimitConfig) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// StringList reads a JSON array of strings decoded into interface{},
+// skipping anything that isn't a string. It returns nil for other values.
+func StringList(raw interface{}) []string {
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil
+	}
+	var result []string
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func isHHMM(s string) bool {
+	_, err := time.Parse("15:04", s)
+	return err == nil
+}
+
+// --- JSON Schema ---
+
+// ConstraintConfigSchemas returns a JSON Schema for the config of each
+// category, for the frontend to render constraint forms.
+func ConstraintConfigSchemas() map[string]interface{} {
+	integer := func(desc string, min, max int) map[string]interface{} {
+		return map[string]interface{}{"type": "integer", "description": desc, "minimum": min, "maximum": max}
+	}
+	number := func(desc string, min, max float64) map[string]interface{} {
+		return map[string]interface{}{"type": "number", "description": desc, "minimum": min, "maximum": max}
+	}
+	stringArray := func(desc string) map[string]interface{} {
+		return map[string]interface{}{"type": "array", "description": desc, "items": map[string]interface{}{"type": "string", "format": "uuid"}}
+	}
+	// anyOf requires at least one of fields, as validate() does when a
+	// config can be given in more than one way
+	anyOf := func(s map[string]interface{}, fields ...string) map[string]interface{} {
+		alternatives := make([]map[string]interface{}, 0, len(fields))
+		for _, field := range fields {
+			alternatives = append(alternatives, map[string]interface{}{"required": []string{field}})
+		}
+		s["anyOf"] = alternatives
+		return s
+	}
+	object := func(title string, props map[string]interface{}, required ...string) map[string]interface{} {
+		s := map[string]interface{}{
+			"$schema":              "https://json-schema.org/draft/2020-12/schema",
+			"title":                title,
+			"type":                 "object",
+			"properties":           props,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	}
+	hourLimit := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"min_hours": number("月間最低労働時間", 0, 744),
+			"max_hours": number("月間最大労働時間", 0, 744),
+		},
+		"additionalProperties": false,
+	}
+	timeHHMM := map[string]interface{}{"type": "string", "pattern": "^[0-2][0-9]:[0-5][0-9]$"}
+	coverageList := func(desc string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":        "array",
+			"description": desc,
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"start":     timeHHMM,
+					"end":       timeHHMM,
+					"min_count": integer("必要人数", 1, 100),
+				},
+				"required":             []string{"start", "end", "min_count"},
+				"additionalProperties": false,
+			},
+		}
+	}
+
+	staffCompatibility := object("スタッフ相性", map[string]interface{}{
+		"rule":        map[string]interface{}{"type": "string", "enum": []string{"avoid_together", "prefer_together", "mentor_required"}},
+		"staff_ids":   stringArray("avoid_together: 一緒に勤務させないスタッフ / prefer_together: なるべく一緒に勤務させるスタッフ"),
+		"scope":       map[string]interface{}{"type": "string", "enum": []string{"same_day", "overlap"}, "default": "same_day"},
+		"trainee_ids": stringArray("mentor_required: 新人スタッフ"),
+		"mentor_ids":  stringArray("mentor_required: 指導担当スタッフ"),
+	}, "rule")
+	// Which lists are required depends on the rule
+	when := func(rules []string, schema map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"if":   map[string]interface{}{"properties": map[string]interface{}{"rule": map[string]interface{}{"enum": rules}}},
+			"then": schema,
+		}
+	}
+	staffCompatibility["allOf"] = []map[string]interface{}{
+		when([]string{"avoid_together", "prefer_together"}, map[string]interface{}{
+			"required":   []string{"staff_ids"},
+			"properties": map[string]interface{}{"staff_ids": map[string]interface{}{"minItems": 2}},
+		}),
+		when([]string{"mentor_required"}, map[string]interface{}{
+			"required": []string{"trainee_ids", "mentor_ids"},
+			"properties": map[string]interface{}{
+				"trainee_ids": map[string]interface{}{"minItems": 1},
+				"mentor_ids":  map[string]interface{}{"minItems": 1},
+			},
+		}),
+	}
+
+	return map[string]interface{}{
+		"min_staff": anyOf(object("最低スタッフ数", map[string]interface{}{
+			"min_count": integer("1日あたりの最低人数", 0, 100),
+			"time_ranges": map[string]interface{}{
+				"type":     "array",
+				"minItems": 1,
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"start":     timeHHMM,
+						"end":       timeHHMM,
+						"min_count": integer("時間帯の最低人数", 0, 100),
+					},
+					"required":             []string{"start", "end", "min_count"},
+					"additionalProperties": false,
+				},
+			},
+		}), "min_count", "time_ranges"),
+		"max_staff": object("最大スタッフ数", map[string]interface{}{
+			"max_count": integer("1日あたりの最大人数", 1, 100),
+		}, "max_count"),
+		"max_consecutive_days": object("連勤制限", map[string]interface{}{
+			"max_days": integer("最大連続勤務日数", 1, 31),
+		}, "max_days"),
+		"rest_hours": object("勤務間インターバル", map[string]interface{}{
+			"min_hours": number("最低休息時間", 0, 24),
+		}, "min_hours"),
+		"monthly_hours": anyOf(object("月間労働時間制限", map[string]interface{}{
+			"min_hours": number("月間最低労働時間", 0, 744),
+			"max_hours": number("月間最大労働時間", 0, 744),
+			"by_employment_type": map[string]interface{}{
+				"type":                 "object",
+				"description":          "雇用形態ごとの上書き",
+				"additionalProperties": hourLimit,
+			},
+			"by_staff": map[string]interface{}{
+				"type":                 "object",
+				"description":          "スタッフIDごとの上書き",
+				"additionalProperties": hourLimit,
+			},
+		}), "min_hours", "max_hours", "by_employment_type", "by_staff"),
+		"fixed_day_off": anyOf(object("固定休日", map[string]interface{}{
+			"staff_id": map[string]interface{}{"type": "string", "format": "uuid"},
+			"weekdays": map[string]interface{}{
+				"type":        "array",
+				"description": "0=日〜6=土",
+				"items":       integer("曜日", 0, 6),
+				"minItems":    1,
+			},
+			"day_of_week": map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 6, "deprecated": true},
+			"except_dates": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string", "format": "date"},
+			},
+		}, "staff_id"), "weekdays", "day_of_week"),
+		"coverage": anyOf(object("時間帯別必要人数", map[string]interface{}{
+			"slot_minutes": map[string]interface{}{"type": "integer", "description": "判定に使う時間枠の長さ（分）", "minimum": 5, "maximum": 240, "default": DefaultCoverageSlotMinutes},
+			"weekday":      coverageList("平日（月〜金）の必要人数"),
+			"weekend":      coverageList("土日の必要人数"),
+		}), "weekday", "weekend"),
+		"staff_compatibility": staffCompatibility,
+		"request_window": object("シフト希望の時間帯", map[string]interface{}{
+			"request_types": map[string]interface{}{
+				"type":        "array",
+				"description": "時間帯を守らせる希望の種類（省略時は両方）",
+				"items":       map[string]interface{}{"type": "string", "enum": []string{"preferred", "available"}},
+			},
+		}),
+	}
+}
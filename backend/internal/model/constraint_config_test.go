@@ -0,0 +1,125 @@
+package model
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestDecodeConstraintConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		category  string
+		config    string
+		wantField string // empty means valid
+	}{
+		{"valid max_consecutive_days", "max_consecutive_days", `{"max_days": 5}`, ""},
+		{"missing max_days", "max_consecutive_days", `{}`, "config.max_days"},
+		{"empty config treated as object", "max_consecutive_days", ``, "config.max_days"},
+		{"max_days out of range", "max_consecutive_days", `{"max_days": 0}`, "config.max_days"},
+		{"unknown key", "max_consecutive_days", `{"max_days": 5, "maxdays": 3}`, "config.maxdays"},
+		{"wrong type", "max_staff", `{"max_count": "five"}`, "config.max_count"},
+		{"not an object", "max_staff", `[1, 2]`, "config"},
+		{"valid min_staff with time_ranges only", "min_staff", `{"time_ranges": [{"start": "11:00", "end": "14:00", "min_count": 3}]}`, ""},
+		{"invalid time range", "min_staff", `{"min_count": 2, "time_ranges": [{"start": "14:00", "end": "11:00", "min_count": 3}]}`, "config.time_ranges[0]"},
+		{"valid rest_hours", "rest_hours", `{"min_hours": 11}`, ""},
+		{"valid monthly_hours overrides", "monthly_hours", `{"by_employment_type": {"part_time": {"max_hours": 80}}}`, ""},
+		{"monthly_hours min above max", "monthly_hours", `{"by_staff": {"s1": {"min_hours": 100, "max_hours": 80}}}`, "config.by_staff.s1.min_hours"},
+		{"monthly_hours empty", "monthly_hours", `{}`, "config"},
+		{"valid fixed_day_off", "fixed_day_off", `{"staff_id": "s1", "weekdays": [3], "except_dates": ["2026-03-18"]}`, ""},
+		{"fixed_day_off invalid weekday", "fixed_day_off", `{"staff_id": "s1", "weekdays": [7]}`, "config.weekdays[0]"},
+		{"fixed_day_off missing staff", "fixed_day_off", `{"weekdays": [3]}`, "config.staff_id"},
+		{"valid avoid_together", "staff_compatibility", `{"rule": "avoid_together", "staff_ids": ["s1", "s2"]}`, ""},
+		{"avoid_together needs two staff", "staff_compatibility", `{"rule": "avoid_together", "staff_ids": ["s1"]}`, "config.staff_ids"},
+		{"valid prefer_together", "staff_compatibility", `{"rule": "prefer_together", "staff_ids": ["s1", "s2"], "scope": "overlap"}`, ""},
+		{"prefer_together needs two staff", "staff_compatibility", `{"rule": "prefer_together", "staff_ids": ["s1"]}`, "config.staff_ids"},
+		{"unknown rule", "staff_compatibility", `{"rule": "always_together"}`, "config.rule"},
+		{"valid request_window", "request_window", `{"request_types": ["available"]}`, ""},
+		{"request_window unknown type", "request_window", `{"request_types": ["unavailable"]}`, "config.request_types[0]"},
+		{"unknown category", "invalid_cat", `{}`, "category"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, details := DecodeConstraintConfig(tt.category, json.RawMessage(tt.config))
+			if tt.wantField == "" {
+				if len(details) > 0 {
+					t.Fatalf("unexpected details: %+v", details)
+				}
+				if cfg == nil {
+					t.Fatal("expected decoded config, got nil")
+				}
+				return
+			}
+			if len(details) == 0 {
+				t.Fatalf("expected error on %q, got none", tt.wantField)
+			}
+			if details[0].Field != tt.wantField {
+				t.Errorf("field = %q, want %q (message: %s)", details[0].Field, tt.wantField, details[0].Message)
+			}
+		})
+	}
+}
+
+func TestConstraintConfigSchemas_CoversAllCategories(t *testing.T) {
+	schemas := ConstraintConfigSchemas()
+	for _, category := range ConstraintCategories {
+		if _, ok := schemas[category]; !ok {
+			t.Errorf("missing schema for category %q", category)
+		}
+	}
+	if _, err := json.Marshal(schemas); err != nil {
+		t.Errorf("schemas are not JSON serializable: %v", err)
+	}
+}
+
+func TestConstraintConfigSchemas_RequiredFieldsMatchValidate(t *testing.T) {
+	schemas := ConstraintConfigSchemas()
+
+	tests := []struct {
+		category     string
+		wantRequired []string
+		wantAnyOf    []string
+	}{
+		// min_count is optional when time_ranges is given
+		{"min_staff", nil, []string{"min_count", "time_ranges"}},
+		{"fixed_day_off", []string{"staff_id"}, []string{"weekdays", "day_of_week"}},
+		{"coverage", nil, []string{"weekday", "weekend"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			schema := schemas[tt.category].(map[string]interface{})
+			required, _ := schema["required"].([]string)
+			if !slices.Equal(required, tt.wantRequired) {
+				t.Errorf("required = %v, want %v", required, tt.wantRequired)
+			}
+			var anyOf []string
+			alternatives, _ := schema["anyOf"].([]map[string]interface{})
+			for _, alt := range alternatives {
+				anyOf = append(anyOf, alt["required"].([]string)...)
+			}
+			if !slices.Equal(anyOf, tt.wantAnyOf) {
+				t.Errorf("anyOf required = %v, want %v", anyOf, tt.wantAnyOf)
+			}
+		})
+	}
+}
+
+func TestRestHoursConfig_MinRestMinutes(t *testing.T) {
+	tests := []struct {
+		hours float64
+		want  int
+	}{
+		{11, 660},
+		{10.5, 630},
+		{10.99, 660},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		cfg := &RestHoursConfig{MinHours: &tt.hours}
+		if got := cfg.MinRestMinutes(); got != tt.want {
+			t.Errorf("MinRestMinutes(%v) = %d, want %d", tt.hours, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package model
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 )
 
@@ -72,37 +75,131 @@ type ShiftPattern struct {
 	Reasoning            *string               `json:"reasoning"`
 	Score                *float64              `json:"score"`
 	ConstraintViolations []ConstraintViolation `json:"constraint_violations"`
+	Repairs              []RepairAction        `json:"repairs"`
+	JobID                *string               `json:"job_id"`
+	PatternIndex         *int                  `json:"pattern_index"`
+	Strategy             *string               `json:"strategy"`
+	DiversityScore       *float64              `json:"diversity_score"`
+	ScoreBreakdown       *ScoreBreakdown       `json:"score_breakdown"`
 	CreatedAt            time.Time             `json:"created_at"`
 	UpdatedAt            time.Time             `json:"updated_at,omitempty"`
 }
 
 // ShiftEntry represents the shift_entries table
 type ShiftEntry struct {
-	ID           string    `json:"id"`
-	PatternID    string    `json:"pattern_id"`
-	StaffID      string    `json:"staff_id"`
-	StaffName    string    `json:"staff_name,omitempty"`
-	Date         string    `json:"date"`
-	StartTime    string    `json:"start_time"`
-	EndTime      string    `json:"end_time"`
-	BreakMinutes int       `json:"break_minutes"`
-	IsManualEdit bool      `json:"is_manual_edit"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-}
-
-// GenerationJob represents the generation_jobs table
+	ID             string    `json:"id"`
+	PatternID      string    `json:"pattern_id"`
+	StaffID        string    `json:"staff_id"`
+	StaffName      string    `json:"staff_name,omitempty"`
+	Date           string    `json:"date"`
+	StartTime      string    `json:"start_time"`
+	EndTime        string    `json:"end_time"`
+	BreakMinutes   int       `json:"break_minutes"`
+	IsManualEdit   bool      `json:"is_manual_edit"`
+	IsAutoRepaired bool      `json:"is_auto_repaired"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// GenerationJob represents the generation_jobs table. Jobs with
+// TargetPatternID regenerate the Scope of that pattern instead of creating
+// new patterns.
 type GenerationJob struct {
-	ID            string     `json:"id"`
-	YearMonth     string     `json:"year_month"`
-	Status        string     `json:"status"`
-	PatternCount  int        `json:"pattern_count"`
-	Progress      int        `json:"progress"`
-	StatusMessage *string    `json:"status_message"`
-	ErrorMessage  *string    `json:"error_message"`
-	StartedAt     *time.Time `json:"started_at"`
-	CompletedAt   *time.Time `json:"completed_at"`
-	CreatedAt     time.Time  `json:"created_at"`
+	ID              string             `json:"id"`
+	YearMonth       string             `json:"year_month"`
+	Status          string             `json:"status"`
+	PatternCount    int                `json:"pattern_count"`
+	Progress        int                `json:"progress"`
+	StatusMessage   *string            `json:"status_message"`
+	ErrorMessage    *string            `json:"error_message"`
+	Generator       string             `json:"generator"`
+	Seed            *int64             `json:"seed"`
+	Parallel        bool               `json:"parallel"`
+	TargetPatternID *string            `json:"target_pattern_id,omitempty"`
+	Scope           *RegenerationScope `json:"scope,omitempty"`
+	StartedAt       *time.Time         `json:"started_at"`
+	CompletedAt     *time.Time         `json:"completed_at"`
+	CreatedAt       time.Time          `json:"created_at"`
+	GenerationGuidance
+}
+
+// Statuses of a generation job
+const (
+	JobStatusPending    = "pending"
+	JobStatusProcessing = "processing"
+	JobStatusCompleted  = "completed"
+	JobStatusFailed     = "failed"
+	JobStatusCancelled  = "cancelled"
+)
+
+// Types of a generation job event
+const (
+	JobEventProgress  = "progress"
+	JobEventPattern   = "pattern"
+	JobEventCompleted = "completed"
+	JobEventFailed    = "failed"
+	JobEventCancelled = "cancelled"
+)
+
+// JobEvent is a change of a generation job, streamed to clients as SSE
+type JobEvent struct {
+	Type          string   `json:"type"`
+	JobID         string   `json:"job_id"`
+	Status        string   `json:"status"`
+	Progress      int      `json:"progress"`
+	StatusMessage *string  `json:"status_message,omitempty"`
+	PatternID     string   `json:"pattern_id,omitempty"`
+	PatternIndex  *int     `json:"pattern_index,omitempty"`
+	Score         *float64 `json:"score,omitempty"`
+	ErrorMessage  *string  `json:"error_message,omitempty"`
+}
+
+// IsFinal reports whether the event ends the job
+func (e JobEvent) IsFinal() bool {
+	return e.Type == JobEventCompleted || e.Type == JobEventFailed || e.Type == JobEventCancelled
+}
+
+// Outcomes of a generation attempt
+const (
+	AttemptOutcomeOK              = "ok"
+	AttemptOutcomeHardViolations  = "hard_violations"
+	AttemptOutcomeGenerationError = "generation_error"
+	AttemptOutcomeValidationError = "validation_error"
+)
+
+// GenerationAttempt represents the generation_attempts table: one generate
+// call of a job for a pattern and retry
+type GenerationAttempt struct {
+	ID           string            `json:"id"`
+	JobID        string            `json:"job_id"`
+	PatternIndex int               `json:"pattern_index"`
+	Retry        int               `json:"retry"`
+	Generator    string            `json:"generator"`
+	Outcome      string            `json:"outcome"`
+	SystemPrompt *string           `json:"system_prompt"`
+	LLMCalls     []LLMCall         `json:"llm_calls"`
+	Response     *LLMResponse      `json:"response"`
+	InputTokens  int               `json:"input_tokens"`
+	OutputTokens int               `json:"output_tokens"`
+	LatencyMs    int               `json:"latency_ms"`
+	ParseErrors  []ErrorDetail     `json:"parse_errors"`
+	ErrorMessage *string           `json:"error_message"`
+	Validation   *ValidationResult `json:"validation"`
+	Repairs      []RepairAction    `json:"repairs"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// LLMCall is one request/reply of an LLM conversation. Prompt is the text
+// sent in that turn (the user prompt first, then any feedback) and Response
+// the raw tool input received.
+type LLMCall struct {
+	Prompt       string          `json:"prompt"`
+	Response     json.RawMessage `json:"response"`
+	InputTokens  int             `json:"input_tokens"`
+	OutputTokens int             `json:"output_tokens"`
+	LatencyMs    int             `json:"latency_ms"`
+	Truncated    bool            `json:"truncated"`
+	Error        string          `json:"error,omitempty"`
 }
 
 // --- Request / Response DTOs ---
@@ -175,6 +272,238 @@ type UpdateConstraintRequest struct {
 type GenerateShiftRequest struct {
 	YearMonth    string `json:"year_month"`
 	PatternCount int    `json:"pattern_count"`
+	Generator    string `json:"generator,omitempty"`
+	Seed         *int64 `json:"seed,omitempty"`
+	// Parallel generates the patterns concurrently, each with its own
+	// strategy, instead of one after another
+	Parallel bool `json:"parallel,omitempty"`
+	GenerationGuidance
+}
+
+// GenerationGuidance is what a manager can tell a generation job besides the
+// month and pattern count. Every field is optional.
+type GenerationGuidance struct {
+	// Instruction is a free-text request passed to the LLM as written, e.g.
+	// 「今月は佐藤さんの土日出勤を増やしてください」
+	Instruction string `json:"instruction,omitempty"`
+	// Strategies[i] is the strategy preset of pattern i. "" or a missing
+	// entry leaves pattern i to the default.
+	Strategies []string `json:"strategies,omitempty"`
+	// Weights replaces the configured score weights for this job and is
+	// shown in the prompt
+	Weights *ObjectiveWeights `json:"weights,omitempty"`
+}
+
+// ObjectiveWeights are the relative weights of the score components. Only
+// their ratios matter; a component weighted 0 doesn't count.
+type ObjectiveWeights struct {
+	HoursFit        float64 `json:"hours_fit"`
+	Preference      float64 `json:"preference"`
+	SoftConstraints float64 `json:"soft_constraints"`
+	Fairness        float64 `json:"fairness"`
+	Cost            float64 `json:"cost"`
+}
+
+// DefaultObjectiveWeights are used when neither the config nor the job sets
+// weights
+var DefaultObjectiveWeights = ObjectiveWeights{
+	HoursFit:        30,
+	Preference:      20,
+	SoftConstraints: 25,
+	Fairness:        15,
+	Cost:            10,
+}
+
+// Total returns the sum of the weights
+func (w ObjectiveWeights) Total() float64 {
+	return w.HoursFit + w.Preference + w.SoftConstraints + w.Fairness + w.Cost
+}
+
+// Validate checks that no weight is negative and that at least one is
+// positive
+func (w ObjectiveWeights) Validate() error {
+	for _, v := range []float64{w.HoursFit, w.Preference, w.SoftConstraints, w.Fairness, w.Cost} {
+		if v < 0 {
+			return errors.New("weights には0以上の値を指定してください")
+		}
+	}
+	if w.Total() == 0 {
+		return errors.New("weights のいずれかに正の値を指定してください")
+	}
+	return nil
+}
+
+// Of returns the weight of a score component
+func (w ObjectiveWeights) Of(component string) float64 {
+	switch component {
+	case ScoreHoursFit:
+		return w.HoursFit
+	case ScorePreference:
+		return w.Preference
+	case ScoreSoftConstraints:
+		return w.SoftConstraints
+	case ScoreFairness:
+		return w.Fairness
+	case ScoreCost:
+		return w.Cost
+	}
+	return 0
+}
+
+// Score components, in the order they appear in a ScoreBreakdown
+const (
+	// ScoreHoursFit: monthly hours inside each staff's preferred range
+	ScoreHoursFit = "hours_fit"
+	// ScorePreference: preferred (◎) and available (○) requests that got a shift
+	ScorePreference = "preference"
+	// ScoreSoftConstraints: soft constraint violations, weighted by priority
+	ScoreSoftConstraints = "soft_constraints"
+	// ScoreFairness: how evenly weekend shifts are spread over the staff
+	ScoreFairness = "fairness"
+	// ScoreCost: total hours against the staff's minimum preferred hours
+	ScoreCost = "cost"
+)
+
+// ScoreBreakdown explains a pattern's score: Total is the weighted mean of
+// the components less HardPenalty, floored at 0
+type ScoreBreakdown struct {
+	Components     []ScoreComponent `json:"components"`
+	WeightedScore  float64          `json:"weighted_score"`
+	HardViolations int              `json:"hard_violations"`
+	HardPenalty    float64          `json:"hard_penalty"`
+	Total          float64          `json:"total"`
+}
+
+// ScoreComponent is one part of a score. Weight is its share of the total
+// weight (0-1) and Deductions say where its points were lost.
+type ScoreComponent struct {
+	Name       string           `json:"name"`
+	Label      string           `json:"label"`
+	Score      float64          `json:"score"`
+	Weight     float64          `json:"weight"`
+	Detail     string           `json:"detail"`
+	Deductions []ScoreDeduction `json:"deductions,omitempty"`
+}
+
+// ScoreDeduction is points a component lost and why
+type ScoreDeduction struct {
+	Reason  string  `json:"reason"`
+	StaffID string  `json:"staff_id,omitempty"`
+	Points  float64 `json:"points"`
+}
+
+// RegenerationScope selects the entries of a pattern to regenerate: those
+// dated StartDate..EndDate (inclusive) of the staff in StaffIDs. An empty
+// field doesn't restrict.
+type RegenerationScope struct {
+	StartDate string   `json:"start_date,omitempty"`
+	EndDate   string   `json:"end_date,omitempty"`
+	StaffIDs  []string `json:"staff_ids,omitempty"`
+}
+
+// ContainsDate reports whether date (YYYY-MM-DD) is within the date range
+func (s *RegenerationScope) ContainsDate(date string) bool {
+	return (s.StartDate == "" || date >= s.StartDate) && (s.EndDate == "" || date <= s.EndDate)
+}
+
+// Contains reports whether an entry of staffID on date is regenerated
+func (s *RegenerationScope) Contains(staffID, date string) bool {
+	if !s.ContainsDate(date) {
+		return false
+	}
+	return len(s.StaffIDs) == 0 || slices.Contains(s.StaffIDs, staffID)
+}
+
+// RegeneratePatternRequest is the body of POST /shifts/patterns/:id/regenerate
+type RegeneratePatternRequest struct {
+	RegenerationScope
+	Generator string `json:"generator,omitempty"`
+	Seed      *int64 `json:"seed,omitempty"`
+}
+
+// FinalizePatternRequest is the body of PUT /shifts/patterns/:id/finalize.
+// OverrideReason lets a pattern with hard violations be finalized anyway;
+// FinalizedBy is then required so the override can be traced.
+type FinalizePatternRequest struct {
+	FinalizedBy    string `json:"finalized_by"`
+	OverrideReason string `json:"override_reason"`
+}
+
+// PatternFinalization represents the shift_pattern_finalizations table
+type PatternFinalization struct {
+	ID                   string      `json:"id"`
+	PatternID            string      `json:"pattern_id"`
+	FinalizedBy          string      `json:"finalized_by"`
+	OverrideReason       string      `json:"override_reason"`
+	OverriddenViolations []Violation `json:"overridden_violations"`
+	FinalizedAt          time.Time   `json:"finalized_at"`
+}
+
+// Generator names accepted in GenerateShiftRequest.Generator
+const (
+	GeneratorLLM   = "llm"
+	GeneratorLocal = "local"
+	// GeneratorHybrid drafts with the LLM and repairs hard violations locally
+	GeneratorHybrid = "hybrid"
+)
+
+// Strategies given to the patterns of a parallel job, in pattern order
+const (
+	StrategyEvenDistribution = "even_distribution"
+	StrategyWeekendHeavy     = "weekend_heavy"
+	StrategyCostMinimizing   = "cost_minimizing"
+	StrategyPreferenceFirst  = "preference_first"
+	StrategyFixedRotation    = "fixed_rotation"
+)
+
+// PatternStrategies lists the strategies; pattern i of a parallel job uses
+// PatternStrategies[i % len(PatternStrategies)]
+var PatternStrategies = []string{
+	StrategyEvenDistribution,
+	StrategyWeekendHeavy,
+	StrategyCostMinimizing,
+	StrategyPreferenceFirst,
+	StrategyFixedRotation,
+}
+
+// GenerationParams is the input for generating a single pattern
+type GenerationParams struct {
+	YearMonth        string
+	PatternCount     int
+	PatternIdx       int
+	PreviousPatterns []LLMResponse
+	LastViolations   []Violation
+	// Strategy, when set, is the approach this pattern should take; parallel
+	// jobs use it instead of PreviousPatterns to keep patterns apart
+	Strategy string
+	// Instruction and Weights come from the job's GenerationGuidance
+	Instruction string
+	Weights     *ObjectiveWeights
+	// Scope, when set, limits generation to part of an existing pattern:
+	// generators return entries inside the scope only, working around
+	// LockedEntries, which stay as they are
+	Scope         *RegenerationScope
+	LockedEntries []LLMShiftEntry
+	// Seed makes generators that use randomness reproducible
+	Seed int64
+	// Trace, when set, receives the LLM calls made for this attempt
+	Trace *GenerationTrace
+}
+
+// GenerationTrace collects what a generator sent to and got back from the
+// LLM during one attempt. Generators that don't call an LLM leave it empty.
+type GenerationTrace struct {
+	SystemPrompt string
+	Calls        []LLMCall
+}
+
+// Tokens returns the total input and output tokens of the calls
+func (t *GenerationTrace) Tokens() (input, output int) {
+	for _, c := range t.Calls {
+		input += c.InputTokens
+		output += c.OutputTokens
+	}
+	return input, output
 }
 
 // CreateShiftEntryRequest is the request body for POST /shifts/entries
@@ -247,16 +576,24 @@ type PatternSummary struct {
 	StaffHours   map[string]float64 `json:"staff_hours"`
 }
 
-// EntryValidation is returned alongside an entry update
+// EntryValidation is returned alongside a manual entry change: the
+// violations and warnings of the pattern that concern the changed staff and
+// date, and the pattern's score after the change
 type EntryValidation struct {
-	IsValid  bool              `json:"is_valid"`
-	Warnings []ValidationWarning `json:"warnings"`
+	IsValid        bool                `json:"is_valid"`
+	Warnings       []ValidationWarning `json:"warnings"`
+	PatternScore   float64             `json:"pattern_score"`
+	ScoreBreakdown *ScoreBreakdown     `json:"score_breakdown"`
 }
 
-// ValidationWarning represents a soft constraint warning
+// ValidationWarning is a violation or warning found after a manual entry
+// change. Type is hard or soft for violations and the warning type otherwise.
 type ValidationWarning struct {
-	Type    string `json:"type"`
-	Message string `json:"message"`
+	Type       string `json:"type"`
+	Constraint string `json:"constraint,omitempty"`
+	StaffID    string `json:"staff_id,omitempty"`
+	Date       string `json:"date,omitempty"`
+	Message    string `json:"message"`
 }
 
 // --- LLM types ---
@@ -268,6 +605,33 @@ type LLMResponse struct {
 	ConstraintViolations []ConstraintViolation `json:"constraint_violations"`
 }
 
+// OutputSchemaError is returned by a generator whose output doesn't match
+// the LLMResponse schema. Details point at the offending fields.
+type OutputSchemaError struct {
+	Details []ErrorDetail
+}
+
+func (e *OutputSchemaError) Error() string {
+	if len(e.Details) == 0 {
+		return "出力がスキーマに一致しません"
+	}
+	return fmt.Sprintf("出力がスキーマに一致しません: %s: %s", e.Details[0].Field, e.Details[0].Message)
+}
+
+// Violations converts the schema errors into violations so they can be fed
+// back to the next generation attempt
+func (e *OutputSchemaError) Violations() []Violation {
+	violations := make([]Violation, 0, len(e.Details))
+	for _, d := range e.Details {
+		violations = append(violations, Violation{
+			Type:       "hard",
+			Constraint: "出力スキーマ",
+			Message:    fmt.Sprintf("%s: %s", d.Field, d.Message),
+		})
+	}
+	return violations
+}
+
 // LLMShiftEntry represents a single shift entry from LLM output
 type LLMShiftEntry struct {
 	StaffID      string `json:"staff_id"`
@@ -275,6 +639,39 @@ type LLMShiftEntry struct {
 	StartTime    string `json:"start_time"`
 	EndTime      string `json:"end_time"`
 	BreakMinutes int    `json:"break_minutes"`
+	// AutoRepaired marks entries added or changed by the repair stage
+	AutoRepaired bool `json:"-"`
+}
+
+// LLMEntries converts stored entries to the form generators and the
+// validator work on
+func LLMEntries(entries []ShiftEntry) []LLMShiftEntry {
+	result := make([]LLMShiftEntry, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, LLMShiftEntry{
+			StaffID:      e.StaffID,
+			Date:         e.Date,
+			StartTime:    e.StartTime,
+			EndTime:      e.EndTime,
+			BreakMinutes: e.BreakMinutes,
+		})
+	}
+	return result
+}
+
+// RepairAction records one change made by the repair stage
+type RepairAction struct {
+	Action  string `json:"action"` // removed / added / adjusted
+	StaffID string `json:"staff_id"`
+	Date    string `json:"date"`
+	Reason  string `json:"reason"`
+}
+
+// RepairResult is a generated response after its hard violations were
+// mechanically fixed
+type RepairResult struct {
+	Response *LLMResponse
+	Actions  []RepairAction
 }
 
 // ValidationResult is returned by the shift validator
@@ -283,6 +680,10 @@ type ValidationResult struct {
 	Violations []Violation `json:"violations"`
 	Warnings   []Warning   `json:"warnings"`
 	Score      float64     `json:"score"`
+	// Breakdown explains Score component by component
+	Breakdown *ScoreBreakdown `json:"breakdown,omitempty"`
+	// PreferenceFulfillment lists the staff with preferred days, by staff ID
+	PreferenceFulfillment []PreferenceFulfillment `json:"preference_fulfillment,omitempty"`
 }
 
 // HasHardViolations returns true if there are any hard constraint violations
@@ -295,18 +696,34 @@ func (v *ValidationResult) HasHardViolations() bool {
 	return false
 }
 
-// Violation represents a constraint violation
+// Violation represents a constraint violation. RelatedStaffIDs are the
+// other staff a violation between staff (such as a pair that must not work
+// together) is about.
 type Violation struct {
-	Type       string `json:"type"`
-	Constraint string `json:"constraint"`
-	Date       string `json:"date,omitempty"`
-	StaffID    string `json:"staff_id,omitempty"`
-	Message    string `json:"message"`
+	Type            string   `json:"type"`
+	Constraint      string   `json:"constraint"`
+	Date            string   `json:"date,omitempty"`
+	StartTime       string   `json:"start_time,omitempty"`
+	EndTime         string   `json:"end_time,omitempty"`
+	StaffID         string   `json:"staff_id,omitempty"`
+	RelatedStaffIDs []string `json:"related_staff_ids,omitempty"`
+	Message         string   `json:"message"`
 }
 
 // Warning represents a soft constraint warning
 type Warning struct {
 	Type       string `json:"type"`
 	Constraint string `json:"constraint"`
+	StaffID    string `json:"staff_id,omitempty"`
+	Date       string `json:"date,omitempty"`
 	Message    string `json:"message"`
 }
+
+// PreferenceFulfillment is how many of a staff's preferred (◎) days got a
+// shift
+type PreferenceFulfillment struct {
+	StaffID   string  `json:"staff_id"`
+	Preferred int     `json:"preferred"`
+	Assigned  int     `json:"assigned"`
+	Rate      float64 `json:"rate"`
+}
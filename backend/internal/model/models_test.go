@@ -51,3 +51,29 @@ func TestValidationResult_HasHardViolations(t *testing.T) {
 		})
 	}
 }
+
+func TestRegenerationScope_Contains(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   RegenerationScope
+		staffID string
+		date    string
+		want    bool
+	}{
+		{"date range inside", RegenerationScope{StartDate: "2025-02-10", EndDate: "2025-02-16"}, "s1", "2025-02-10", true},
+		{"date range end inclusive", RegenerationScope{StartDate: "2025-02-10", EndDate: "2025-02-16"}, "s1", "2025-02-16", true},
+		{"date range before", RegenerationScope{StartDate: "2025-02-10", EndDate: "2025-02-16"}, "s1", "2025-02-09", false},
+		{"open start", RegenerationScope{EndDate: "2025-02-16"}, "s1", "2025-02-01", true},
+		{"staff listed", RegenerationScope{StaffIDs: []string{"s1", "s2"}}, "s2", "2025-02-01", true},
+		{"staff not listed", RegenerationScope{StaffIDs: []string{"s1"}}, "s2", "2025-02-01", false},
+		{"staff listed, date outside", RegenerationScope{StartDate: "2025-02-10", StaffIDs: []string{"s1"}}, "s1", "2025-02-01", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scope.Contains(tt.staffID, tt.date); got != tt.want {
+				t.Errorf("Contains(%q, %q) = %v, want %v", tt.staffID, tt.date, got, tt.want)
+			}
+		})
+	}
+}
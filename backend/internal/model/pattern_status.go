@@ -0,0 +1,79 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// Statuses of a shift pattern
+const (
+	PatternStatusDraft     = "draft"
+	PatternStatusSelected  = "selected"
+	PatternStatusFinalized = "finalized"
+	PatternStatusPublished = "published"
+	PatternStatusArchived  = "archived"
+)
+
+// patternTransitions lists the statuses each status can move to:
+//
+//	draft → selected → finalized → published → archived
+//
+// A selected pattern goes back to draft when another one is selected,
+// unfinalizing takes a finalized pattern back to selected, and revising
+// reopens a published one. Drafts that were never used can be archived.
+var patternTransitions = map[string][]string{
+	PatternStatusDraft:     {PatternStatusSelected, PatternStatusArchived},
+	PatternStatusSelected:  {PatternStatusDraft, PatternStatusFinalized},
+	PatternStatusFinalized: {PatternStatusSelected, PatternStatusPublished},
+	PatternStatusPublished: {PatternStatusSelected, PatternStatusArchived},
+	PatternStatusArchived:  {},
+}
+
+// patternStatusLabels are the statuses as shown in messages
+var patternStatusLabels = map[string]string{
+	PatternStatusDraft:     "下書き",
+	PatternStatusSelected:  "選択中",
+	PatternStatusFinalized: "確定済み",
+	PatternStatusPublished: "公開済み",
+	PatternStatusArchived:  "アーカイブ済み",
+}
+
+// CanTransitionPattern reports whether a pattern can move from one status to
+// another
+func CanTransitionPattern(from, to string) bool {
+	return slices.Contains(patternTransitions[from], to)
+}
+
+// PatternStatusLabel returns the label of a status, or the status itself when
+// it is unknown
+func PatternStatusLabel(status string) string {
+	if label, ok := patternStatusLabels[status]; ok {
+		return label
+	}
+	return status
+}
+
+// PatternEditable reports whether the entries of a pattern in a status can
+// be changed. Finalized and published patterns are locked until they are
+// reopened, archived ones for good.
+func PatternEditable(status string) bool {
+	return status == PatternStatusDraft || status == PatternStatusSelected
+}
+
+// PatternStatusChange represents the shift_pattern_status_history table
+type PatternStatusChange struct {
+	ID         string    `json:"id"`
+	PatternID  string    `json:"pattern_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  string    `json:"changed_by"`
+	Reason     string    `json:"reason"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// PatternTransitionRequest is the optional body of the pattern lifecycle
+// endpoints (select, unfinalize, publish, revise, archive)
+type PatternTransitionRequest struct {
+	ChangedBy string `json:"changed_by"`
+	Reason    string `json:"reason"`
+}
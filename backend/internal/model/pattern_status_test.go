@@ -0,0 +1,46 @@
+package model
+
+import "testing"
+
+func TestCanTransitionPattern(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{PatternStatusDraft, PatternStatusSelected, true},
+		{PatternStatusDraft, PatternStatusFinalized, false},
+		{PatternStatusSelected, PatternStatusFinalized, true},
+		{PatternStatusSelected, PatternStatusDraft, true},
+		{PatternStatusSelected, PatternStatusPublished, false},
+		{PatternStatusFinalized, PatternStatusPublished, true},
+		{PatternStatusFinalized, PatternStatusSelected, true},
+		{PatternStatusFinalized, PatternStatusArchived, false},
+		{PatternStatusPublished, PatternStatusSelected, true},
+		{PatternStatusPublished, PatternStatusArchived, true},
+		{PatternStatusArchived, PatternStatusDraft, false},
+		{"unknown", PatternStatusSelected, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			if got := CanTransitionPattern(tt.from, tt.to); got != tt.want {
+				t.Errorf("CanTransitionPattern(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternEditable(t *testing.T) {
+	for status, want := range map[string]bool{
+		PatternStatusDraft:     true,
+		PatternStatusSelected:  true,
+		PatternStatusFinalized: false,
+		PatternStatusPublished: false,
+		PatternStatusArchived:  false,
+	} {
+		if got := PatternEditable(status); got != want {
+			t.Errorf("PatternEditable(%q) = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// MinutesPerDay is the number of minutes in a calendar day
+const MinutesPerDay = 24 * 60
+
+// ClockMinutes converts "HH:MM" (or "HH:MM:SS" as returned by TIME::text) to
+// minutes since midnight. It returns -1 when the value can't be parsed.
+func ClockMinutes(hhmm string) int {
+	var h, m int
+	if n, _ := fmt.Sscanf(hhmm, "%d:%d", &h, &m); n != 2 || h < 0 || h > 24 || m < 0 || m > 59 {
+		return -1
+	}
+	return h*60 + m
+}
+
+// FormatClock converts minutes since midnight to "HH:MM", wrapping past 24:00
+func FormatClock(minutes int) string {
+	minutes = ((minutes % MinutesPerDay) + MinutesPerDay) % MinutesPerDay
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// ShiftSpan returns the start and end of a shift in minutes from midnight of
+// the shift's date. An end at or before the start means the shift ends on the
+// next day (e.g. 20:00-02:00), so end can exceed MinutesPerDay.
+func ShiftSpan(startTime, endTime string) (int, int) {
+	start, end := ClockMinutes(startTime), ClockMinutes(endTime)
+	if end <= start {
+		end += MinutesPerDay
+	}
+	return start, end
+}
+
+// IsOvernight reports whether a shift ends on the day after it starts
+func IsOvernight(startTime, endTime string) bool {
+	start, end := ClockMinutes(startTime), ClockMinutes(endTime)
+	return end >= 0 && start >= 0 && end < start
+}
+
+// WorkMinutes returns the worked minutes of a shift excluding the break,
+// clamped to 0.
+func WorkMinutes(startTime, endTime string, breakMinutes int) int {
+	start, end := ShiftSpan(startTime, endTime)
+	total := end - start - breakMinutes
+	if total < 0 {
+		return 0
+	}
+	return total
+}
+
+// DayIndex returns the number of days since the Unix epoch for a YYYY-MM-DD
+// date, so that shifts on different dates can be compared on one time axis.
+func DayIndex(date string) (int, bool) {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return 0, false
+	}
+	return int(d.Unix() / 86400), true
+}
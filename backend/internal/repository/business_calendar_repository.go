@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"shift-app/internal/model"
+)
+
+type BusinessCalendarRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBusinessCalendarRepository(db *pgxpool.Pool) *BusinessCalendarRepository {
+	return &BusinessCalendarRepository{db: db}
+}
+
+func (r *BusinessCalendarRepository) ListHours(ctx context.Context) ([]model.BusinessHours, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT weekday, to_char(open_time, 'HH24:MI'), to_char(close_time, 'HH24:MI'), is_closed
+		 FROM business_hours ORDER BY weekday`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hours []model.BusinessHours
+	for rows.Next() {
+		var h model.BusinessHours
+		if err := rows.Scan(&h.Weekday, &h.OpenTime, &h.CloseTime, &h.IsClosed); err != nil {
+			return nil, err
+		}
+		hours = append(hours, h)
+	}
+	return hours, rows.Err()
+}
+
+// ReplaceHours upserts the regular hours for every weekday in hours
+func (r *BusinessCalendarRepository) ReplaceHours(ctx context.Context, hours []model.BusinessHours) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, h := range hours {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO business_hours (weekday, open_time, close_time, is_closed)
+			 VALUES ($1, $2, $3, $4)
+			 ON CONFLICT (weekday)
+			 DO UPDATE SET open_time = EXCLUDED.open_time,
+			               close_time = EXCLUDED.close_time,
+			               is_closed = EXCLUDED.is_closed,
+			               updated_at = NOW()`,
+			h.Weekday, h.OpenTime, h.CloseTime, h.IsClosed)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *BusinessCalendarRepository) ListSpecialDays(ctx context.Context, yearMonth string) ([]model.BusinessSpecialDay, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT date::text, is_closed, to_char(open_time, 'HH24:MI'), to_char(close_time, 'HH24:MI'), note, created_at, updated_at
+		 FROM business_special_days
+		 WHERE to_char(date, 'YYYY-MM') = $1
+		 ORDER BY date`, yearMonth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []model.BusinessSpecialDay
+	for rows.Next() {
+		var d model.BusinessSpecialDay
+		if err := rows.Scan(&d.Date, &d.IsClosed, &d.OpenTime, &d.CloseTime, &d.Note, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+func (r *BusinessCalendarRepository) UpsertSpecialDay(ctx context.Context, date string, req model.UpsertBusinessSpecialDayRequest) (*model.BusinessSpecialDay, error) {
+	var d model.BusinessSpecialDay
+	err := r.db.QueryRow(ctx,
+		`INSERT INTO business_special_days (date, is_closed, open_time, close_time, note)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (date)
+		 DO UPDATE SET is_closed = EXCLUDED.is_closed,
+		               open_time = EXCLUDED.open_time,
+		               close_time = EXCLUDED.close_time,
+		               note = EXCLUDED.note,
+		               updated_at = NOW()
+		 RETURNING date::text, is_closed, to_char(open_time, 'HH24:MI'), to_char(close_time, 'HH24:MI'), note, created_at, updated_at`,
+		date, req.IsClosed, req.OpenTime, req.CloseTime, req.Note,
+	).Scan(&d.Date, &d.IsClosed, &d.OpenTime, &d.CloseTime, &d.Note, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *BusinessCalendarRepository) DeleteSpecialDay(ctx context.Context, date string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM business_special_days WHERE date = $1`, date)
+	return err
+}
+
+// GetCalendar loads regular hours and the special days of yearMonth
+func (r *BusinessCalendarRepository) GetCalendar(ctx context.Context, yearMonth string) (*model.BusinessCalendar, error) {
+	hours, err := r.ListHours(ctx)
+	if err != nil {
+		return nil, err
+	}
+	special, err := r.ListSpecialDays(ctx, yearMonth)
+	if err != nil {
+		return nil, err
+	}
+	return model.NewBusinessCalendar(hours, special), nil
+}
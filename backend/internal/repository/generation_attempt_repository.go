@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"shift-app/internal/model"
+)
+
+type GenerationAttemptRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGenerationAttemptRepository(db *pgxpool.Pool) *GenerationAttemptRepository {
+	return &GenerationAttemptRepository{db: db}
+}
+
+func (r *GenerationAttemptRepository) Create(ctx context.Context, a *model.GenerationAttempt) error {
+	if a.LLMCalls == nil {
+		a.LLMCalls = []model.LLMCall{}
+	}
+	if a.ParseErrors == nil {
+		a.ParseErrors = []model.ErrorDetail{}
+	}
+	if a.Repairs == nil {
+		a.Repairs = []model.RepairAction{}
+	}
+	callsJSON, _ := json.Marshal(a.LLMCalls)
+	parseErrorsJSON, _ := json.Marshal(a.ParseErrors)
+	repairsJSON, _ := json.Marshal(a.Repairs)
+	var responseJSON, validationJSON []byte
+	if a.Response != nil {
+		responseJSON, _ = json.Marshal(a.Response)
+	}
+	if a.Validation != nil {
+		validationJSON, _ = json.Marshal(a.Validation)
+	}
+
+	return r.db.QueryRow(ctx,
+		`INSERT INTO generation_attempts (job_id, pattern_index, retry, generator, outcome, system_prompt, llm_calls, response,
+		 input_tokens, output_tokens, latency_ms, parse_errors, error_message, validation, repairs)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		 RETURNING id, created_at`,
+		a.JobID, a.PatternIndex, a.Retry, a.Generator, a.Outcome, a.SystemPrompt, callsJSON, responseJSON,
+		a.InputTokens, a.OutputTokens, a.LatencyMs, parseErrorsJSON, a.ErrorMessage, validationJSON, repairsJSON,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+func (r *GenerationAttemptRepository) ListByJobID(ctx context.Context, jobID string) ([]model.GenerationAttempt, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, job_id, pattern_index, retry, generator, outcome, system_prompt, llm_calls, response,
+		 input_tokens, output_tokens, latency_ms, parse_errors, error_message, validation, repairs, created_at
+		 FROM generation_attempts WHERE job_id = $1 ORDER BY pattern_index, retry, created_at`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []model.GenerationAttempt
+	for rows.Next() {
+		var a model.GenerationAttempt
+		var callsJSON, responseJSON, parseErrorsJSON, validationJSON, repairsJSON []byte
+		if err := rows.Scan(&a.ID, &a.JobID, &a.PatternIndex, &a.Retry, &a.Generator, &a.Outcome, &a.SystemPrompt, &callsJSON, &responseJSON,
+			&a.InputTokens, &a.OutputTokens, &a.LatencyMs, &parseErrorsJSON, &a.ErrorMessage, &validationJSON, &repairsJSON, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal(callsJSON, &a.LLMCalls)
+		_ = json.Unmarshal(parseErrorsJSON, &a.ParseErrors)
+		_ = json.Unmarshal(repairsJSON, &a.Repairs)
+		if responseJSON != nil {
+			_ = json.Unmarshal(responseJSON, &a.Response)
+		}
+		if validationJSON != nil {
+			_ = json.Unmarshal(validationJSON, &a.Validation)
+		}
+		if a.LLMCalls == nil {
+			a.LLMCalls = []model.LLMCall{}
+		}
+		if a.ParseErrors == nil {
+			a.ParseErrors = []model.ErrorDetail{}
+		}
+		if a.Repairs == nil {
+			a.Repairs = []model.RepairAction{}
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -18,31 +19,111 @@ func NewGenerationJobRepository(db *pgxpool.Pool) *GenerationJobRepository {
 	return &GenerationJobRepository{db: db}
 }
 
-func (r *GenerationJobRepository) Create(ctx context.Context, yearMonth string, patternCount int) (*model.GenerationJob, error) {
+func (r *GenerationJobRepository) Create(ctx context.Context, yearMonth string, patternCount int, generator string, seed *int64, parallel bool, guidance model.GenerationGuidance) (*model.GenerationJob, error) {
+	strategiesBytes, weightsBytes, err := encodeGuidance(guidance)
+	if err != nil {
+		return nil, err
+	}
+
 	var j model.GenerationJob
-	err := r.db.QueryRow(ctx,
-		`INSERT INTO generation_jobs (year_month, pattern_count) VALUES ($1, $2)
-		 RETURNING id, year_month, status, pattern_count, progress, status_message, error_message, started_at, completed_at, created_at`,
-		yearMonth, patternCount,
-	).Scan(&j.ID, &j.YearMonth, &j.Status, &j.PatternCount, &j.Progress, &j.StatusMessage, &j.ErrorMessage, &j.StartedAt, &j.CompletedAt, &j.CreatedAt)
+	var scopeJSON, strategiesJSON, weightsJSON []byte
+	err = r.db.QueryRow(ctx,
+		`INSERT INTO generation_jobs (year_month, pattern_count, generator, seed, parallel, instruction, strategies, weights) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING id, year_month, status, pattern_count, progress, status_message, error_message, generator, seed, parallel, target_pattern_id, scope, instruction, strategies, weights, started_at, completed_at, created_at`,
+		yearMonth, patternCount, generator, seed, parallel, guidance.Instruction, strategiesBytes, weightsBytes,
+	).Scan(&j.ID, &j.YearMonth, &j.Status, &j.PatternCount, &j.Progress, &j.StatusMessage, &j.ErrorMessage, &j.Generator, &j.Seed, &j.Parallel, &j.TargetPatternID, &scopeJSON, &j.Instruction, &strategiesJSON, &weightsJSON, &j.StartedAt, &j.CompletedAt, &j.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	decodeScope(scopeJSON, &j)
+	decodeGuidance(strategiesJSON, weightsJSON, &j)
+	return &j, nil
+}
+
+// CreateRegeneration queues a job that regenerates the scope of a pattern
+func (r *GenerationJobRepository) CreateRegeneration(ctx context.Context, yearMonth string, generator string, seed *int64, patternID string, scope model.RegenerationScope, guidance model.GenerationGuidance) (*model.GenerationJob, error) {
+	scopeBytes, err := json.Marshal(scope)
+	if err != nil {
+		return nil, err
+	}
+	strategiesBytes, weightsBytes, err := encodeGuidance(guidance)
+	if err != nil {
+		return nil, err
+	}
+
+	var j model.GenerationJob
+	var scopeJSON, strategiesJSON, weightsJSON []byte
+	err = r.db.QueryRow(ctx,
+		`INSERT INTO generation_jobs (year_month, pattern_count, generator, seed, target_pattern_id, scope, instruction, strategies, weights) VALUES ($1, 1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING id, year_month, status, pattern_count, progress, status_message, error_message, generator, seed, parallel, target_pattern_id, scope, instruction, strategies, weights, started_at, completed_at, created_at`,
+		yearMonth, generator, seed, patternID, scopeBytes, guidance.Instruction, strategiesBytes, weightsBytes,
+	).Scan(&j.ID, &j.YearMonth, &j.Status, &j.PatternCount, &j.Progress, &j.StatusMessage, &j.ErrorMessage, &j.Generator, &j.Seed, &j.Parallel, &j.TargetPatternID, &scopeJSON, &j.Instruction, &strategiesJSON, &weightsJSON, &j.StartedAt, &j.CompletedAt, &j.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
+	decodeScope(scopeJSON, &j)
+	decodeGuidance(strategiesJSON, weightsJSON, &j)
 	return &j, nil
 }
 
+// decodeScope fills j.Scope from the scope column, leaving it nil for jobs
+// that create new patterns
+func decodeScope(raw []byte, j *model.GenerationJob) {
+	if raw == nil {
+		return
+	}
+	var scope model.RegenerationScope
+	if err := json.Unmarshal(raw, &scope); err == nil {
+		j.Scope = &scope
+	}
+}
+
+// encodeGuidance returns the strategies and weights columns of a job, NULL
+// when unset
+func encodeGuidance(g model.GenerationGuidance) ([]byte, []byte, error) {
+	var strategies, weights []byte
+	var err error
+	if len(g.Strategies) > 0 {
+		if strategies, err = json.Marshal(g.Strategies); err != nil {
+			return nil, nil, err
+		}
+	}
+	if g.Weights != nil {
+		if weights, err = json.Marshal(g.Weights); err != nil {
+			return nil, nil, err
+		}
+	}
+	return strategies, weights, nil
+}
+
+// decodeGuidance fills the strategies and weights of j from their columns
+func decodeGuidance(strategies, weights []byte, j *model.GenerationJob) {
+	if strategies != nil {
+		_ = json.Unmarshal(strategies, &j.Strategies)
+	}
+	if weights != nil {
+		var w model.ObjectiveWeights
+		if err := json.Unmarshal(weights, &w); err == nil {
+			j.Weights = &w
+		}
+	}
+}
+
 func (r *GenerationJobRepository) GetByID(ctx context.Context, id string) (*model.GenerationJob, error) {
 	var j model.GenerationJob
+	var scopeJSON, strategiesJSON, weightsJSON []byte
 	err := r.db.QueryRow(ctx,
-		`SELECT id, year_month, status, pattern_count, progress, status_message, error_message, started_at, completed_at, created_at
+		`SELECT id, year_month, status, pattern_count, progress, status_message, error_message, generator, seed, parallel, target_pattern_id, scope, instruction, strategies, weights, started_at, completed_at, created_at
 		 FROM generation_jobs WHERE id = $1`, id,
-	).Scan(&j.ID, &j.YearMonth, &j.Status, &j.PatternCount, &j.Progress, &j.StatusMessage, &j.ErrorMessage, &j.StartedAt, &j.CompletedAt, &j.CreatedAt)
+	).Scan(&j.ID, &j.YearMonth, &j.Status, &j.PatternCount, &j.Progress, &j.StatusMessage, &j.ErrorMessage, &j.Generator, &j.Seed, &j.Parallel, &j.TargetPatternID, &scopeJSON, &j.Instruction, &strategiesJSON, &weightsJSON, &j.StartedAt, &j.CompletedAt, &j.CreatedAt)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
+	decodeScope(scopeJSON, &j)
+	decodeGuidance(strategiesJSON, weightsJSON, &j)
 	return &j, nil
 }
 
@@ -52,33 +133,140 @@ func (r *GenerationJobRepository) UpdateStatus(ctx context.Context, id string, s
 	return err
 }
 
-func (r *GenerationJobRepository) SetProcessing(ctx context.Context, id string) error {
-	now := time.Now()
+// Claim takes the oldest pending job for workerID and marks it processing.
+// Concurrent workers skip rows another worker has locked, so a job is
+// claimed once. It returns nil when the queue is empty.
+func (r *GenerationJobRepository) Claim(ctx context.Context, workerID string) (*model.GenerationJob, error) {
+	var j model.GenerationJob
+	var scopeJSON, strategiesJSON, weightsJSON []byte
+	err := r.db.QueryRow(ctx,
+		`UPDATE generation_jobs
+		 SET status = 'processing', started_at = COALESCE(started_at, NOW()), locked_by = $1, heartbeat_at = NOW()
+		 WHERE id = (
+		   SELECT id FROM generation_jobs WHERE status = 'pending'
+		   ORDER BY created_at ASC
+		   FOR UPDATE SKIP LOCKED
+		   LIMIT 1
+		 )
+		 RETURNING id, year_month, status, pattern_count, progress, status_message, error_message, generator, seed, parallel, target_pattern_id, scope, instruction, strategies, weights, started_at, completed_at, created_at`,
+		workerID,
+	).Scan(&j.ID, &j.YearMonth, &j.Status, &j.PatternCount, &j.Progress, &j.StatusMessage, &j.ErrorMessage, &j.Generator, &j.Seed, &j.Parallel, &j.TargetPatternID, &scopeJSON, &j.Instruction, &strategiesJSON, &weightsJSON, &j.StartedAt, &j.CompletedAt, &j.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	decodeScope(scopeJSON, &j)
+	decodeGuidance(strategiesJSON, weightsJSON, &j)
+	return &j, nil
+}
+
+// Heartbeat refreshes the claim of workerID on a job and returns the job's
+// status. It returns "" when the job is no longer claimed by workerID.
+func (r *GenerationJobRepository) Heartbeat(ctx context.Context, id string, workerID string) (string, error) {
+	var status string
+	err := r.db.QueryRow(ctx,
+		`UPDATE generation_jobs SET heartbeat_at = NOW() WHERE id = $1 AND locked_by = $2 RETURNING status`,
+		id, workerID,
+	).Scan(&status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return status, nil
+}
+
+// Release hands a processing job claimed by workerID back to the queue
+func (r *GenerationJobRepository) Release(ctx context.Context, id string, workerID string) error {
 	_, err := r.db.Exec(ctx,
-		`UPDATE generation_jobs SET status = 'processing', started_at = $1 WHERE id = $2`, now, id)
+		`UPDATE generation_jobs SET status = 'pending', locked_by = NULL, heartbeat_at = NULL
+		 WHERE id = $1 AND locked_by = $2 AND status = 'processing'`, id, workerID)
 	return err
 }
 
 func (r *GenerationJobRepository) SetCompleted(ctx context.Context, id string) error {
 	now := time.Now()
 	_, err := r.db.Exec(ctx,
-		`UPDATE generation_jobs SET status = 'completed', completed_at = $1 WHERE id = $2`, now, id)
+		`UPDATE generation_jobs SET status = 'completed', progress = 100, completed_at = $1 WHERE id = $2 AND status = 'processing'`, now, id)
 	return err
 }
 
 func (r *GenerationJobRepository) SetFailed(ctx context.Context, id string, errMsg string) error {
 	now := time.Now()
 	_, err := r.db.Exec(ctx,
-		`UPDATE generation_jobs SET status = 'failed', error_message = $1, completed_at = $2 WHERE id = $3`, errMsg, now, id)
+		`UPDATE generation_jobs SET status = 'failed', error_message = $1, completed_at = $2 WHERE id = $3 AND status IN ('pending', 'processing')`, errMsg, now, id)
 	return err
 }
 
+// SetCancelled cancels a pending or processing job. It reports false when
+// the job had already finished.
+func (r *GenerationJobRepository) SetCancelled(ctx context.Context, id string) (bool, error) {
+	now := time.Now()
+	tag, err := r.db.Exec(ctx,
+		`UPDATE generation_jobs SET status = 'cancelled', completed_at = $1 WHERE id = $2 AND status IN ('pending', 'processing')`, now, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Requeue puts a failed or cancelled job back to pending so it can be run
+// again. It returns false when the job is in another status.
+func (r *GenerationJobRepository) Requeue(ctx context.Context, id string) (bool, error) {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE generation_jobs SET status = 'pending', error_message = NULL, completed_at = NULL, locked_by = NULL, heartbeat_at = NULL
+		 WHERE id = $1 AND status IN ('failed', 'cancelled')`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// staleCondition matches processing jobs whose worker stopped sending
+// heartbeats ($1 is the threshold)
+const staleCondition = `status = 'processing' AND COALESCE(heartbeat_at, started_at, created_at) < $1`
+
+// RequeueStale puts processing jobs without a heartbeat since before
+// threshold back to pending and returns how many there were
+func (r *GenerationJobRepository) RequeueStale(ctx context.Context, threshold time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE generation_jobs SET status = 'pending', locked_by = NULL, heartbeat_at = NULL WHERE `+staleCondition, threshold)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// FailStale marks processing jobs without a heartbeat since before threshold
+// failed and returns how many there were
+func (r *GenerationJobRepository) FailStale(ctx context.Context, threshold time.Time, errMsg string) (int64, error) {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE generation_jobs SET status = 'failed', error_message = $2, completed_at = NOW(), locked_by = NULL WHERE `+staleCondition, threshold, errMsg)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
 func (r *GenerationJobRepository) UpdateProgress(ctx context.Context, id string, progress int, statusMessage string) error {
 	_, err := r.db.Exec(ctx,
 		`UPDATE generation_jobs SET progress = $1, status_message = $2 WHERE id = $3`, progress, statusMessage, id)
 	return err
 }
 
+// HasActiveRegeneration reports whether a regeneration job of a pattern is
+// pending or processing
+func (r *GenerationJobRepository) HasActiveRegeneration(ctx context.Context, patternID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM generation_jobs WHERE target_pattern_id = $1 AND status IN ('pending', 'processing'))`, patternID,
+	).Scan(&exists)
+	return exists, err
+}
+
 func (r *GenerationJobRepository) HasProcessingJob(ctx context.Context, yearMonth string) (bool, error) {
 	var count int
 	err := r.db.QueryRow(ctx,
@@ -19,7 +19,7 @@ func NewShiftEntryRepository(db *pgxpool.Pool) *ShiftEntryRepository {
 
 func (r *ShiftEntryRepository) ListByPatternID(ctx context.Context, patternID string) ([]model.ShiftEntry, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT se.id, se.pattern_id, se.staff_id, s.name, se.date::text, se.start_time::text, se.end_time::text, se.break_minutes, se.is_manual_edit, se.created_at, se.updated_at
+		`SELECT se.id, se.pattern_id, se.staff_id, s.name, se.date::text, se.start_time::text, se.end_time::text, se.break_minutes, se.is_manual_edit, se.is_auto_repaired, se.created_at, se.updated_at
 		 FROM shift_entries se
 		 JOIN staffs s ON s.id = se.staff_id
 		 WHERE se.pattern_id = $1
@@ -32,7 +32,7 @@ func (r *ShiftEntryRepository) ListByPatternID(ctx context.Context, patternID st
 	var entries []model.ShiftEntry
 	for rows.Next() {
 		var e model.ShiftEntry
-		if err := rows.Scan(&e.ID, &e.PatternID, &e.StaffID, &e.StaffName, &e.Date, &e.StartTime, &e.EndTime, &e.BreakMinutes, &e.IsManualEdit, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		if err := rows.Scan(&e.ID, &e.PatternID, &e.StaffID, &e.StaffName, &e.Date, &e.StartTime, &e.EndTime, &e.BreakMinutes, &e.IsManualEdit, &e.IsAutoRepaired, &e.CreatedAt, &e.UpdatedAt); err != nil {
 			return nil, err
 		}
 		entries = append(entries, e)
@@ -43,11 +43,11 @@ func (r *ShiftEntryRepository) ListByPatternID(ctx context.Context, patternID st
 func (r *ShiftEntryRepository) GetByID(ctx context.Context, id string) (*model.ShiftEntry, error) {
 	var e model.ShiftEntry
 	err := r.db.QueryRow(ctx,
-		`SELECT se.id, se.pattern_id, se.staff_id, s.name, se.date::text, se.start_time::text, se.end_time::text, se.break_minutes, se.is_manual_edit, se.created_at, se.updated_at
+		`SELECT se.id, se.pattern_id, se.staff_id, s.name, se.date::text, se.start_time::text, se.end_time::text, se.break_minutes, se.is_manual_edit, se.is_auto_repaired, se.created_at, se.updated_at
 		 FROM shift_entries se
 		 JOIN staffs s ON s.id = se.staff_id
 		 WHERE se.id = $1`, id,
-	).Scan(&e.ID, &e.PatternID, &e.StaffID, &e.StaffName, &e.Date, &e.StartTime, &e.EndTime, &e.BreakMinutes, &e.IsManualEdit, &e.CreatedAt, &e.UpdatedAt)
+	).Scan(&e.ID, &e.PatternID, &e.StaffID, &e.StaffName, &e.Date, &e.StartTime, &e.EndTime, &e.BreakMinutes, &e.IsManualEdit, &e.IsAutoRepaired, &e.CreatedAt, &e.UpdatedAt)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -62,9 +62,9 @@ func (r *ShiftEntryRepository) Create(ctx context.Context, req model.CreateShift
 	err := r.db.QueryRow(ctx,
 		`INSERT INTO shift_entries (pattern_id, staff_id, date, start_time, end_time, break_minutes, is_manual_edit)
 		 VALUES ($1, $2, $3, $4, $5, $6, true)
-		 RETURNING id, pattern_id, staff_id, date::text, start_time::text, end_time::text, break_minutes, is_manual_edit, created_at, updated_at`,
+		 RETURNING id, pattern_id, staff_id, date::text, start_time::text, end_time::text, break_minutes, is_manual_edit, is_auto_repaired, created_at, updated_at`,
 		req.PatternID, req.StaffID, req.Date, req.StartTime, req.EndTime, req.BreakMinutes,
-	).Scan(&e.ID, &e.PatternID, &e.StaffID, &e.Date, &e.StartTime, &e.EndTime, &e.BreakMinutes, &e.IsManualEdit, &e.CreatedAt, &e.UpdatedAt)
+	).Scan(&e.ID, &e.PatternID, &e.StaffID, &e.Date, &e.StartTime, &e.EndTime, &e.BreakMinutes, &e.IsManualEdit, &e.IsAutoRepaired, &e.CreatedAt, &e.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -103,9 +103,9 @@ func (r *ShiftEntryRepository) Update(ctx context.Context, id string, req model.
 	err = r.db.QueryRow(ctx,
 		`UPDATE shift_entries SET start_time=$1, end_time=$2, break_minutes=$3, is_manual_edit=true, updated_at=NOW()
 		 WHERE id=$4
-		 RETURNING id, pattern_id, staff_id, date::text, start_time::text, end_time::text, break_minutes, is_manual_edit, created_at, updated_at`,
+		 RETURNING id, pattern_id, staff_id, date::text, start_time::text, end_time::text, break_minutes, is_manual_edit, is_auto_repaired, created_at, updated_at`,
 		startTime, endTime, breakMinutes, id,
-	).Scan(&e.ID, &e.PatternID, &e.StaffID, &e.Date, &e.StartTime, &e.EndTime, &e.BreakMinutes, &e.IsManualEdit, &e.CreatedAt, &e.UpdatedAt)
+	).Scan(&e.ID, &e.PatternID, &e.StaffID, &e.Date, &e.StartTime, &e.EndTime, &e.BreakMinutes, &e.IsManualEdit, &e.IsAutoRepaired, &e.CreatedAt, &e.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -131,9 +131,9 @@ func (r *ShiftEntryRepository) BulkCreate(ctx context.Context, patternID string,
 
 	for _, entry := range entries {
 		_, err := tx.Exec(ctx,
-			`INSERT INTO shift_entries (pattern_id, staff_id, date, start_time, end_time, break_minutes)
-			 VALUES ($1, $2, $3, $4, $5, $6)`,
-			patternID, entry.StaffID, entry.Date, entry.StartTime, entry.EndTime, entry.BreakMinutes)
+			`INSERT INTO shift_entries (pattern_id, staff_id, date, start_time, end_time, break_minutes, is_auto_repaired)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			patternID, entry.StaffID, entry.Date, entry.StartTime, entry.EndTime, entry.BreakMinutes, entry.AutoRepaired)
 		if err != nil {
 			return err
 		}
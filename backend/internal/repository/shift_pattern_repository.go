@@ -3,13 +3,24 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"shift-app/internal/model"
 )
 
+// ErrMonthLocked is returned when a pattern would become the second
+// finalized or published pattern of its month, which the
+// idx_shift_patterns_one_locked index refuses
+var ErrMonthLocked = errors.New("この月にはすでに確定済みか公開済みのパターンがあります")
+
+// ErrPatternLocked is returned when the entries of a pattern that was
+// finalized, published or archived meanwhile would be replaced
+var ErrPatternLocked = errors.New("パターンが編集できない状態に変更されました")
+
 type ShiftPatternRepository struct {
 	db *pgxpool.Pool
 }
@@ -20,7 +31,7 @@ func NewShiftPatternRepository(db *pgxpool.Pool) *ShiftPatternRepository {
 
 func (r *ShiftPatternRepository) ListByYearMonth(ctx context.Context, yearMonth string) ([]model.ShiftPattern, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, year_month, status, reasoning, score, constraint_violations, created_at, updated_at
+		`SELECT id, year_month, status, reasoning, score, constraint_violations, repairs, job_id, pattern_index, strategy, diversity_score, score_breakdown, created_at, updated_at
 		 FROM shift_patterns WHERE year_month = $1 ORDER BY created_at ASC`, yearMonth)
 	if err != nil {
 		return nil, err
@@ -30,8 +41,38 @@ func (r *ShiftPatternRepository) ListByYearMonth(ctx context.Context, yearMonth
 	var patterns []model.ShiftPattern
 	for rows.Next() {
 		var p model.ShiftPattern
-		var violationsJSON []byte
-		if err := rows.Scan(&p.ID, &p.YearMonth, &p.Status, &p.Reasoning, &p.Score, &violationsJSON, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		var violationsJSON, repairsJSON, breakdownJSON []byte
+		if err := rows.Scan(&p.ID, &p.YearMonth, &p.Status, &p.Reasoning, &p.Score, &violationsJSON, &repairsJSON, &p.JobID, &p.PatternIndex, &p.Strategy, &p.DiversityScore, &breakdownJSON, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if violationsJSON != nil {
+			_ = json.Unmarshal(violationsJSON, &p.ConstraintViolations)
+		}
+		if p.ConstraintViolations == nil {
+			p.ConstraintViolations = []model.ConstraintViolation{}
+		}
+		decodeRepairs(repairsJSON, &p)
+		decodeBreakdown(breakdownJSON, &p)
+		patterns = append(patterns, p)
+	}
+	return patterns, rows.Err()
+}
+
+// ListByJobID returns the patterns a job has saved, in pattern order
+func (r *ShiftPatternRepository) ListByJobID(ctx context.Context, jobID string) ([]model.ShiftPattern, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, year_month, status, reasoning, score, constraint_violations, repairs, job_id, pattern_index, strategy, diversity_score, score_breakdown, created_at, updated_at
+		 FROM shift_patterns WHERE job_id = $1 ORDER BY pattern_index ASC`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var patterns []model.ShiftPattern
+	for rows.Next() {
+		var p model.ShiftPattern
+		var violationsJSON, repairsJSON, breakdownJSON []byte
+		if err := rows.Scan(&p.ID, &p.YearMonth, &p.Status, &p.Reasoning, &p.Score, &violationsJSON, &repairsJSON, &p.JobID, &p.PatternIndex, &p.Strategy, &p.DiversityScore, &breakdownJSON, &p.CreatedAt, &p.UpdatedAt); err != nil {
 			return nil, err
 		}
 		if violationsJSON != nil {
@@ -40,6 +81,8 @@ func (r *ShiftPatternRepository) ListByYearMonth(ctx context.Context, yearMonth
 		if p.ConstraintViolations == nil {
 			p.ConstraintViolations = []model.ConstraintViolation{}
 		}
+		decodeRepairs(repairsJSON, &p)
+		decodeBreakdown(breakdownJSON, &p)
 		patterns = append(patterns, p)
 	}
 	return patterns, rows.Err()
@@ -47,11 +90,11 @@ func (r *ShiftPatternRepository) ListByYearMonth(ctx context.Context, yearMonth
 
 func (r *ShiftPatternRepository) GetByID(ctx context.Context, id string) (*model.ShiftPattern, error) {
 	var p model.ShiftPattern
-	var violationsJSON []byte
+	var violationsJSON, repairsJSON, breakdownJSON []byte
 	err := r.db.QueryRow(ctx,
-		`SELECT id, year_month, status, reasoning, score, constraint_violations, created_at, updated_at
+		`SELECT id, year_month, status, reasoning, score, constraint_violations, repairs, job_id, pattern_index, strategy, diversity_score, score_breakdown, created_at, updated_at
 		 FROM shift_patterns WHERE id = $1`, id,
-	).Scan(&p.ID, &p.YearMonth, &p.Status, &p.Reasoning, &p.Score, &violationsJSON, &p.CreatedAt, &p.UpdatedAt)
+	).Scan(&p.ID, &p.YearMonth, &p.Status, &p.Reasoning, &p.Score, &violationsJSON, &repairsJSON, &p.JobID, &p.PatternIndex, &p.Strategy, &p.DiversityScore, &breakdownJSON, &p.CreatedAt, &p.UpdatedAt)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -64,20 +107,27 @@ func (r *ShiftPatternRepository) GetByID(ctx context.Context, id string) (*model
 	if p.ConstraintViolations == nil {
 		p.ConstraintViolations = []model.ConstraintViolation{}
 	}
+	decodeRepairs(repairsJSON, &p)
+	decodeBreakdown(breakdownJSON, &p)
 	return &p, nil
 }
 
-func (r *ShiftPatternRepository) Create(ctx context.Context, yearMonth string, reasoning string, score float64, violations []model.ConstraintViolation) (*model.ShiftPattern, error) {
+func (r *ShiftPatternRepository) Create(ctx context.Context, yearMonth string, jobID string, patternIndex int, strategy *string, reasoning string, score float64, breakdown *model.ScoreBreakdown, violations []model.ConstraintViolation, repairs []model.RepairAction) (*model.ShiftPattern, error) {
 	violationsJSON, _ := json.Marshal(violations)
+	breakdownBytes := encodeBreakdown(breakdown)
+	if repairs == nil {
+		repairs = []model.RepairAction{}
+	}
+	repairsJSON, _ := json.Marshal(repairs)
 
 	var p model.ShiftPattern
-	var violBytes []byte
+	var violBytes, repairBytes, breakdownJSON []byte
 	err := r.db.QueryRow(ctx,
-		`INSERT INTO shift_patterns (year_month, reasoning, score, constraint_violations)
-		 VALUES ($1, $2, $3, $4)
-		 RETURNING id, year_month, status, reasoning, score, constraint_violations, created_at, updated_at`,
-		yearMonth, reasoning, score, violationsJSON,
-	).Scan(&p.ID, &p.YearMonth, &p.Status, &p.Reasoning, &p.Score, &violBytes, &p.CreatedAt, &p.UpdatedAt)
+		`INSERT INTO shift_patterns (year_month, job_id, pattern_index, strategy, reasoning, score, score_breakdown, constraint_violations, repairs)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING id, year_month, status, reasoning, score, constraint_violations, repairs, job_id, pattern_index, strategy, diversity_score, score_breakdown, created_at, updated_at`,
+		yearMonth, jobID, patternIndex, strategy, reasoning, score, breakdownBytes, violationsJSON, repairsJSON,
+	).Scan(&p.ID, &p.YearMonth, &p.Status, &p.Reasoning, &p.Score, &violBytes, &repairBytes, &p.JobID, &p.PatternIndex, &p.Strategy, &p.DiversityScore, &breakdownJSON, &p.CreatedAt, &p.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -87,31 +137,263 @@ func (r *ShiftPatternRepository) Create(ctx context.Context, yearMonth string, r
 	if p.ConstraintViolations == nil {
 		p.ConstraintViolations = []model.ConstraintViolation{}
 	}
+	decodeRepairs(repairBytes, &p)
+	decodeBreakdown(breakdownJSON, &p)
 	return &p, nil
 }
 
-func (r *ShiftPatternRepository) UpdateStatus(ctx context.Context, id string, status string) error {
+// decodeRepairs fills p.Repairs from the repairs column, never leaving it nil
+func decodeRepairs(raw []byte, p *model.ShiftPattern) {
+	if raw != nil {
+		_ = json.Unmarshal(raw, &p.Repairs)
+	}
+	if p.Repairs == nil {
+		p.Repairs = []model.RepairAction{}
+	}
+}
+
+// encodeBreakdown returns the score_breakdown column, NULL when unset
+func encodeBreakdown(breakdown *model.ScoreBreakdown) []byte {
+	if breakdown == nil {
+		return nil
+	}
+	raw, _ := json.Marshal(breakdown)
+	return raw
+}
+
+// decodeBreakdown fills p.ScoreBreakdown from the score_breakdown column
+func decodeBreakdown(raw []byte, p *model.ShiftPattern) {
+	if raw == nil {
+		return
+	}
+	var breakdown model.ScoreBreakdown
+	if err := json.Unmarshal(raw, &breakdown); err == nil {
+		p.ScoreBreakdown = &breakdown
+	}
+}
+
+// ReplaceEntries swaps the entries removeIDs of a pattern for entries and
+// records the re-validated score and violations, all in one transaction.
+// Entries edited by hand since removeIDs was read are kept, and so is the
+// staff's day in entries when it collides with one. It returns how many
+// entries were kept that way; the score no longer matches when it isn't 0.
+// It returns ErrPatternLocked when the pattern is no longer draft or
+// selected.
+func (r *ShiftPatternRepository) ReplaceEntries(ctx context.Context, patternID string, removeIDs []string, entries []model.LLMShiftEntry, score float64, breakdown *model.ScoreBreakdown, violations []model.ConstraintViolation) (int, error) {
+	violationsJSON, _ := json.Marshal(violations)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Updated first so the row stays locked against status changes until commit
+	tag, err := tx.Exec(ctx,
+		`UPDATE shift_patterns SET score = $1, score_breakdown = $2, constraint_violations = $3, updated_at = NOW()
+		 WHERE id = $4 AND status IN ('draft', 'selected')`,
+		score, encodeBreakdown(breakdown), violationsJSON, patternID)
+	if err != nil {
+		return 0, err
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, ErrPatternLocked
+	}
+	tag, err = tx.Exec(ctx,
+		`DELETE FROM shift_entries WHERE pattern_id = $1 AND id = ANY($2) AND is_manual_edit = false`, patternID, removeIDs)
+	if err != nil {
+		return 0, err
+	}
+	kept := len(removeIDs) - int(tag.RowsAffected())
+	for _, entry := range entries {
+		tag, err := tx.Exec(ctx,
+			`INSERT INTO shift_entries (pattern_id, staff_id, date, start_time, end_time, break_minutes, is_auto_repaired)
+			 SELECT $1::uuid, $2::uuid, $3::date, $4::time, $5::time, $6::int, $7::boolean
+			 WHERE NOT EXISTS (
+			     SELECT 1 FROM shift_entries
+			     WHERE pattern_id = $1::uuid AND staff_id = $2::uuid AND date = $3::date AND is_manual_edit = true
+			 )`,
+			patternID, entry.StaffID, entry.Date, entry.StartTime, entry.EndTime, entry.BreakMinutes, entry.AutoRepaired)
+		if err != nil {
+			return 0, err
+		}
+		if tag.RowsAffected() == 0 {
+			kept++
+		}
+	}
+	return kept, tx.Commit(ctx)
+}
+
+// UpdateValidation records the score and violations of a pattern
+// re-validated after its entries changed
+func (r *ShiftPatternRepository) UpdateValidation(ctx context.Context, id string, score float64, breakdown *model.ScoreBreakdown, violations []model.ConstraintViolation) error {
+	violationsJSON, _ := json.Marshal(violations)
 	_, err := r.db.Exec(ctx,
-		`UPDATE shift_patterns SET status = $1, updated_at = NOW() WHERE id = $2`, status, id)
+		`UPDATE shift_patterns SET score = $1, score_breakdown = $2, constraint_violations = $3, updated_at = NOW() WHERE id = $4`,
+		score, encodeBreakdown(breakdown), violationsJSON, id)
 	return err
 }
 
-// ResetOtherPatterns sets all other patterns for the same year_month to 'draft'
-func (r *ShiftPatternRepository) ResetOtherPatterns(ctx context.Context, id string, yearMonth string) error {
+// UpdateDiversityScore records how much a pattern differs from the other
+// patterns of its job
+func (r *ShiftPatternRepository) UpdateDiversityScore(ctx context.Context, id string, score float64) error {
 	_, err := r.db.Exec(ctx,
-		`UPDATE shift_patterns SET status = 'draft', updated_at = NOW() WHERE year_month = $1 AND id != $2 AND status != 'finalized'`,
-		yearMonth, id)
+		`UPDATE shift_patterns SET diversity_score = $1 WHERE id = $2`, score, id)
 	return err
 }
 
+// Transition moves a pattern from one status to another and records the
+// change in its history. It returns false when the pattern is no longer in
+// status from.
+func (r *ShiftPatternRepository) Transition(ctx context.Context, id, from, to, changedBy, reason string) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	ok, err := transition(ctx, tx, id, from, to, changedBy, reason)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// Select moves a pattern to selected and the other selected pattern of its
+// month back to draft, recording both changes. It returns false when the
+// pattern is no longer in status from.
+func (r *ShiftPatternRepository) Select(ctx context.Context, id, from, yearMonth, changedBy, reason string) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		`WITH reset AS (
+		   UPDATE shift_patterns SET status = 'draft', updated_at = NOW()
+		   WHERE year_month = $1 AND id != $2 AND status = 'selected'
+		   RETURNING id
+		 )
+		 INSERT INTO shift_pattern_status_history (pattern_id, from_status, to_status, changed_by, reason)
+		 SELECT id, 'selected', 'draft', $3, '別のパターンが選択されたため' FROM reset`,
+		yearMonth, id, changedBy); err != nil {
+		return false, err
+	}
+	ok, err := transition(ctx, tx, id, from, model.PatternStatusSelected, changedBy, reason)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// Finalize moves a selected pattern to finalized and records who finalized
+// it, in one transaction. It returns nil when the pattern is no longer
+// selected.
+func (r *ShiftPatternRepository) Finalize(ctx context.Context, id string, finalizedBy, overrideReason string, overridden []model.Violation) (*model.PatternFinalization, error) {
+	if overridden == nil {
+		overridden = []model.Violation{}
+	}
+	overriddenJSON, _ := json.Marshal(overridden)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	ok, err := transition(ctx, tx, id, model.PatternStatusSelected, model.PatternStatusFinalized, finalizedBy, overrideReason)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var f model.PatternFinalization
+	var violationsJSON []byte
+	err = tx.QueryRow(ctx,
+		`INSERT INTO shift_pattern_finalizations (pattern_id, finalized_by, override_reason, overridden_violations)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING id, pattern_id, finalized_by, override_reason, overridden_violations, finalized_at`,
+		id, finalizedBy, overrideReason, overriddenJSON,
+	).Scan(&f.ID, &f.PatternID, &f.FinalizedBy, &f.OverrideReason, &violationsJSON, &f.FinalizedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	_ = json.Unmarshal(violationsJSON, &f.OverriddenViolations)
+	return &f, nil
+}
+
+// transition changes the status of a pattern still in status from and adds
+// the change to its history
+func transition(ctx context.Context, tx pgx.Tx, id, from, to, changedBy, reason string) (bool, error) {
+	tag, err := tx.Exec(ctx,
+		`UPDATE shift_patterns SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3`, to, id, from)
+	if err != nil {
+		// Another pattern of the month was locked concurrently
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_shift_patterns_one_locked" {
+			return false, ErrMonthLocked
+		}
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO shift_pattern_status_history (pattern_id, from_status, to_status, changed_by, reason)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		id, from, to, changedBy, reason)
+	return err == nil, err
+}
+
+// ListStatusHistory returns the status changes of a pattern, oldest first
+func (r *ShiftPatternRepository) ListStatusHistory(ctx context.Context, patternID string) ([]model.PatternStatusChange, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, pattern_id, from_status, to_status, changed_by, reason, changed_at
+		 FROM shift_pattern_status_history WHERE pattern_id = $1
+		 ORDER BY changed_at ASC, id ASC`, patternID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []model.PatternStatusChange
+	for rows.Next() {
+		var c model.PatternStatusChange
+		if err := rows.Scan(&c.ID, &c.PatternID, &c.FromStatus, &c.ToStatus, &c.ChangedBy, &c.Reason, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// LockedID returns the ID of the finalized or published pattern of a month,
+// or ""
+func (r *ShiftPatternRepository) LockedID(ctx context.Context, yearMonth string) (string, error) {
+	var id string
+	err := r.db.QueryRow(ctx,
+		`SELECT id FROM shift_patterns WHERE year_month = $1 AND status IN ('finalized', 'published')`, yearMonth,
+	).Scan(&id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return id, nil
+}
+
 func (r *ShiftPatternRepository) GetLatestStatusByYearMonth(ctx context.Context, yearMonth string) (string, error) {
 	var status string
 	err := r.db.QueryRow(ctx,
-		`SELECT status FROM shift_patterns WHERE year_month = $1 ORDER BY
+		`SELECT status FROM shift_patterns WHERE year_month = $1 AND status != 'archived' ORDER BY
 		 CASE status
-		   WHEN 'finalized' THEN 1
-		   WHEN 'selected' THEN 2
-		   WHEN 'draft' THEN 3
+		   WHEN 'published' THEN 1
+		   WHEN 'finalized' THEN 2
+		   WHEN 'selected' THEN 3
+		   WHEN 'draft' THEN 4
 		 END ASC
 		 LIMIT 1`, yearMonth,
 	).Scan(&status)
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"shift-app/internal/model"
+	"shift-app/internal/repository"
+)
+
+type BusinessCalendarService struct {
+	repo *repository.BusinessCalendarRepository
+}
+
+func NewBusinessCalendarService(repo *repository.BusinessCalendarRepository) *BusinessCalendarService {
+	return &BusinessCalendarService{repo: repo}
+}
+
+func (s *BusinessCalendarService) ListHours(ctx context.Context) ([]model.BusinessHours, error) {
+	hours, err := s.repo.ListHours(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if hours == nil {
+		hours = []model.BusinessHours{}
+	}
+	return hours, nil
+}
+
+func (s *BusinessCalendarService) UpdateHours(ctx context.Context, req model.UpdateBusinessHoursRequest) ([]model.BusinessHours, error) {
+	if len(req.Hours) == 0 {
+		return nil, errors.New("hours は必須です")
+	}
+	seen := make(map[int]bool)
+	for _, h := range req.Hours {
+		if h.Weekday < 0 || h.Weekday > 6 {
+			return nil, errors.New("weekday は0(日)〜6(土)で指定してください")
+		}
+		if seen[h.Weekday] {
+			return nil, fmt.Errorf("weekday %d が重複しています", h.Weekday)
+		}
+		seen[h.Weekday] = true
+		if h.IsClosed {
+			continue
+		}
+		if err := validateOpenHours(h.OpenTime, h.CloseTime); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.ReplaceHours(ctx, req.Hours); err != nil {
+		return nil, err
+	}
+	return s.ListHours(ctx)
+}
+
+func (s *BusinessCalendarService) ListSpecialDays(ctx context.Context, yearMonth string) ([]model.BusinessSpecialDay, error) {
+	if yearMonth == "" {
+		return nil, errors.New("year_month は必須です")
+	}
+	days, err := s.repo.ListSpecialDays(ctx, yearMonth)
+	if err != nil {
+		return nil, err
+	}
+	if days == nil {
+		days = []model.BusinessSpecialDay{}
+	}
+	return days, nil
+}
+
+func (s *BusinessCalendarService) UpsertSpecialDay(ctx context.Context, date string, req model.UpsertBusinessSpecialDayRequest) (*model.BusinessSpecialDay, error) {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, errors.New("date は YYYY-MM-DD 形式で指定してください")
+	}
+	if !req.IsClosed {
+		if err := validateOpenHours(req.OpenTime, req.CloseTime); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.UpsertSpecialDay(ctx, date, req)
+}
+
+func (s *BusinessCalendarService) DeleteSpecialDay(ctx context.Context, date string) error {
+	return s.repo.DeleteSpecialDay(ctx, date)
+}
+
+// GetMonth returns the resolved business hours for every day of yearMonth
+func (s *BusinessCalendarService) GetMonth(ctx context.Context, yearMonth string) ([]model.BusinessDay, error) {
+	if _, err := time.Parse("2006-01", yearMonth); err != nil {
+		return nil, errors.New("year_month は YYYY-MM 形式で指定してください")
+	}
+	cal, err := s.repo.GetCalendar(ctx, yearMonth)
+	if err != nil {
+		return nil, err
+	}
+	return cal.Month(yearMonth), nil
+}
+
+func validateOpenHours(openTime, closeTime *string) error {
+	if openTime == nil || closeTime == nil {
+		return errors.New("営業日は open_time と close_time を指定してください")
+	}
+	// close_time <= open_time means closing after midnight (e.g. 18:00-02:00),
+	// and equal times mean open around the clock
+	_, err1 := time.Parse("15:04", *openTime)
+	_, err2 := time.Parse("15:04", *closeTime)
+	if err1 != nil || err2 != nil {
+		return errors.New("open_time, close_time は HH:MM 形式で指定してください")
+	}
+	return nil
+}
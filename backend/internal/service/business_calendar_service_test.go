@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"shift-app/internal/model"
+)
+
+func TestBusinessCalendarService_UpdateHours_Validation(t *testing.T) {
+	svc := NewBusinessCalendarService(nil)
+	ctx := context.Background()
+	open, closeAt, bad := "09:00", "22:00", "9時"
+
+	tests := []struct {
+		name    string
+		req     model.UpdateBusinessHoursRequest
+		wantErr string
+	}{
+		{
+			name:    "empty hours",
+			req:     model.UpdateBusinessHoursRequest{},
+			wantErr: "hours は必須です",
+		},
+		{
+			name:    "weekday out of range",
+			req:     model.UpdateBusinessHoursRequest{Hours: []model.BusinessHours{{Weekday: 7, OpenTime: &open, CloseTime: &closeAt}}},
+			wantErr: "weekday は0(日)〜6(土)で指定してください",
+		},
+		{
+			name: "duplicate weekday",
+			req: model.UpdateBusinessHoursRequest{Hours: []model.BusinessHours{
+				{Weekday: 1, OpenTime: &open, CloseTime: &closeAt},
+				{Weekday: 1, IsClosed: true},
+			}},
+			wantErr: "weekday 1 が重複しています",
+		},
+		{
+			name:    "missing times on open day",
+			req:     model.UpdateBusinessHoursRequest{Hours: []model.BusinessHours{{Weekday: 1}}},
+			wantErr: "営業日は open_time と close_time を指定してください",
+		},
+		{
+			name:    "invalid time format",
+			req:     model.UpdateBusinessHoursRequest{Hours: []model.BusinessHours{{Weekday: 1, OpenTime: &bad, CloseTime: &closeAt}}},
+			wantErr: "open_time, close_time は HH:MM 形式で指定してください",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.UpdateHours(ctx, tt.req)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if err.Error() != tt.wantErr {
+				t.Errorf("error = %q, want %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBusinessCalendarService_UpsertSpecialDay_InvalidDate(t *testing.T) {
+	svc := NewBusinessCalendarService(nil)
+
+	_, err := svc.UpsertSpecialDay(context.Background(), "2026/03/20", model.UpsertBusinessSpecialDayRequest{IsClosed: true})
+	if err == nil || err.Error() != "date は YYYY-MM-DD 形式で指定してください" {
+		t.Errorf("error = %v, want date format error", err)
+	}
+}
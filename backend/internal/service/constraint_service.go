@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 
 	"shift-app/internal/model"
@@ -48,20 +49,69 @@ func (s *ConstraintService) Create(ctx context.Context, req model.CreateConstrai
 	if req.Category == "" {
 		return nil, errors.New("category は必須です")
 	}
-	validCategories := map[string]bool{
-		"min_staff": true, "max_staff": true, "max_consecutive_days": true,
-		"monthly_hours": true, "fixed_day_off": true, "staff_compatibility": true, "rest_hours": true,
-	}
-	if !validCategories[req.Category] {
+	if !isValidCategory(req.Category) {
 		return nil, errors.New("無効な category です")
 	}
+	if len(req.Config) == 0 {
+		req.Config = json.RawMessage("{}")
+	}
+	if _, details := model.DecodeConstraintConfig(req.Category, req.Config); len(details) > 0 {
+		return nil, &ValidationError{Details: details}
+	}
 	return s.repo.Create(ctx, req)
 }
 
 func (s *ConstraintService) Update(ctx context.Context, id string, req model.UpdateConstraintRequest) (*model.Constraint, error) {
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, nil
+	}
+
+	// Validate the constraint as it will look after the update
+	var details []model.ErrorDetail
+	if req.Name != nil && (*req.Name == "" || len(*req.Name) > 200) {
+		details = append(details, model.ErrorDetail{Field: "name", Message: "名前は1〜200文字で入力してください"})
+	}
+	if req.Type != nil && *req.Type != "hard" && *req.Type != "soft" {
+		details = append(details, model.ErrorDetail{Field: "type", Message: "type は hard, soft のいずれかで指定してください"})
+	}
+	category := current.Category
+	if req.Category != nil {
+		category = *req.Category
+	}
+	config := current.Config
+	if req.Config != nil {
+		config = *req.Config
+	}
+	if !isValidCategory(category) {
+		details = append(details, model.ErrorDetail{Field: "category", Message: "無効な category です"})
+	} else if _, configDetails := model.DecodeConstraintConfig(category, config); len(configDetails) > 0 {
+		details = append(details, configDetails...)
+	}
+	if len(details) > 0 {
+		return nil, &ValidationError{Details: details}
+	}
+
 	return s.repo.Update(ctx, id, req)
 }
 
+// ConfigSchemas returns the JSON Schema of the config for each category
+func (s *ConstraintService) ConfigSchemas() map[string]interface{} {
+	return model.ConstraintConfigSchemas()
+}
+
+func isValidCategory(category string) bool {
+	for _, c := range model.ConstraintCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *ConstraintService) Delete(ctx context.Context, id string) error {
 	return s.repo.Delete(ctx, id)
 }
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 
 	"shift-app/internal/model"
@@ -60,3 +62,24 @@ func TestConstraintService_Create_Validation(t *testing.T) {
 		})
 	}
 }
+
+func TestConstraintService_Create_ConfigValidation(t *testing.T) {
+	svc := NewConstraintService(nil)
+	ctx := context.Background()
+
+	req := model.CreateConstraintRequest{
+		Name:     "連勤制限",
+		Type:     "hard",
+		Category: "max_consecutive_days",
+		Config:   json.RawMessage(`{"maxdays": 5}`),
+	}
+
+	_, err := svc.Create(ctx, req)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if len(verr.Details) == 0 || verr.Details[0].Field != "config.maxdays" {
+		t.Errorf("details = %+v, want first field config.maxdays", verr.Details)
+	}
+}
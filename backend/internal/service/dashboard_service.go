@@ -65,21 +65,20 @@ func (s *DashboardService) GetSummary(ctx context.Context, yearMonth string) (*m
 
 	shiftStatus := s.determineShiftStatus(ctx, yearMonth, requestCount)
 
-	// Get daily staff counts from the best pattern (finalized > selected > draft)
+	// Get daily staff counts from the best pattern (published/finalized > selected > draft)
 	var dailyCounts []model.DailyStaffCount
 	patterns, _ := s.patternRepo.ListByYearMonth(ctx, yearMonth)
-	if len(patterns) > 0 {
-		// Find the best pattern
-		bestPattern := patterns[0]
-		for _, p := range patterns {
-			if p.Status == "finalized" {
-				bestPattern = p
-				break
-			}
-			if p.Status == "selected" {
-				bestPattern = p
-			}
+	var bestPattern *model.ShiftPattern
+	for i, p := range patterns {
+		if p.Status == model.PatternStatusPublished || p.Status == model.PatternStatusFinalized {
+			bestPattern = &patterns[i]
+			break
 		}
+		if p.Status == model.PatternStatusSelected || (bestPattern == nil && p.Status == model.PatternStatusDraft) {
+			bestPattern = &patterns[i]
+		}
+	}
+	if bestPattern != nil {
 		dailyCounts, _ = s.entryRepo.DailyStaffCounts(ctx, bestPattern.ID)
 	}
 	if dailyCounts == nil {
@@ -108,11 +107,13 @@ func (s *DashboardService) determineShiftStatus(ctx context.Context, yearMonth s
 	// Check pattern status
 	patternStatus, _ := s.patternRepo.GetLatestStatusByYearMonth(ctx, yearMonth)
 	switch patternStatus {
-	case "finalized":
+	case model.PatternStatusPublished:
+		return "published"
+	case model.PatternStatusFinalized:
 		return "finalized"
-	case "selected":
+	case model.PatternStatusSelected:
 		return "selected"
-	case "draft":
+	case model.PatternStatusDraft:
 		return "generated"
 	}
 
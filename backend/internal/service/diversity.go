@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+
+	"shift-app/internal/model"
+)
+
+// scoreDiversity records on each pattern of a job how much it differs from
+// the job's other patterns. Failing to score is only logged; the patterns
+// are still usable.
+func (s *ShiftService) scoreDiversity(ctx context.Context, jobID string) {
+	patterns, err := s.patternRepo.ListByJobID(ctx, jobID)
+	if err != nil {
+		log.Printf("Failed to score diversity of job %s: %v", jobID, err)
+		return
+	}
+	if len(patterns) < 2 {
+		return
+	}
+
+	sets := make([]map[string]bool, len(patterns))
+	for i, p := range patterns {
+		entries, err := s.entryRepo.ListByPatternID(ctx, p.ID)
+		if err != nil {
+			log.Printf("Failed to score diversity of job %s: %v", jobID, err)
+			return
+		}
+		sets[i] = assignmentSet(entries)
+	}
+	for i, score := range diversityScores(sets) {
+		if err := s.patternRepo.UpdateDiversityScore(ctx, patterns[i].ID, score); err != nil {
+			log.Printf("Failed to save diversity score of pattern %s: %v", patterns[i].ID, err)
+		}
+	}
+}
+
+// assignmentSet is the set of (staff, date, start, end) a pattern assigns
+func assignmentSet(entries []model.ShiftEntry) map[string]bool {
+	set := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		set[e.StaffID+"|"+e.Date+"|"+e.StartTime+"|"+e.EndTime] = true
+	}
+	return set
+}
+
+// diversityScores returns for each assignment set its mean Jaccard distance
+// to the other sets, from 0 (same shifts as every other pattern) to 100
+// (no shift in common with any)
+func diversityScores(sets []map[string]bool) []float64 {
+	scores := make([]float64, len(sets))
+	if len(sets) < 2 {
+		return scores
+	}
+	for i := range sets {
+		var total float64
+		for j := range sets {
+			if i != j {
+				total += 1 - jaccard(sets[i], sets[j])
+			}
+		}
+		scores[i] = math.Round(total/float64(len(sets)-1)*10000) / 100
+	}
+	return scores
+}
+
+// jaccard is |a ∩ b| / |a ∪ b|; two empty sets are identical
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	common := 0
+	for k := range a {
+		if b[k] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
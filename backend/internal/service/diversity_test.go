@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	"shift-app/internal/model"
+)
+
+func TestDiversityScores(t *testing.T) {
+	a := assignmentSet([]model.ShiftEntry{
+		{StaffID: "s1", Date: "2025-01-01", StartTime: "09:00", EndTime: "17:00"},
+		{StaffID: "s2", Date: "2025-01-01", StartTime: "13:00", EndTime: "21:00"},
+	})
+	b := assignmentSet([]model.ShiftEntry{
+		{StaffID: "s1", Date: "2025-01-01", StartTime: "09:00", EndTime: "17:00"},
+		{StaffID: "s2", Date: "2025-01-02", StartTime: "13:00", EndTime: "21:00"},
+	})
+	c := assignmentSet([]model.ShiftEntry{
+		{StaffID: "s3", Date: "2025-01-03", StartTime: "10:00", EndTime: "18:00"},
+	})
+
+	tests := []struct {
+		name string
+		sets []map[string]bool
+		want []float64
+	}{
+		{"single pattern", []map[string]bool{a}, []float64{0}},
+		{"identical patterns", []map[string]bool{a, a}, []float64{0, 0}},
+		{"disjoint patterns", []map[string]bool{a, c}, []float64{100, 100}},
+		// a and b share 1 of 3 distinct shifts: distance 2/3
+		{"partial overlap", []map[string]bool{a, b}, []float64{66.67, 66.67}},
+		// a: (2/3 + 1) / 2, c: (1 + 1) / 2
+		{"three patterns", []map[string]bool{a, b, c}, []float64{83.33, 83.33, 100}},
+		{"empty patterns", []map[string]bool{{}, {}}, []float64{0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diversityScores(tt.sets)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d scores, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("score[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
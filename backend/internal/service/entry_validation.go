@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"shift-app/internal/model"
+)
+
+// ValidatePattern re-validates the stored entries of a pattern, manual edits
+// included, and records the new score and violations on it unless the
+// pattern is locked. It returns nil when the pattern doesn't exist.
+func (s *ShiftService) ValidatePattern(ctx context.Context, id string) (*model.ShiftPattern, *model.ValidationResult, error) {
+	pattern, err := s.patternRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pattern == nil {
+		return nil, nil, nil
+	}
+	result, err := s.revalidatePattern(ctx, pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	pattern, err = s.patternRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pattern, result, nil
+}
+
+// revalidatePattern validates the stored entries of a pattern with the
+// weights it was generated with and records the score and violations. A
+// locked pattern keeps the score it was finalized with; only the fresh result
+// is returned.
+func (s *ShiftService) revalidatePattern(ctx context.Context, pattern *model.ShiftPattern) (*model.ValidationResult, error) {
+	entries, err := s.entryRepo.ListByPatternID(ctx, pattern.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var weights *model.ObjectiveWeights
+	if pattern.JobID != nil {
+		job, err := s.jobRepo.GetByID(ctx, *pattern.JobID)
+		if err != nil {
+			return nil, err
+		}
+		if job != nil {
+			weights = job.Weights
+		}
+	}
+
+	result, err := s.validator.ValidateEntries(ctx, pattern.YearMonth, entries, weights)
+	if err != nil {
+		return nil, err
+	}
+	if !model.PatternEditable(pattern.Status) {
+		return result, nil
+	}
+	if err := s.patternRepo.UpdateValidation(ctx, pattern.ID, result.Score, result.Breakdown, constraintViolations(result.Violations)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// revalidateEntry re-validates a pattern after one of its entries was
+// created, updated or deleted and returns the findings that concern the
+// changed staff and date
+func (s *ShiftService) revalidateEntry(ctx context.Context, patternID, staffID, date string) (*model.EntryValidation, error) {
+	pattern, err := s.patternRepo.GetByID(ctx, patternID)
+	if err != nil {
+		return nil, err
+	}
+	if pattern == nil {
+		return nil, errors.New("パターンが見つかりません")
+	}
+	result, err := s.revalidatePattern(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return entryFindings(result, staffID, date), nil
+}
+
+// entryFindings picks the violations and warnings of a validation result
+// that concern a changed entry: those of its staff, including violations
+// between staff recorded against the other staff, and those of its date that
+// aren't about one staff (such as the staff count of the day). The entry is
+// valid when none of them is a hard violation.
+func entryFindings(result *model.ValidationResult, staffID, date string) *model.EntryValidation {
+	concerns := func(findingStaffID, findingDate string, related ...string) bool {
+		if findingStaffID != "" {
+			return findingStaffID == staffID || slices.Contains(related, staffID)
+		}
+		return findingDate == date
+	}
+
+	validation := &model.EntryValidation{
+		IsValid:        true,
+		Warnings:       []model.ValidationWarning{},
+		PatternScore:   result.Score,
+		ScoreBreakdown: result.Breakdown,
+	}
+	for _, v := range result.Violations {
+		if !concerns(v.StaffID, v.Date, v.RelatedStaffIDs...) {
+			continue
+		}
+		if v.Type == "hard" {
+			validation.IsValid = false
+		}
+		validation.Warnings = append(validation.Warnings, model.ValidationWarning{
+			Type:       v.Type,
+			Constraint: v.Constraint,
+			StaffID:    v.StaffID,
+			Date:       v.Date,
+			Message:    v.Message,
+		})
+	}
+	for _, w := range result.Warnings {
+		if !concerns(w.StaffID, w.Date) {
+			continue
+		}
+		validation.Warnings = append(validation.Warnings, model.ValidationWarning{
+			Type:       w.Type,
+			Constraint: w.Constraint,
+			StaffID:    w.StaffID,
+			Date:       w.Date,
+			Message:    w.Message,
+		})
+	}
+	return validation
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"shift-app/internal/model"
+)
+
+func TestEntryFindings(t *testing.T) {
+	result := &model.ValidationResult{
+		Violations: []model.Violation{
+			{Type: "hard", Constraint: "出勤不可日チェック", StaffID: "s1", Date: "2025-02-10", Message: "unavailable"},
+			{Type: "soft", Constraint: "最低人数", Date: "2025-02-10", Message: "min staff"},
+			{Type: "soft", Constraint: "最低人数", Date: "2025-02-11", Message: "other day"},
+			{Type: "hard", Constraint: "休息時間", StaffID: "s2", Date: "2025-02-10", Message: "other staff"},
+			{Type: "hard", Constraint: "スタッフ相性", StaffID: "s2", RelatedStaffIDs: []string{"s4"}, Date: "2025-02-12", Message: "pair"},
+		},
+		Warnings: []model.Warning{
+			{Type: "soft_constraint", Constraint: "月間労働時間", StaffID: "s1", Message: "monthly hours"},
+			{Type: "config_error", Constraint: "壊れた制約", Message: "config"},
+		},
+		Score:     62.5,
+		Breakdown: &model.ScoreBreakdown{Total: 62.5},
+	}
+
+	tests := []struct {
+		name      string
+		staffID   string
+		date      string
+		wantValid bool
+		want      []string
+	}{
+		{"staff and day findings", "s1", "2025-02-10", false, []string{"unavailable", "min staff", "monthly hours"}},
+		{"other day of the staff", "s1", "2025-02-11", false, []string{"unavailable", "other day", "monthly hours"}},
+		{"nothing about the staff", "s3", "2025-02-12", true, []string{}},
+		{"violation recorded against the other staff of a pair", "s4", "2025-02-12", false, []string{"pair"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := entryFindings(result, tt.staffID, tt.date)
+			if got.IsValid != tt.wantValid {
+				t.Errorf("IsValid = %v, want %v", got.IsValid, tt.wantValid)
+			}
+			messages := []string{}
+			for _, w := range got.Warnings {
+				messages = append(messages, w.Message)
+			}
+			if !reflect.DeepEqual(messages, tt.want) {
+				t.Errorf("warnings = %v, want %v", messages, tt.want)
+			}
+			if got.PatternScore != 62.5 || got.ScoreBreakdown != result.Breakdown {
+				t.Errorf("score = %v, breakdown = %+v, want the pattern's", got.PatternScore, got.ScoreBreakdown)
+			}
+		})
+	}
+}
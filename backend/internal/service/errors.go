@@ -0,0 +1,98 @@
+package service
+
+import (
+	"fmt"
+
+	"shift-app/internal/model"
+)
+
+// ValidationError carries field-level validation details so handlers can
+// return them as ErrorResponse.details.
+type ValidationError struct {
+	Details []model.ErrorDetail
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Details) == 0 {
+		return "入力内容が不正です"
+	}
+	return e.Details[0].Message
+}
+
+// fieldError is a ValidationError about a single request field
+func fieldError(field, message string) *ValidationError {
+	return &ValidationError{Details: []model.ErrorDetail{{Field: field, Message: message}}}
+}
+
+// HardViolationsError is returned when a pattern with hard violations is
+// finalized without an override reason
+type HardViolationsError struct {
+	Violations []model.Violation
+}
+
+func (e *HardViolationsError) Error() string {
+	return fmt.Sprintf("ハード制約違反が%d件あるため確定できません。確定するには override_reason を指定してください", len(e.Violations))
+}
+
+// StatusError is returned when the lifecycle status of a pattern or a
+// generation job doesn't allow what was asked. Handlers answer it with 409.
+type StatusError struct {
+	Code    string
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+// Codes of StatusError
+const (
+	CodeInvalidTransition = "INVALID_STATUS_TRANSITION"
+	CodePatternLocked     = "PATTERN_LOCKED"
+	CodeMonthFinalized    = "MONTH_ALREADY_FINALIZED"
+	CodeRegenerating      = "PATTERN_REGENERATING"
+	CodeInvalidJobStatus  = "INVALID_JOB_STATUS"
+)
+
+// transitionError refuses to move a pattern from one status to another
+func transitionError(from, to string) *StatusError {
+	return &StatusError{
+		Code:    CodeInvalidTransition,
+		Message: fmt.Sprintf("%sのパターンは%sにできません", model.PatternStatusLabel(from), model.PatternStatusLabel(to)),
+	}
+}
+
+// lockedError refuses to change the entries of a pattern that isn't editable
+func lockedError(status string) *StatusError {
+	message := fmt.Sprintf("%sのパターンは編集できません", model.PatternStatusLabel(status))
+	if status != model.PatternStatusArchived {
+		message += "。確定を取り消すか修正を開始してから編集してください"
+	}
+	return &StatusError{Code: CodePatternLocked, Message: message}
+}
+
+// monthFinalizedError refuses to select or finalize a pattern of a month that
+// already has a locked one
+func monthFinalizedError(yearMonth string) *StatusError {
+	return &StatusError{
+		Code:    CodeMonthFinalized,
+		Message: fmt.Sprintf("%sにはすでに確定済みのパターンがあります。確定を取り消すか修正を開始してから変更してください", yearMonth),
+	}
+}
+
+// regeneratingError refuses to select or finalize a pattern whose entries a
+// regeneration job is about to replace
+func regeneratingError() *StatusError {
+	return &StatusError{Code: CodeRegenerating, Message: "このパターンは再生成中です。再生成が終わるかキャンセルしてから変更してください"}
+}
+
+// jobStatusError refuses to cancel or resume a job in its current status
+func jobStatusError(message string) *StatusError {
+	return &StatusError{Code: CodeInvalidJobStatus, Message: message}
+}
+
+// statusChangedError is returned when a pattern changed status between
+// being read and being updated
+func statusChangedError() *StatusError {
+	return &StatusError{Code: CodeInvalidTransition, Message: "パターンの状態が他の操作で変更されました。最新の状態を確認してください"}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"shift-app/internal/model"
+)
+
+// WorkerOptions configures a GenerationWorkerPool
+type WorkerOptions struct {
+	// Concurrency is the number of jobs run at the same time
+	Concurrency int
+	// PollInterval is how often an idle worker checks the queue
+	PollInterval time.Duration
+	// StaleAfter is how long a processing job may go without a heartbeat
+	// before it is treated as orphaned by a dead worker
+	StaleAfter time.Duration
+	// ResumeStale requeues orphaned jobs; when false they are marked failed
+	ResumeStale bool
+}
+
+// GenerationWorkerPool runs queued generation jobs. Pools in several
+// processes (the API server and cmd/worker) can share the queue: jobs are
+// claimed with FOR UPDATE SKIP LOCKED, so each runs once.
+type GenerationWorkerPool struct {
+	svc      *ShiftService
+	opts     WorkerOptions
+	workerID string
+}
+
+func NewGenerationWorkerPool(svc *ShiftService, opts WorkerOptions) *GenerationWorkerPool {
+	host, _ := os.Hostname()
+	return &GenerationWorkerPool{
+		svc:      svc,
+		opts:     opts,
+		workerID: fmt.Sprintf("%s-%d", host, os.Getpid()),
+	}
+}
+
+// Run works the queue until ctx is cancelled. Jobs still running then are
+// handed back to the queue.
+func (p *GenerationWorkerPool) Run(ctx context.Context) {
+	log.Printf("Generation worker %s started (concurrency %d)", p.workerID, p.opts.Concurrency)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.recoverLoop(ctx)
+	}()
+	for i := 0; i < p.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.workLoop(ctx)
+		}()
+	}
+	wg.Wait()
+	log.Printf("Generation worker %s stopped", p.workerID)
+}
+
+func (p *GenerationWorkerPool) workLoop(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := p.svc.jobRepo.Claim(ctx, p.workerID)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to claim generation job: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-p.svc.wake:
+			case <-time.After(p.opts.PollInterval):
+			}
+			continue
+		}
+		p.svc.runJob(ctx, job, p.workerID, p.opts.StaleAfter/4)
+	}
+}
+
+// recoverLoop periodically recovers jobs orphaned by dead workers, including
+// those of a previous run of this process
+func (p *GenerationWorkerPool) recoverLoop(ctx context.Context) {
+	ticker := time.NewTicker(p.opts.StaleAfter)
+	defer ticker.Stop()
+	for {
+		if err := p.svc.RecoverStaleJobs(ctx, p.opts.StaleAfter, p.opts.ResumeStale); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to recover stale generation jobs: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runJob runs a claimed job. A heartbeat keeps the claim alive and cancels
+// the run when the job was cancelled from another process or lost its claim.
+// If ctx (the worker's lifetime) ends first, the job goes back to the queue.
+func (s *ShiftService) runJob(ctx context.Context, job *model.GenerationJob, workerID string, heartbeat time.Duration) {
+	if _, ok := s.generators[job.Generator]; !ok {
+		s.failJob(context.Background(), job.ID, fmt.Sprintf("generator %s は利用できません", job.Generator))
+		return
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.mu.Lock()
+	s.cancels[job.ID] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.cancels, job.ID)
+		s.mu.Unlock()
+	}()
+
+	go func() {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			status, err := s.jobRepo.Heartbeat(context.Background(), job.ID, workerID)
+			if err != nil {
+				log.Printf("Heartbeat failed for job %s: %v", job.ID, err)
+				continue
+			}
+			if status != model.JobStatusProcessing {
+				log.Printf("Job %s is %q, stopping", job.ID, status)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	if job.TargetPatternID != nil {
+		s.runRegeneration(runCtx, job)
+	} else {
+		s.runGeneration(runCtx, job)
+	}
+
+	if ctx.Err() != nil {
+		log.Printf("Worker shutting down, requeueing job %s", job.ID)
+		if err := s.jobRepo.Release(context.Background(), job.ID, workerID); err != nil {
+			log.Printf("Failed to requeue job %s: %v", job.ID, err)
+		}
+	}
+}
+
+// RecoverStaleJobs handles processing jobs whose worker stopped sending
+// heartbeats (crashed or restarted). With resume they go back to the queue
+// and continue from the first unsaved pattern; otherwise they are marked
+// failed so they stop blocking their month.
+func (s *ShiftService) RecoverStaleJobs(ctx context.Context, staleAfter time.Duration, resume bool) error {
+	threshold := time.Now().Add(-staleAfter)
+	if resume {
+		n, err := s.jobRepo.RequeueStale(ctx, threshold)
+		if n > 0 {
+			log.Printf("Requeued %d orphaned generation jobs", n)
+			s.notifyWorkers()
+		}
+		return err
+	}
+	n, err := s.jobRepo.FailStale(ctx, threshold, "ワーカーの停止により中断されました")
+	if n > 0 {
+		log.Printf("Marked %d orphaned generation jobs failed", n)
+	}
+	return err
+}
+
+// notifyWorkers wakes an idle worker of this process without waiting for
+// its next poll
+func (s *ShiftService) notifyWorkers() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
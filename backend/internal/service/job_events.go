@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"shift-app/internal/model"
+)
+
+// jobWatchPollInterval is how often WatchJob re-reads a job to catch changes
+// made by another process
+const jobWatchPollInterval = 2 * time.Second
+
+// JobEvents is an in-process pub/sub of generation job events. Subscribers
+// only see events published in this process.
+type JobEvents struct {
+	mu   sync.Mutex
+	subs map[string]map[chan model.JobEvent]struct{}
+}
+
+func NewJobEvents() *JobEvents {
+	return &JobEvents{subs: make(map[string]map[chan model.JobEvent]struct{})}
+}
+
+// Subscribe returns the events of a job and a func to stop receiving them
+func (b *JobEvents) Subscribe(jobID string) (<-chan model.JobEvent, func()) {
+	ch := make(chan model.JobEvent, 16)
+	b.mu.Lock()
+	if b.subs[jobID] == nil {
+		b.subs[jobID] = make(map[chan model.JobEvent]struct{})
+	}
+	b.subs[jobID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs[jobID], ch)
+		if len(b.subs[jobID]) == 0 {
+			delete(b.subs, jobID)
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Publish sends an event to the subscribers of its job. It never blocks:
+// a subscriber whose buffer is full misses the event.
+func (b *JobEvents) Publish(e model.JobEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[e.JobID] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// WatchJob streams the events of a job until it finishes or ctx ends. The
+// first event is the current state of the job. Jobs run by another process
+// (cmd/worker) don't publish here, so the job is also re-read periodically
+// and a changed state is sent as an event. It returns nil when the job
+// doesn't exist.
+func (s *ShiftService) WatchJob(ctx context.Context, jobID string) (<-chan model.JobEvent, error) {
+	// Subscribe before reading the job so no event falls in between
+	sub, unsubscribe := s.events.Subscribe(jobID)
+	job, err := s.jobRepo.GetByID(ctx, jobID)
+	if err != nil || job == nil {
+		unsubscribe()
+		return nil, err
+	}
+
+	out := make(chan model.JobEvent)
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+
+		send := func(e model.JobEvent) bool {
+			select {
+			case out <- e:
+				return !e.IsFinal()
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		last := jobEvent(job)
+		if !send(last) {
+			return
+		}
+		ticker := time.NewTicker(jobWatchPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-sub:
+				if e.Type == model.JobEventProgress || e.IsFinal() {
+					last = e
+				}
+				if !send(e) {
+					return
+				}
+			case <-ticker.C:
+				job, err := s.jobRepo.GetByID(ctx, jobID)
+				if err != nil || job == nil {
+					continue
+				}
+				current := jobEvent(job)
+				if sameState(last, current) {
+					continue
+				}
+				last = current
+				if !send(current) {
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// jobEvent describes the stored state of a job
+func jobEvent(job *model.GenerationJob) model.JobEvent {
+	e := model.JobEvent{
+		Type:          model.JobEventProgress,
+		JobID:         job.ID,
+		Status:        job.Status,
+		Progress:      job.Progress,
+		StatusMessage: job.StatusMessage,
+		ErrorMessage:  job.ErrorMessage,
+	}
+	switch job.Status {
+	case model.JobStatusCompleted:
+		e.Type = model.JobEventCompleted
+	case model.JobStatusFailed:
+		e.Type = model.JobEventFailed
+	case model.JobStatusCancelled:
+		e.Type = model.JobEventCancelled
+	}
+	return e
+}
+
+func sameState(a, b model.JobEvent) bool {
+	return a.Type == b.Type && a.Status == b.Status && a.Progress == b.Progress &&
+		derefString(a.StatusMessage) == derefString(b.StatusMessage)
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// publishProgress records the progress of a running job and publishes it
+func (s *ShiftService) publishProgress(ctx context.Context, jobID string, progress int, statusMsg string) {
+	_ = s.jobRepo.UpdateProgress(ctx, jobID, progress, statusMsg)
+	s.events.Publish(model.JobEvent{
+		Type:          model.JobEventProgress,
+		JobID:         jobID,
+		Status:        model.JobStatusProcessing,
+		Progress:      progress,
+		StatusMessage: &statusMsg,
+	})
+}
+
+// failJob marks a job failed and publishes it
+func (s *ShiftService) failJob(ctx context.Context, jobID string, errMsg string) {
+	_ = s.jobRepo.SetFailed(ctx, jobID, errMsg)
+	s.events.Publish(model.JobEvent{
+		Type:         model.JobEventFailed,
+		JobID:        jobID,
+		Status:       model.JobStatusFailed,
+		ErrorMessage: &errMsg,
+	})
+}
+
+// publishPattern publishes that a job saved a pattern
+func (s *ShiftService) publishPattern(jobID string, pattern *model.ShiftPattern, progress int) {
+	s.events.Publish(model.JobEvent{
+		Type:         model.JobEventPattern,
+		JobID:        jobID,
+		Status:       model.JobStatusProcessing,
+		Progress:     progress,
+		PatternID:    pattern.ID,
+		PatternIndex: pattern.PatternIndex,
+		Score:        pattern.Score,
+	})
+}
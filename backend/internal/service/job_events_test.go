@@ -0,0 +1,82 @@
+package service
+
+import (
+	"testing"
+
+	"shift-app/internal/model"
+)
+
+func TestJobEvents_PublishSubscribe(t *testing.T) {
+	b := NewJobEvents()
+	ch, unsubscribe := b.Subscribe("job-1")
+	other, unsubscribeOther := b.Subscribe("job-2")
+	defer unsubscribeOther()
+
+	b.Publish(model.JobEvent{Type: model.JobEventProgress, JobID: "job-1", Progress: 10})
+
+	select {
+	case e := <-ch:
+		if e.Progress != 10 {
+			t.Errorf("progress = %d, want 10", e.Progress)
+		}
+	default:
+		t.Fatal("subscriber of job-1 got no event")
+	}
+	select {
+	case e := <-other:
+		t.Errorf("subscriber of job-2 got %+v", e)
+	default:
+	}
+
+	unsubscribe()
+	b.Publish(model.JobEvent{Type: model.JobEventCompleted, JobID: "job-1"})
+	select {
+	case e := <-ch:
+		t.Errorf("unsubscribed channel got %+v", e)
+	default:
+	}
+	if _, ok := b.subs["job-1"]; ok {
+		t.Error("subscription of job-1 was not removed")
+	}
+}
+
+func TestJobEvents_PublishDoesNotBlock(t *testing.T) {
+	b := NewJobEvents()
+	_, unsubscribe := b.Subscribe("job-1")
+	defer unsubscribe()
+
+	// Nobody reads the channel; publishing past its buffer must not block
+	for i := 0; i < 100; i++ {
+		b.Publish(model.JobEvent{Type: model.JobEventProgress, JobID: "job-1", Progress: i})
+	}
+}
+
+func TestJobEvent(t *testing.T) {
+	msg := "パターン1/3 生成中"
+	tests := []struct {
+		status    string
+		wantType  string
+		wantFinal bool
+	}{
+		{model.JobStatusPending, model.JobEventProgress, false},
+		{model.JobStatusProcessing, model.JobEventProgress, false},
+		{model.JobStatusCompleted, model.JobEventCompleted, true},
+		{model.JobStatusFailed, model.JobEventFailed, true},
+		{model.JobStatusCancelled, model.JobEventCancelled, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			e := jobEvent(&model.GenerationJob{ID: "job-1", Status: tt.status, Progress: 30, StatusMessage: &msg})
+			if e.Type != tt.wantType {
+				t.Errorf("type = %q, want %q", e.Type, tt.wantType)
+			}
+			if e.IsFinal() != tt.wantFinal {
+				t.Errorf("IsFinal() = %v, want %v", e.IsFinal(), tt.wantFinal)
+			}
+			if e.JobID != "job-1" || e.Progress != 30 || e.StatusMessage != &msg {
+				t.Errorf("event = %+v, want the job's state", e)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"shift-app/internal/model"
+	"shift-app/internal/repository"
+)
+
+// UnfinalizePattern reopens a finalized pattern: it goes back to selected so
+// its entries can be edited and it can be finalized again
+func (s *ShiftService) UnfinalizePattern(ctx context.Context, id string, req model.PatternTransitionRequest) (*model.ShiftPattern, error) {
+	return s.changePatternStatus(ctx, id, []string{model.PatternStatusFinalized}, model.PatternStatusSelected, req)
+}
+
+// PublishPattern publishes a finalized pattern to the staff
+func (s *ShiftService) PublishPattern(ctx context.Context, id string, req model.PatternTransitionRequest) (*model.ShiftPattern, error) {
+	return s.changePatternStatus(ctx, id, []string{model.PatternStatusFinalized}, model.PatternStatusPublished, req)
+}
+
+// RevisePattern reopens a published pattern for revision: it goes back to
+// selected and has to be finalized and published again
+func (s *ShiftService) RevisePattern(ctx context.Context, id string, req model.PatternTransitionRequest) (*model.ShiftPattern, error) {
+	return s.changePatternStatus(ctx, id, []string{model.PatternStatusPublished}, model.PatternStatusSelected, req)
+}
+
+// ArchivePattern archives a published pattern whose month is over, or a
+// draft that won't be used
+func (s *ShiftService) ArchivePattern(ctx context.Context, id string, req model.PatternTransitionRequest) (*model.ShiftPattern, error) {
+	return s.changePatternStatus(ctx, id, []string{model.PatternStatusDraft, model.PatternStatusPublished}, model.PatternStatusArchived, req)
+}
+
+// ListStatusHistory returns the status changes of a pattern, or nil when the
+// pattern doesn't exist
+func (s *ShiftService) ListStatusHistory(ctx context.Context, id string) ([]model.PatternStatusChange, error) {
+	pattern, err := s.patternRepo.GetByID(ctx, id)
+	if err != nil || pattern == nil {
+		return nil, err
+	}
+	history, err := s.patternRepo.ListStatusHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []model.PatternStatusChange{}
+	}
+	return history, nil
+}
+
+// changePatternStatus moves a pattern in one of the statuses from to status
+// to. It returns nil when the pattern doesn't exist.
+func (s *ShiftService) changePatternStatus(ctx context.Context, id string, from []string, to string, req model.PatternTransitionRequest) (*model.ShiftPattern, error) {
+	req, err := trimTransitionRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	pattern, err := s.patternRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if pattern == nil {
+		return nil, nil
+	}
+	if !slices.Contains(from, pattern.Status) || !model.CanTransitionPattern(pattern.Status, to) {
+		return nil, transitionError(pattern.Status, to)
+	}
+
+	ok, err := s.patternRepo.Transition(ctx, id, pattern.Status, to, req.ChangedBy, req.Reason)
+	if errors.Is(err, repository.ErrMonthLocked) {
+		return nil, monthFinalizedError(pattern.YearMonth)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, statusChangedError()
+	}
+	return s.patternRepo.GetByID(ctx, id)
+}
+
+// checkEditable refuses changes to the entries of a finalized, published or
+// archived pattern
+func (s *ShiftService) checkEditable(ctx context.Context, patternID string) error {
+	pattern, err := s.patternRepo.GetByID(ctx, patternID)
+	if err != nil {
+		return err
+	}
+	if pattern != nil && !model.PatternEditable(pattern.Status) {
+		return lockedError(pattern.Status)
+	}
+	return nil
+}
+
+// trimTransitionRequest trims a PatternTransitionRequest and checks its
+// lengths
+func trimTransitionRequest(req model.PatternTransitionRequest) (model.PatternTransitionRequest, error) {
+	req.ChangedBy = strings.TrimSpace(req.ChangedBy)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if utf8.RuneCountInString(req.ChangedBy) > maxActorLength {
+		return req, fieldError("changed_by", fmt.Sprintf("changed_by は%d文字以内で指定してください", maxActorLength))
+	}
+	if utf8.RuneCountInString(req.Reason) > maxReasonLength {
+		return req, fieldError("reason", fmt.Sprintf("reason は%d文字以内で指定してください", maxReasonLength))
+	}
+	return req, nil
+}
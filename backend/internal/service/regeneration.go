@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"shift-app/internal/model"
+	"shift-app/internal/repository"
+)
+
+// RegeneratePattern queues a job that regenerates the entries of a pattern
+// inside a date range and/or for some staff. Entries outside the scope and
+// manually edited entries are kept. It returns nil when the pattern doesn't
+// exist.
+func (s *ShiftService) RegeneratePattern(ctx context.Context, patternID string, req model.RegeneratePatternRequest) (*model.GenerationJob, error) {
+	pattern, err := s.patternRepo.GetByID(ctx, patternID)
+	if err != nil || pattern == nil {
+		return nil, err
+	}
+	if !model.PatternEditable(pattern.Status) {
+		return nil, lockedError(pattern.Status)
+	}
+	scope := req.RegenerationScope
+	if err := validateScope(scope, pattern.YearMonth); err != nil {
+		return nil, err
+	}
+	for _, id := range scope.StaffIDs {
+		staff, err := s.staffRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if staff == nil {
+			return nil, fieldError("staff_ids", fmt.Sprintf("スタッフ %s が見つかりません", id))
+		}
+	}
+
+	if req.Generator == "" {
+		req.Generator = model.GeneratorLLM
+	}
+	if _, ok := s.generators[req.Generator]; !ok {
+		return nil, fieldError("generator", "generator は llm, local, hybrid のいずれかで指定してください")
+	}
+	if req.Seed == nil && req.Generator == model.GeneratorLocal {
+		seed := time.Now().UnixNano()
+		req.Seed = &seed
+	}
+
+	hasJob, err := s.jobRepo.HasProcessingJob(ctx, pattern.YearMonth)
+	if err != nil {
+		return nil, err
+	}
+	if hasJob {
+		return nil, fieldError("year_month", "この月のシフト生成が既に進行中です")
+	}
+
+	guidance, err := s.regenerationGuidance(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.jobRepo.CreateRegeneration(ctx, pattern.YearMonth, req.Generator, req.Seed, patternID, scope, guidance)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyWorkers()
+	return job, nil
+}
+
+// regenerationGuidance carries the instruction and weights of the job that
+// created a pattern, and the pattern's strategy, over to its regeneration so
+// the new entries follow and are scored by the same guidance
+func (s *ShiftService) regenerationGuidance(ctx context.Context, pattern *model.ShiftPattern) (model.GenerationGuidance, error) {
+	var guidance model.GenerationGuidance
+	if pattern.Strategy != nil {
+		guidance.Strategies = []string{*pattern.Strategy}
+	}
+	if pattern.JobID == nil {
+		return guidance, nil
+	}
+	origin, err := s.jobRepo.GetByID(ctx, *pattern.JobID)
+	if err != nil || origin == nil {
+		return guidance, err
+	}
+	guidance.Instruction = origin.Instruction
+	guidance.Weights = origin.Weights
+	return guidance, nil
+}
+
+// validateScope checks that a scope restricts something and that its dates
+// fall in the pattern's month
+func validateScope(scope model.RegenerationScope, yearMonth string) error {
+	if scope.StartDate == "" && scope.EndDate == "" && len(scope.StaffIDs) == 0 {
+		return fieldError("scope", "再生成する期間（start_date, end_date）またはスタッフ（staff_ids）を指定してください")
+	}
+	dates := []struct{ field, date string }{{"start_date", scope.StartDate}, {"end_date", scope.EndDate}}
+	for _, d := range dates {
+		if d.date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d.date); err != nil || d.date[:7] != yearMonth {
+			return fieldError(d.field, fmt.Sprintf("start_date, end_date はパターンの対象月（%s）の日付を YYYY-MM-DD 形式で指定してください", yearMonth))
+		}
+	}
+	if scope.StartDate != "" && scope.EndDate != "" && scope.StartDate > scope.EndDate {
+		return fieldError("start_date", "start_date は end_date 以前の日付を指定してください")
+	}
+	return nil
+}
+
+// runRegeneration regenerates the scope of a job's target pattern and swaps
+// the new entries in
+func (s *ShiftService) runRegeneration(runCtx context.Context, job *model.GenerationJob) {
+	ctx := context.Background()
+	patternID := *job.TargetPatternID
+
+	entries, err := s.entryRepo.ListByPatternID(ctx, patternID)
+	if err != nil {
+		s.failJob(ctx, job.ID, fmt.Sprintf("エントリ取得失敗: %v", err))
+		return
+	}
+	locked, replaced := splitByScope(entries, job.Scope)
+
+	generated, err := s.generatePattern(runCtx, job, model.GenerationParams{Strategy: strategyFor(job, 0), Scope: job.Scope, LockedEntries: locked}, func(retry int) {
+		statusMsg := "パターン再生成中"
+		if retry > 0 {
+			statusMsg = fmt.Sprintf("パターン再生成中 (試行%d/%d)", retry+1, maxGenerationRetries)
+		}
+		s.publishProgress(ctx, job.ID, (retry*100)/maxGenerationRetries, statusMsg)
+	})
+	if runCtx.Err() != nil {
+		log.Printf("Job %s cancelled during regeneration", job.ID)
+		return
+	}
+	if err != nil {
+		s.failJob(ctx, job.ID, err.Error())
+		return
+	}
+
+	violations, score := generated.violations()
+	added := withoutEntries(generated.result.Entries, locked)
+	kept, err := s.patternRepo.ReplaceEntries(ctx, patternID, replaced, added, score, generated.breakdown(), violations)
+	if errors.Is(err, repository.ErrPatternLocked) {
+		s.failJob(ctx, job.ID, "パターンが確定されたため再生成結果を保存できませんでした")
+		return
+	}
+	if err != nil {
+		s.failJob(ctx, job.ID, fmt.Sprintf("エントリ保存失敗: %v", err))
+		return
+	}
+
+	pattern, err := s.patternRepo.GetByID(ctx, patternID)
+	// Entries edited by hand during generation were kept, so the generated
+	// score doesn't describe the stored entries
+	if err == nil && pattern != nil && kept > 0 {
+		if _, err := s.revalidatePattern(ctx, pattern); err != nil {
+			log.Printf("Failed to re-validate pattern %s: %v", patternID, err)
+		}
+		pattern, err = s.patternRepo.GetByID(ctx, patternID)
+	}
+	if err == nil && pattern != nil {
+		s.publishPattern(job.ID, pattern, 95)
+		// The pattern changed, so its distance to its siblings did too
+		if pattern.JobID != nil {
+			s.scoreDiversity(ctx, *pattern.JobID)
+		}
+	}
+	_ = s.jobRepo.SetCompleted(ctx, job.ID)
+	s.events.Publish(model.JobEvent{Type: model.JobEventCompleted, JobID: job.ID, Status: model.JobStatusCompleted, Progress: 100})
+}
+
+// splitByScope divides the entries of a pattern into those kept as they are
+// (outside the scope or manually edited) and the IDs of those to replace
+func splitByScope(entries []model.ShiftEntry, scope *model.RegenerationScope) ([]model.LLMShiftEntry, []string) {
+	locked := []model.LLMShiftEntry{}
+	replaced := []string{}
+	for _, e := range entries {
+		if e.IsManualEdit || !scope.Contains(e.StaffID, e.Date) {
+			locked = append(locked, model.LLMShiftEntry{
+				StaffID:      e.StaffID,
+				Date:         e.Date,
+				StartTime:    e.StartTime,
+				EndTime:      e.EndTime,
+				BreakMinutes: e.BreakMinutes,
+			})
+			continue
+		}
+		replaced = append(replaced, e.ID)
+	}
+	return locked, replaced
+}
+
+// withLocked returns the pattern after regeneration: the locked entries plus
+// the generated entries that are inside the scope and don't collide with a
+// locked entry of the same staff and date
+func withLocked(result *model.LLMResponse, scope *model.RegenerationScope, locked []model.LLMShiftEntry) *model.LLMResponse {
+	taken := make(map[string]bool, len(locked))
+	for _, e := range locked {
+		taken[e.StaffID+"|"+e.Date] = true
+	}
+	merged := *result
+	merged.Entries = append([]model.LLMShiftEntry{}, locked...)
+	for _, e := range result.Entries {
+		key := e.StaffID + "|" + e.Date
+		if !scope.Contains(e.StaffID, e.Date) || taken[key] {
+			continue
+		}
+		taken[key] = true
+		merged.Entries = append(merged.Entries, e)
+	}
+	return &merged
+}
+
+// withoutEntries returns the entries that aren't in remove (by staff and date)
+func withoutEntries(entries, remove []model.LLMShiftEntry) []model.LLMShiftEntry {
+	skip := make(map[string]bool, len(remove))
+	for _, e := range remove {
+		skip[e.StaffID+"|"+e.Date] = true
+	}
+	var result []model.LLMShiftEntry
+	for _, e := range entries {
+		if !skip[e.StaffID+"|"+e.Date] {
+			result = append(result, e)
+		}
+	}
+	return result
+}
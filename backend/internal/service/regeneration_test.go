@@ -0,0 +1,91 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"shift-app/internal/model"
+)
+
+func TestValidateScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   model.RegenerationScope
+		wantErr string
+	}{
+		{"date range", model.RegenerationScope{StartDate: "2025-02-10", EndDate: "2025-02-16"}, ""},
+		{"staff only", model.RegenerationScope{StaffIDs: []string{"s1"}}, ""},
+		{"empty", model.RegenerationScope{}, "再生成する期間（start_date, end_date）またはスタッフ（staff_ids）を指定してください"},
+		{"other month", model.RegenerationScope{StartDate: "2025-03-01"}, "start_date, end_date はパターンの対象月（2025-02）の日付を YYYY-MM-DD 形式で指定してください"},
+		{"bad format", model.RegenerationScope{EndDate: "2025-02-30"}, "start_date, end_date はパターンの対象月（2025-02）の日付を YYYY-MM-DD 形式で指定してください"},
+		{"reversed", model.RegenerationScope{StartDate: "2025-02-16", EndDate: "2025-02-10"}, "start_date は end_date 以前の日付を指定してください"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateScope(tt.scope, "2025-02")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) || err.Error() != tt.wantErr {
+				t.Errorf("error = %v, want ValidationError %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSplitByScope(t *testing.T) {
+	scope := &model.RegenerationScope{StartDate: "2025-02-10", EndDate: "2025-02-16"}
+	entries := []model.ShiftEntry{
+		{ID: "e1", StaffID: "s1", Date: "2025-02-09", StartTime: "09:00", EndTime: "18:00"},
+		{ID: "e2", StaffID: "s1", Date: "2025-02-10", StartTime: "09:00", EndTime: "18:00"},
+		{ID: "e3", StaffID: "s2", Date: "2025-02-11", StartTime: "13:00", EndTime: "22:00", IsManualEdit: true},
+	}
+
+	locked, replaced := splitByScope(entries, scope)
+	if !reflect.DeepEqual(replaced, []string{"e2"}) {
+		t.Errorf("replaced = %v, want [e2]", replaced)
+	}
+	if len(locked) != 2 || locked[0].Date != "2025-02-09" || locked[1].StaffID != "s2" {
+		t.Errorf("locked = %+v, want the out-of-scope and manually edited entries", locked)
+	}
+}
+
+func TestWithLocked(t *testing.T) {
+	scope := &model.RegenerationScope{StaffIDs: []string{"s1"}}
+	locked := []model.LLMShiftEntry{
+		{StaffID: "s2", Date: "2025-02-10", StartTime: "09:00", EndTime: "18:00"},
+		{StaffID: "s1", Date: "2025-02-11", StartTime: "09:00", EndTime: "18:00"},
+	}
+	result := &model.LLMResponse{
+		Reasoning: "再生成",
+		Entries: []model.LLMShiftEntry{
+			{StaffID: "s1", Date: "2025-02-10", StartTime: "13:00", EndTime: "22:00"},
+			// Out of scope
+			{StaffID: "s2", Date: "2025-02-12", StartTime: "09:00", EndTime: "18:00"},
+			// Collides with a locked entry
+			{StaffID: "s1", Date: "2025-02-11", StartTime: "13:00", EndTime: "22:00"},
+		},
+	}
+
+	merged := withLocked(result, scope, locked)
+	if len(merged.Entries) != 3 {
+		t.Fatalf("got %d entries, want 3: %+v", len(merged.Entries), merged.Entries)
+	}
+	if merged.Reasoning != "再生成" {
+		t.Errorf("reasoning = %q", merged.Reasoning)
+	}
+	added := withoutEntries(merged.Entries, locked)
+	want := []model.LLMShiftEntry{{StaffID: "s1", Date: "2025-02-10", StartTime: "13:00", EndTime: "22:00"}}
+	if !reflect.DeepEqual(added, want) {
+		t.Errorf("added = %+v, want %+v", added, want)
+	}
+	if len(result.Entries) != 3 {
+		t.Error("withLocked modified the generator's result")
+	}
+}
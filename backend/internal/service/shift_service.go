@@ -5,45 +5,74 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"shift-app/internal/model"
 	"shift-app/internal/repository"
 )
 
-// ShiftGenerator is an interface for the LLM generator
+// ShiftGenerator is an interface for shift generators (LLM, local solver)
 type ShiftGenerator interface {
-	Generate(ctx context.Context, yearMonth string, patternCount int, patternIdx int, previousPatterns []model.LLMResponse, lastViolations []model.Violation) (*model.LLMResponse, error)
+	Generate(ctx context.Context, params model.GenerationParams) (*model.LLMResponse, error)
+}
+
+// ShiftRepairer fixes hard violations of a generated response without
+// calling the generator again
+type ShiftRepairer interface {
+	Repair(ctx context.Context, yearMonth string, response *model.LLMResponse, violations []model.Violation) (*model.RepairResult, error)
 }
 
 // ShiftValidator is an interface for the shift validator
 type ShiftValidator interface {
-	Validate(ctx context.Context, yearMonth string, response *model.LLMResponse) (*model.ValidationResult, error)
+	Validate(ctx context.Context, yearMonth string, response *model.LLMResponse, weights *model.ObjectiveWeights) (*model.ValidationResult, error)
+	ValidateEntries(ctx context.Context, yearMonth string, entries []model.ShiftEntry, weights *model.ObjectiveWeights) (*model.ValidationResult, error)
 }
 
 type ShiftService struct {
 	patternRepo *repository.ShiftPatternRepository
 	entryRepo   *repository.ShiftEntryRepository
 	jobRepo     *repository.GenerationJobRepository
+	attemptRepo *repository.GenerationAttemptRepository
 	staffRepo   *repository.StaffRepository
-	generator   ShiftGenerator
+	generators  map[string]ShiftGenerator
+	repairer    ShiftRepairer
 	validator   ShiftValidator
+
+	// cancels holds the cancel funcs of the jobs running in this process
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+	// wake tells the worker pool of this process that a job was queued
+	wake chan struct{}
+	// events carries the progress of the jobs run in this process
+	events *JobEvents
 }
 
 func NewShiftService(
 	patternRepo *repository.ShiftPatternRepository,
 	entryRepo *repository.ShiftEntryRepository,
 	jobRepo *repository.GenerationJobRepository,
+	attemptRepo *repository.GenerationAttemptRepository,
 	staffRepo *repository.StaffRepository,
-	generator ShiftGenerator,
+	generators map[string]ShiftGenerator,
+	repairer ShiftRepairer,
 	validator ShiftValidator,
 ) *ShiftService {
 	return &ShiftService{
 		patternRepo: patternRepo,
 		entryRepo:   entryRepo,
 		jobRepo:     jobRepo,
+		attemptRepo: attemptRepo,
 		staffRepo:   staffRepo,
-		generator:   generator,
+		generators:  generators,
+		repairer:    repairer,
 		validator:   validator,
+		cancels:     make(map[string]context.CancelFunc),
+		wake:        make(chan struct{}, 1),
+		events:      NewJobEvents(),
 	}
 }
 
@@ -60,6 +89,20 @@ func (s *ShiftService) StartGeneration(ctx context.Context, req model.GenerateSh
 	if req.PatternCount > 5 {
 		return nil, errors.New("パターン数は5以下で指定してください")
 	}
+	if req.Generator == "" {
+		req.Generator = model.GeneratorLLM
+	}
+	if _, ok := s.generators[req.Generator]; !ok {
+		return nil, errors.New("generator は llm, local, hybrid のいずれかで指定してください")
+	}
+	if err := validateGuidance(&req.GenerationGuidance, req.PatternCount); err != nil {
+		return nil, err
+	}
+	// Record a seed for the local solver so the result can be reproduced
+	if req.Seed == nil && req.Generator == model.GeneratorLocal {
+		seed := time.Now().UnixNano()
+		req.Seed = &seed
+	}
 
 	// Check for existing processing job
 	hasJob, err := s.jobRepo.HasProcessingJob(ctx, req.YearMonth)
@@ -70,128 +113,511 @@ func (s *ShiftService) StartGeneration(ctx context.Context, req model.GenerateSh
 		return nil, errors.New("この月のシフト生成が既に進行中です")
 	}
 
-	job, err := s.jobRepo.Create(ctx, req.YearMonth, req.PatternCount)
+	job, err := s.jobRepo.Create(ctx, req.YearMonth, req.PatternCount, req.Generator, req.Seed, req.Parallel, req.GenerationGuidance)
 	if err != nil {
 		return nil, err
 	}
 
-	// Start async generation
-	go s.runGeneration(job.ID, req.YearMonth, req.PatternCount)
+	// Queued; a worker picks it up
+	s.notifyWorkers()
 
 	return job, nil
 }
 
-func (s *ShiftService) runGeneration(jobID string, yearMonth string, patternCount int) {
+// maxInstructionLength is the longest manager instruction, in characters
+const maxInstructionLength = 500
+
+// validateGuidance checks the optional guidance of a generation request and
+// trims its instruction
+func validateGuidance(g *model.GenerationGuidance, patternCount int) error {
+	g.Instruction = strings.TrimSpace(g.Instruction)
+	if utf8.RuneCountInString(g.Instruction) > maxInstructionLength {
+		return fmt.Errorf("instruction は%d文字以内で指定してください", maxInstructionLength)
+	}
+	if len(g.Strategies) > patternCount {
+		return fmt.Errorf("strategies はパターン数（%d）以下の件数で指定してください", patternCount)
+	}
+	for _, strategy := range g.Strategies {
+		if strategy != "" && !slices.Contains(model.PatternStrategies, strategy) {
+			return fmt.Errorf("strategies には %s のいずれかを指定してください", strings.Join(model.PatternStrategies, ", "))
+		}
+	}
+	if g.Weights != nil {
+		if err := g.Weights.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CancelGeneration cancels a pending or processing job. Patterns already
+// saved are kept. It returns nil when the job doesn't exist.
+func (s *ShiftService) CancelGeneration(ctx context.Context, jobID string) (*model.GenerationJob, error) {
+	job, err := s.jobRepo.GetByID(ctx, jobID)
+	if err != nil || job == nil {
+		return nil, err
+	}
+	cancelled, err := s.jobRepo.SetCancelled(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, jobStatusError("このジョブは既に終了しているためキャンセルできません")
+	}
+	s.events.Publish(model.JobEvent{Type: model.JobEventCancelled, JobID: jobID, Status: model.JobStatusCancelled, Progress: job.Progress})
+
+	// Stop it right away if it runs in this process; a worker in another
+	// process notices the status on its next heartbeat
+	s.mu.Lock()
+	cancel := s.cancels[jobID]
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return s.jobRepo.GetByID(ctx, jobID)
+}
+
+// ResumeGeneration runs a failed or cancelled job again, generating only the
+// patterns it hasn't saved yet. It returns nil when the job doesn't exist.
+func (s *ShiftService) ResumeGeneration(ctx context.Context, jobID string) (*model.GenerationJob, error) {
+	job, err := s.jobRepo.GetByID(ctx, jobID)
+	if err != nil || job == nil {
+		return nil, err
+	}
+	if job.Status != model.JobStatusFailed && job.Status != model.JobStatusCancelled {
+		return nil, jobStatusError("再開できるのは failed または cancelled のジョブのみです")
+	}
+	if _, ok := s.generators[job.Generator]; !ok {
+		return nil, &ValidationError{Details: []model.ErrorDetail{{Field: "generator", Message: fmt.Sprintf("generator %s は利用できません", job.Generator)}}}
+	}
+	hasJob, err := s.jobRepo.HasProcessingJob(ctx, job.YearMonth)
+	if err != nil {
+		return nil, err
+	}
+	if hasJob {
+		return nil, &ValidationError{Details: []model.ErrorDetail{{Field: "year_month", Message: "この月のシフト生成が既に進行中です"}}}
+	}
+
+	requeued, err := s.jobRepo.Requeue(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if !requeued {
+		return nil, jobStatusError("再開できるのは failed または cancelled のジョブのみです")
+	}
+	s.notifyWorkers()
+	return s.jobRepo.GetByID(ctx, jobID)
+}
+
+// runGeneration generates the patterns of a claimed job. runCtx is cancelled
+// when the job is cancelled or the worker shuts down; database writes use
+// their own context so the job state can still be recorded.
+func (s *ShiftService) runGeneration(runCtx context.Context, job *model.GenerationJob) {
 	ctx := context.Background()
-	maxRetries := 3
+	jobID, patternCount := job.ID, job.PatternCount
 
-	if err := s.jobRepo.SetProcessing(ctx, jobID); err != nil {
-		log.Printf("Failed to set job processing: %v", err)
+	// Patterns saved by an earlier run of this job are kept and skipped
+	done, previousPatterns, err := s.savedPatterns(ctx, jobID)
+	if err != nil {
+		s.failJob(ctx, jobID, fmt.Sprintf("保存済みパターン取得失敗: %v", err))
 		return
 	}
 
-	var previousPatterns []model.LLMResponse
-
-	for i := 0; i < patternCount; i++ {
-		var finalResult *model.LLMResponse
-		var finalValidation *model.ValidationResult
-		var lastViolations []model.Violation
-
-		for retry := 0; retry < maxRetries; retry++ {
-			// Update progress: each pattern has equal weight, retries subdivide
-			progressPct := (i * 100) / patternCount
-			retryPct := (retry * 100) / (patternCount * maxRetries)
-			progressPct += retryPct
-			if progressPct > 95 {
-				progressPct = 95
+	if job.Parallel {
+		if !s.runParallel(runCtx, job, done) {
+			return
+		}
+	} else {
+		for i := 0; i < patternCount; i++ {
+			if done[i] {
+				continue
 			}
-			statusMsg := fmt.Sprintf("パターン%d/%d 生成中", i+1, patternCount)
-			if retry > 0 {
-				statusMsg = fmt.Sprintf("パターン%d/%d 再生成中 (試行%d/%d)", i+1, patternCount, retry+1, maxRetries)
+			if runCtx.Err() != nil {
+				log.Printf("Job %s cancelled before pattern %d", jobID, i+1)
+				return
 			}
-			_ = s.jobRepo.UpdateProgress(ctx, jobID, progressPct, statusMsg)
-
-			result, err := s.generator.Generate(ctx, yearMonth, patternCount, i, previousPatterns, lastViolations)
-			if err != nil {
-				log.Printf("LLM generation failed (pattern %d, retry %d): %v", i+1, retry+1, err)
-				if retry == maxRetries-1 {
-					_ = s.jobRepo.SetFailed(ctx, jobID, fmt.Sprintf("パターン%d生成失敗: %v", i+1, err))
-					return
+			strategy := strategyFor(job, i)
+			params := model.GenerationParams{PatternIdx: i, Strategy: strategy, PreviousPatterns: previousPatterns}
+			generated, err := s.generatePattern(runCtx, job, params, func(retry int) {
+				// Each pattern has equal weight, retries subdivide
+				progressPct := (i * 100) / patternCount
+				progressPct += (retry * 100) / (patternCount * maxGenerationRetries)
+				statusMsg := fmt.Sprintf("パターン%d/%d 生成中", i+1, patternCount)
+				if retry > 0 {
+					statusMsg = fmt.Sprintf("パターン%d/%d 再生成中 (試行%d/%d)", i+1, patternCount, retry+1, maxGenerationRetries)
 				}
-				continue
+				s.publishProgress(ctx, jobID, min(progressPct, 95), statusMsg)
+			})
+			if runCtx.Err() != nil {
+				log.Printf("Job %s cancelled during pattern %d", jobID, i+1)
+				return
 			}
-
-			validation, err := s.validator.Validate(ctx, yearMonth, result)
 			if err != nil {
-				log.Printf("Validation failed: %v", err)
-				if retry == maxRetries-1 {
-					// Save even with validation errors
-					finalResult = result
-					finalValidation = validation
-					break
-				}
-				continue
+				s.failJob(ctx, jobID, err.Error())
+				return
 			}
+			pattern, err := s.savePattern(ctx, job, i, optionalString(strategy), generated)
+			if err != nil {
+				s.failJob(ctx, jobID, err.Error())
+				return
+			}
+			s.publishPattern(jobID, pattern, min(((i+1)*100)/patternCount, 95))
+			previousPatterns = append(previousPatterns, *generated.result)
+		}
+	}
 
-			finalResult = result
-			finalValidation = validation
+	s.scoreDiversity(ctx, jobID)
+	_ = s.jobRepo.SetCompleted(ctx, jobID)
+	s.events.Publish(model.JobEvent{Type: model.JobEventCompleted, JobID: jobID, Status: model.JobStatusCompleted, Progress: 100})
+}
 
-			if !validation.HasHardViolations() {
-				break
+// runParallel generates the unsaved patterns of a job at the same time. The
+// patterns can't see each other, so each gets its own strategy to keep them
+// apart. A failed pattern doesn't stop the others; the job fails once all
+// have finished and can be resumed for the missing ones. It reports whether
+// every pattern was saved.
+func (s *ShiftService) runParallel(runCtx context.Context, job *model.GenerationJob, done map[int]bool) bool {
+	ctx := context.Background()
+	jobID, patternCount := job.ID, job.PatternCount
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		finished = len(done)
+		failures = make([]string, patternCount)
+	)
+	s.publishProgress(ctx, jobID, min((finished*100)/patternCount, 95), fmt.Sprintf("パターン%d件を並列生成中", patternCount-finished))
+	for i := 0; i < patternCount; i++ {
+		if done[i] {
+			continue
+		}
+		strategy := strategyFor(job, i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			generated, err := s.generatePattern(runCtx, job, model.GenerationParams{PatternIdx: i, Strategy: strategy}, nil)
+			var pattern *model.ShiftPattern
+			if err == nil && runCtx.Err() == nil {
+				pattern, err = s.savePattern(ctx, job, i, &strategy, generated)
 			}
-			// Log violation details and pass them to next retry
-			lastViolations = validation.Violations
-			for _, v := range validation.Violations {
-				if v.Type == "hard" {
-					log.Printf("  [hard] %s: %s (staff=%s, date=%s)", v.Constraint, v.Message, v.StaffID, v.Date)
-				}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if runCtx.Err() != nil {
+				return
 			}
-			log.Printf("Hard constraint violations found (pattern %d, retry %d), retrying with feedback...", i+1, retry+1)
+			if err != nil {
+				failures[i] = err.Error()
+				return
+			}
+			finished++
+			progressPct := min((finished*100)/patternCount, 95)
+			s.publishPattern(jobID, pattern, progressPct)
+			s.publishProgress(ctx, jobID, progressPct, fmt.Sprintf("パターン並列生成中 (完了 %d/%d)", finished, patternCount))
+		}()
+	}
+	wg.Wait()
+
+	if runCtx.Err() != nil {
+		log.Printf("Job %s cancelled during parallel generation", jobID)
+		return false
+	}
+	var messages []string
+	for _, f := range failures {
+		if f != "" {
+			messages = append(messages, f)
 		}
+	}
+	if len(messages) > 0 {
+		s.failJob(ctx, jobID, strings.Join(messages, "; "))
+		return false
+	}
+	return true
+}
 
-		if finalResult == nil {
-			_ = s.jobRepo.SetFailed(ctx, jobID, fmt.Sprintf("パターン%d: 結果が空です", i+1))
-			return
+// strategyFor returns the strategy preset of pattern i of a job: the one
+// requested for it, else for parallel jobs one picked by the pattern's index.
+// Sequential patterns without a preset return "" and are kept apart by the
+// previous patterns instead.
+func strategyFor(job *model.GenerationJob, i int) string {
+	if i < len(job.Strategies) && job.Strategies[i] != "" {
+		return job.Strategies[i]
+	}
+	if job.Parallel {
+		return model.PatternStrategies[i%len(model.PatternStrategies)]
+	}
+	return ""
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// maxGenerationRetries is how many times a pattern is generated before the
+// job gives up on it
+const maxGenerationRetries = 3
+
+// generatedPattern is a pattern ready to be saved
+type generatedPattern struct {
+	result     *model.LLMResponse
+	validation *model.ValidationResult
+	repairs    []model.RepairAction
+}
+
+// generatePattern generates pattern i of a job, retrying with the violations
+// of the previous attempt as feedback. progress, when set, is called before
+// each attempt. When runCtx ends the result is meaningless and the caller
+// must check runCtx.Err() first.
+func (s *ShiftService) generatePattern(runCtx context.Context, job *model.GenerationJob, params model.GenerationParams, progress func(retry int)) (*generatedPattern, error) {
+	ctx := context.Background()
+	i := params.PatternIdx
+	generator := s.generators[job.Generator]
+	var seed int64
+	if job.Seed != nil {
+		seed = *job.Seed
+	}
+
+	var final *generatedPattern
+	var lastViolations []model.Violation
+	for retry := 0; retry < maxGenerationRetries; retry++ {
+		if progress != nil {
+			progress(retry)
 		}
 
-		// Merge violations from LLM and validator
-		violations := finalResult.ConstraintViolations
-		if finalValidation != nil {
-			for _, v := range finalValidation.Violations {
-				violations = append(violations, model.ConstraintViolation{
-					ConstraintName: v.Constraint,
-					Type:           v.Type,
-					Message:        v.Message,
-				})
+		// Retries shift the seed so a deterministic generator tries a
+		// different solution instead of repeating the same one
+		attempt := &model.GenerationAttempt{JobID: job.ID, PatternIndex: i, Retry: retry, Generator: job.Generator}
+		trace := &model.GenerationTrace{}
+		started := time.Now()
+		params.YearMonth = job.YearMonth
+		params.PatternCount = job.PatternCount
+		params.LastViolations = lastViolations
+		params.Seed = seed + int64(retry)
+		params.Instruction = job.Instruction
+		params.Weights = job.Weights
+		params.Trace = trace
+		result, err := generator.Generate(runCtx, params)
+		attempt.LatencyMs = int(time.Since(started).Milliseconds())
+		attempt.Response = result
+		if runCtx.Err() != nil {
+			return nil, runCtx.Err()
+		}
+		if err == nil && params.Scope != nil {
+			// Validate the whole pattern as it will be after regeneration
+			result = withLocked(result, params.Scope, params.LockedEntries)
+		}
+		if err != nil {
+			log.Printf("Generation failed (pattern %d, retry %d): %v", i+1, retry+1, err)
+			errMsg := err.Error()
+			attempt.Outcome = model.AttemptOutcomeGenerationError
+			attempt.ErrorMessage = &errMsg
+			// Feed schema errors back so the next attempt can fix them
+			var schemaErr *model.OutputSchemaError
+			if errors.As(err, &schemaErr) {
+				lastViolations = schemaErr.Violations()
+				attempt.ParseErrors = schemaErr.Details
 			}
+			s.recordAttempt(ctx, attempt, trace)
+			if retry == maxGenerationRetries-1 {
+				return nil, fmt.Errorf("パターン%d生成失敗: %v", i+1, err)
+			}
+			continue
 		}
 
-		score := float64(0)
-		if finalValidation != nil {
-			score = finalValidation.Score
+		validation, err := s.validator.Validate(runCtx, job.YearMonth, result, job.Weights)
+		attempt.Validation = validation
+		if err != nil {
+			log.Printf("Validation failed: %v", err)
+			errMsg := err.Error()
+			attempt.Outcome = model.AttemptOutcomeValidationError
+			attempt.ErrorMessage = &errMsg
+			s.recordAttempt(ctx, attempt, trace)
+			if retry == maxGenerationRetries-1 {
+				// Save even with validation errors
+				final = &generatedPattern{result: result, validation: validation}
+				break
+			}
+			continue
 		}
 
-		pattern, err := s.patternRepo.Create(ctx, yearMonth, finalResult.Reasoning, score, violations)
-		if err != nil {
-			_ = s.jobRepo.SetFailed(ctx, jobID, fmt.Sprintf("パターン%d保存失敗: %v", i+1, err))
-			return
+		final = &generatedPattern{result: result, validation: validation}
+
+		if !validation.HasHardViolations() {
+			attempt.Outcome = model.AttemptOutcomeOK
+			s.recordAttempt(ctx, attempt, trace)
+			break
 		}
 
-		if err := s.entryRepo.BulkCreate(ctx, pattern.ID, finalResult.Entries); err != nil {
-			_ = s.jobRepo.SetFailed(ctx, jobID, fmt.Sprintf("エントリ保存失敗: %v", err))
-			return
+		// Hybrid: fix the draft locally before spending another LLM call.
+		// Not for regeneration, where repair could move locked entries.
+		if job.Generator == model.GeneratorHybrid && params.Scope == nil {
+			if repaired, revalidation, ok := s.repair(runCtx, job, result, validation); ok {
+				final = &generatedPattern{result: repaired.Response, validation: revalidation, repairs: repaired.Actions}
+				attempt.Repairs = repaired.Actions
+				log.Printf("Repaired pattern %d locally (%d changes)", i+1, len(repaired.Actions))
+				if !revalidation.HasHardViolations() {
+					attempt.Outcome = model.AttemptOutcomeOK
+					s.recordAttempt(ctx, attempt, trace)
+					break
+				}
+				validation = revalidation
+			}
+		}
+		attempt.Outcome = model.AttemptOutcomeHardViolations
+		s.recordAttempt(ctx, attempt, trace)
+
+		// Log violation details and pass them to next retry
+		lastViolations = validation.Violations
+		for _, v := range validation.Violations {
+			if v.Type == "hard" {
+				log.Printf("  [hard] %s: %s (staff=%s, date=%s)", v.Constraint, v.Message, v.StaffID, v.Date)
+			}
 		}
+		log.Printf("Hard constraint violations found (pattern %d, retry %d), retrying with feedback...", i+1, retry+1)
+	}
 
-		previousPatterns = append(previousPatterns, *finalResult)
+	if runCtx.Err() != nil {
+		return nil, runCtx.Err()
+	}
+	if final == nil {
+		return nil, fmt.Errorf("パターン%d: 結果が空です", i+1)
 	}
+	return final, nil
+}
 
-	_ = s.jobRepo.SetCompleted(ctx, jobID)
+// violations merges the violations reported by the generator and found by
+// the validator, and returns them with the validator's score
+func (g *generatedPattern) violations() ([]model.ConstraintViolation, float64) {
+	violations := g.result.ConstraintViolations
+	score := float64(0)
+	if g.validation != nil {
+		violations = append(violations, constraintViolations(g.validation.Violations)...)
+		score = g.validation.Score
+	}
+	return violations, score
+}
+
+// constraintViolations converts validator violations to the form stored on a
+// pattern
+func constraintViolations(violations []model.Violation) []model.ConstraintViolation {
+	result := make([]model.ConstraintViolation, 0, len(violations))
+	for _, v := range violations {
+		result = append(result, model.ConstraintViolation{
+			ConstraintName: v.Constraint,
+			Type:           v.Type,
+			Message:        v.Message,
+		})
+	}
+	return result
+}
+
+// breakdown returns how the validator made up the score
+func (g *generatedPattern) breakdown() *model.ScoreBreakdown {
+	if g.validation == nil {
+		return nil
+	}
+	return g.validation.Breakdown
+}
+
+// savePattern stores a generated pattern and its entries
+func (s *ShiftService) savePattern(ctx context.Context, job *model.GenerationJob, i int, strategy *string, generated *generatedPattern) (*model.ShiftPattern, error) {
+	violations, score := generated.violations()
+	pattern, err := s.patternRepo.Create(ctx, job.YearMonth, job.ID, i, strategy, generated.result.Reasoning, score, generated.breakdown(), violations, generated.repairs)
+	if err != nil {
+		return nil, fmt.Errorf("パターン%d保存失敗: %v", i+1, err)
+	}
+	if err := s.entryRepo.BulkCreate(ctx, pattern.ID, generated.result.Entries); err != nil {
+		return nil, fmt.Errorf("エントリ保存失敗: %v", err)
+	}
+	return pattern, nil
+}
+
+// savedPatterns returns the pattern indexes a job has already saved and their
+// contents, to be passed on as previous patterns
+func (s *ShiftService) savedPatterns(ctx context.Context, jobID string) (map[int]bool, []model.LLMResponse, error) {
+	patterns, err := s.patternRepo.ListByJobID(ctx, jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	done := make(map[int]bool, len(patterns))
+	var responses []model.LLMResponse
+	for _, p := range patterns {
+		if p.PatternIndex == nil {
+			continue
+		}
+		entries, err := s.entryRepo.ListByPatternID(ctx, p.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		resp := model.LLMResponse{Entries: model.LLMEntries(entries)}
+		if p.Reasoning != nil {
+			resp.Reasoning = *p.Reasoning
+		}
+		done[*p.PatternIndex] = true
+		responses = append(responses, resp)
+	}
+	return done, responses, nil
+}
+
+// repair runs the repair stage on a result with hard violations and
+// re-validates it. ok is false when repair or re-validation failed.
+func (s *ShiftService) repair(ctx context.Context, job *model.GenerationJob, result *model.LLMResponse, validation *model.ValidationResult) (*model.RepairResult, *model.ValidationResult, bool) {
+	if s.repairer == nil {
+		return nil, nil, false
+	}
+	repaired, err := s.repairer.Repair(ctx, job.YearMonth, result, validation.Violations)
+	if err != nil {
+		log.Printf("Repair failed: %v", err)
+		return nil, nil, false
+	}
+	revalidation, err := s.validator.Validate(ctx, job.YearMonth, repaired.Response, job.Weights)
+	if err != nil {
+		log.Printf("Validation after repair failed: %v", err)
+		return nil, nil, false
+	}
+	return repaired, revalidation, true
+}
+
+// recordAttempt saves an attempt with what the generator traced. Failing to
+// save is only logged; the history must not break generation.
+func (s *ShiftService) recordAttempt(ctx context.Context, attempt *model.GenerationAttempt, trace *model.GenerationTrace) {
+	if trace.SystemPrompt != "" {
+		attempt.SystemPrompt = &trace.SystemPrompt
+	}
+	attempt.LLMCalls = trace.Calls
+	attempt.InputTokens, attempt.OutputTokens = trace.Tokens()
+	if err := s.attemptRepo.Create(ctx, attempt); err != nil {
+		log.Printf("Failed to record generation attempt (pattern %d, retry %d): %v", attempt.PatternIndex+1, attempt.Retry+1, err)
+	}
 }
 
 func (s *ShiftService) GetJob(ctx context.Context, jobID string) (*model.GenerationJob, error) {
 	return s.jobRepo.GetByID(ctx, jobID)
 }
 
+// ListAttempts returns the attempt history of a job, or nil when the job
+// doesn't exist
+func (s *ShiftService) ListAttempts(ctx context.Context, jobID string) ([]model.GenerationAttempt, error) {
+	job, err := s.jobRepo.GetByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, nil
+	}
+	attempts, err := s.attemptRepo.ListByJobID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if attempts == nil {
+		attempts = []model.GenerationAttempt{}
+	}
+	return attempts, nil
+}
+
 func (s *ShiftService) ListPatterns(ctx context.Context, yearMonth string) ([]model.PatternWithSummary, error) {
 	patterns, err := s.patternRepo.ListByYearMonth(ctx, yearMonth)
 	if err != nil {
@@ -235,7 +661,14 @@ func (s *ShiftService) GetPatternDetail(ctx context.Context, id string) (*model.
 	}, nil
 }
 
-func (s *ShiftService) SelectPattern(ctx context.Context, id string) (*model.ShiftPattern, error) {
+// SelectPattern makes a pattern the selected one of its month; the pattern
+// selected before goes back to draft. It returns nil when the pattern
+// doesn't exist.
+func (s *ShiftService) SelectPattern(ctx context.Context, id string, req model.PatternTransitionRequest) (*model.ShiftPattern, error) {
+	req, err := trimTransitionRequest(req)
+	if err != nil {
+		return nil, err
+	}
 	pattern, err := s.patternRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -243,41 +676,162 @@ func (s *ShiftService) SelectPattern(ctx context.Context, id string) (*model.Shi
 	if pattern == nil {
 		return nil, nil
 	}
-
-	// Reset other patterns and set this one as selected
-	if err := s.patternRepo.ResetOtherPatterns(ctx, id, pattern.YearMonth); err != nil {
-		return nil, err
+	if pattern.Status == model.PatternStatusSelected {
+		return pattern, nil
 	}
-	if err := s.patternRepo.UpdateStatus(ctx, id, "selected"); err != nil {
+	if pattern.Status != model.PatternStatusDraft {
+		return nil, transitionError(pattern.Status, model.PatternStatusSelected)
+	}
+	if err := s.checkSelectable(ctx, pattern); err != nil {
 		return nil, err
 	}
 
-	pattern.Status = "selected"
-	return pattern, nil
+	ok, err := s.patternRepo.Select(ctx, id, pattern.Status, pattern.YearMonth, req.ChangedBy, req.Reason)
+	if errors.Is(err, repository.ErrMonthLocked) {
+		return nil, monthFinalizedError(pattern.YearMonth)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, statusChangedError()
+	}
+	return s.patternRepo.GetByID(ctx, id)
 }
 
-func (s *ShiftService) FinalizePattern(ctx context.Context, id string) (*model.ShiftPattern, error) {
+// Limits of who changed the status of a pattern and why
+const (
+	maxActorLength  = 100
+	maxReasonLength = 500
+)
+
+// FinalizePattern fixes the selected pattern of a month as its schedule. The
+// pattern is re-validated first, and hard violations block it unless the
+// request gives a reason to override them. It returns nil when the pattern
+// doesn't exist.
+func (s *ShiftService) FinalizePattern(ctx context.Context, id string, req model.FinalizePatternRequest) (*model.ShiftPattern, *model.PatternFinalization, error) {
+	req.FinalizedBy = strings.TrimSpace(req.FinalizedBy)
+	req.OverrideReason = strings.TrimSpace(req.OverrideReason)
+	if err := validateFinalizeRequest(req); err != nil {
+		return nil, nil, err
+	}
+
 	pattern, err := s.patternRepo.GetByID(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if pattern == nil {
-		return nil, nil
+		return nil, nil, nil
+	}
+	if pattern.Status != model.PatternStatusSelected {
+		return nil, nil, transitionError(pattern.Status, model.PatternStatusFinalized)
+	}
+	if err := s.checkSelectable(ctx, pattern); err != nil {
+		return nil, nil, err
 	}
 
-	if err := s.patternRepo.UpdateStatus(ctx, id, "finalized"); err != nil {
-		return nil, err
+	// Finalize what the entries are now, manual edits included
+	result, err := s.revalidatePattern(ctx, pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	hard := hardViolations(result.Violations)
+	if len(hard) > 0 && req.OverrideReason == "" {
+		return nil, nil, &HardViolationsError{Violations: hard}
 	}
 
-	pattern.Status = "finalized"
-	return pattern, nil
+	finalization, err := s.patternRepo.Finalize(ctx, id, req.FinalizedBy, req.OverrideReason, hard)
+	if errors.Is(err, repository.ErrMonthLocked) {
+		// Another pattern of the month was finalized since LockedID
+		return nil, nil, monthFinalizedError(pattern.YearMonth)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if finalization == nil {
+		return nil, nil, statusChangedError()
+	}
+	pattern, err = s.patternRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pattern, finalization, nil
 }
 
-func (s *ShiftService) CreateEntry(ctx context.Context, req model.CreateShiftEntryRequest) (*model.ShiftEntry, error) {
-	return s.entryRepo.Create(ctx, req)
+// checkSelectable refuses to select or finalize a pattern while its month
+// has a finalized or published pattern, or while a regeneration job is about
+// to replace its entries
+func (s *ShiftService) checkSelectable(ctx context.Context, pattern *model.ShiftPattern) error {
+	lockedID, err := s.patternRepo.LockedID(ctx, pattern.YearMonth)
+	if err != nil {
+		return err
+	}
+	if lockedID != "" {
+		return monthFinalizedError(pattern.YearMonth)
+	}
+	regenerating, err := s.jobRepo.HasActiveRegeneration(ctx, pattern.ID)
+	if err != nil {
+		return err
+	}
+	if regenerating {
+		return regeneratingError()
+	}
+	return nil
+}
+
+// validateFinalizeRequest checks a trimmed FinalizePatternRequest
+func validateFinalizeRequest(req model.FinalizePatternRequest) error {
+	if utf8.RuneCountInString(req.FinalizedBy) > maxActorLength {
+		return fieldError("finalized_by", fmt.Sprintf("finalized_by は%d文字以内で指定してください", maxActorLength))
+	}
+	if utf8.RuneCountInString(req.OverrideReason) > maxReasonLength {
+		return fieldError("override_reason", fmt.Sprintf("override_reason は%d文字以内で指定してください", maxReasonLength))
+	}
+	if req.OverrideReason != "" && req.FinalizedBy == "" {
+		return fieldError("finalized_by", "override_reason を指定する場合は finalized_by（確定する人）を指定してください")
+	}
+	return nil
 }
 
+// hardViolations returns the hard violations of a validation
+func hardViolations(violations []model.Violation) []model.Violation {
+	var hard []model.Violation
+	for _, v := range violations {
+		if v.Type == "hard" {
+			hard = append(hard, v)
+		}
+	}
+	return hard
+}
+
+// CreateEntry adds an entry to a pattern and re-validates the pattern
+func (s *ShiftService) CreateEntry(ctx context.Context, req model.CreateShiftEntryRequest) (*model.ShiftEntry, *model.EntryValidation, error) {
+	if err := s.checkEditable(ctx, req.PatternID); err != nil {
+		return nil, nil, err
+	}
+	entry, err := s.entryRepo.Create(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	validation, err := s.revalidateEntry(ctx, entry.PatternID, entry.StaffID, entry.Date)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entry, validation, nil
+}
+
+// UpdateEntry changes the times of an entry and re-validates its pattern
 func (s *ShiftService) UpdateEntry(ctx context.Context, id string, req model.UpdateShiftEntryRequest) (*model.ShiftEntry, *model.EntryValidation, error) {
+	current, err := s.entryRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if current == nil {
+		return nil, nil, nil
+	}
+	if err := s.checkEditable(ctx, current.PatternID); err != nil {
+		return nil, nil, err
+	}
 	entry, err := s.entryRepo.Update(ctx, id, req)
 	if err != nil {
 		return nil, nil, err
@@ -285,17 +839,30 @@ func (s *ShiftService) UpdateEntry(ctx context.Context, id string, req model.Upd
 	if entry == nil {
 		return nil, nil, nil
 	}
-
-	validation := &model.EntryValidation{
-		IsValid:  true,
-		Warnings: []model.ValidationWarning{},
+	validation, err := s.revalidateEntry(ctx, entry.PatternID, entry.StaffID, entry.Date)
+	if err != nil {
+		return nil, nil, err
 	}
-
 	return entry, validation, nil
 }
 
-func (s *ShiftService) DeleteEntry(ctx context.Context, id string) error {
-	return s.entryRepo.Delete(ctx, id)
+// DeleteEntry removes an entry and re-validates its pattern. It returns nil
+// when the entry doesn't exist.
+func (s *ShiftService) DeleteEntry(ctx context.Context, id string) (*model.EntryValidation, error) {
+	entry, err := s.entryRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	if err := s.checkEditable(ctx, entry.PatternID); err != nil {
+		return nil, err
+	}
+	if err := s.entryRepo.Delete(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.revalidateEntry(ctx, entry.PatternID, entry.StaffID, entry.Date)
 }
 
 func (s *ShiftService) computeSummary(ctx context.Context, patternID string) (*model.PatternSummary, error) {
@@ -321,14 +888,6 @@ func (s *ShiftService) computeSummary(ctx context.Context, patternID string) (*m
 }
 
 func computeWorkHours(startTime, endTime string, breakMinutes int) float64 {
-	// Parse HH:MM format
-	var sh, sm, eh, em int
-	fmt.Sscanf(startTime, "%d:%d", &sh, &sm)
-	fmt.Sscanf(endTime, "%d:%d", &eh, &em)
-
-	totalMinutes := (eh*60 + em) - (sh*60 + sm) - breakMinutes
-	if totalMinutes < 0 {
-		totalMinutes = 0
-	}
-	return float64(totalMinutes) / 60.0
+	// end_time <= start_time is an overnight shift ending the next day
+	return float64(model.WorkMinutes(startTime, endTime, breakMinutes)) / 60.0
 }
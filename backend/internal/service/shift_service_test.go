@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"shift-app/internal/model"
@@ -57,6 +59,13 @@ func TestComputeWorkHours(t *testing.T) {
 			breakMinutes: 45,
 			want:         7.25,
 		},
+		{
+			name:         "overnight shift ending next day",
+			startTime:    "22:00",
+			endTime:      "06:00",
+			breakMinutes: 60,
+			want:         7.0,
+		},
 	}
 
 	for _, tt := range tests {
@@ -93,7 +102,7 @@ func TestShiftService_StartGeneration_DefaultPatternCount(t *testing.T) {
 	// We can only verify this doesn't panic; the actual DB call will fail
 	// This test verifies the validation path doesn't error on patternCount=0
 	// (it should default to 3 and proceed to DB check which will panic on nil)
-	svc := &ShiftService{}
+	svc := &ShiftService{generators: map[string]ShiftGenerator{model.GeneratorLLM: nil}}
 	ctx := context.Background()
 
 	req := model.GenerateShiftRequest{
@@ -112,3 +121,162 @@ func TestShiftService_StartGeneration_DefaultPatternCount(t *testing.T) {
 
 	_, _ = svc.StartGeneration(ctx, req)
 }
+
+func TestShiftService_StartGeneration_UnknownGenerator(t *testing.T) {
+	svc := &ShiftService{generators: map[string]ShiftGenerator{model.GeneratorLLM: nil, model.GeneratorLocal: nil}}
+	ctx := context.Background()
+
+	req := model.GenerateShiftRequest{
+		YearMonth:    "2025-01",
+		PatternCount: 3,
+		Generator:    "genetic",
+	}
+
+	_, err := svc.StartGeneration(ctx, req)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if err.Error() != "generator は llm, local, hybrid のいずれかで指定してください" {
+		t.Errorf("error = %q", err.Error())
+	}
+}
+
+func TestValidateGuidance(t *testing.T) {
+	tests := []struct {
+		name     string
+		guidance model.GenerationGuidance
+		wantErr  string
+	}{
+		{"empty", model.GenerationGuidance{}, ""},
+		{"full", model.GenerationGuidance{
+			Instruction: "佐藤さんの土日出勤を増やしてください",
+			Strategies:  []string{model.StrategyWeekendHeavy, "", model.StrategyCostMinimizing},
+			Weights:     &model.ObjectiveWeights{Fairness: 1, Cost: 0, Preference: 2},
+		}, ""},
+		{"long instruction", model.GenerationGuidance{Instruction: strings.Repeat("あ", 501)}, "instruction は500文字以内で指定してください"},
+		{"too many strategies", model.GenerationGuidance{Strategies: []string{"", "", "", ""}}, "strategies はパターン数（3）以下の件数で指定してください"},
+		{"unknown strategy", model.GenerationGuidance{Strategies: []string{"random"}}, "strategies には even_distribution, weekend_heavy, cost_minimizing, preference_first, fixed_rotation のいずれかを指定してください"},
+		{"negative weight", model.GenerationGuidance{Weights: &model.ObjectiveWeights{Fairness: -1, Cost: 1}}, "weights には0以上の値を指定してください"},
+		{"zero weights", model.GenerationGuidance{Weights: &model.ObjectiveWeights{}}, "weights のいずれかに正の値を指定してください"},
+		{"negative hours_fit", model.GenerationGuidance{Weights: &model.ObjectiveWeights{HoursFit: -50, Preference: 60}}, "weights には0以上の値を指定してください"},
+		{"negative soft_constraints", model.GenerationGuidance{Weights: &model.ObjectiveWeights{SoftConstraints: -1, Cost: 1}}, "weights には0以上の値を指定してください"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGuidance(&tt.guidance, 3)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStrategyFor(t *testing.T) {
+	tests := []struct {
+		name string
+		job  model.GenerationJob
+		i    int
+		want string
+	}{
+		{"sequential default", model.GenerationJob{}, 1, ""},
+		{"parallel default", model.GenerationJob{Parallel: true}, 1, model.PatternStrategies[1]},
+		{"requested", model.GenerationJob{GenerationGuidance: model.GenerationGuidance{Strategies: []string{model.StrategyFixedRotation}}}, 0, model.StrategyFixedRotation},
+		{"blank falls back", model.GenerationJob{Parallel: true, GenerationGuidance: model.GenerationGuidance{Strategies: []string{"", ""}}}, 1, model.PatternStrategies[1]},
+		{"beyond requested", model.GenerationJob{GenerationGuidance: model.GenerationGuidance{Strategies: []string{model.StrategyFixedRotation}}}, 2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strategyFor(&tt.job, tt.i); got != tt.want {
+				t.Errorf("strategyFor(%d) = %q, want %q", tt.i, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFinalizeRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     model.FinalizePatternRequest
+		wantErr string
+	}{
+		{"empty", model.FinalizePatternRequest{}, ""},
+		{"override with name", model.FinalizePatternRequest{FinalizedBy: "店長", OverrideReason: "欠員のため了承済み"}, ""},
+		{"override without name", model.FinalizePatternRequest{OverrideReason: "欠員のため了承済み"}, "override_reason を指定する場合は finalized_by（確定する人）を指定してください"},
+		{"long name", model.FinalizePatternRequest{FinalizedBy: strings.Repeat("あ", 101)}, "finalized_by は100文字以内で指定してください"},
+		{"long reason", model.FinalizePatternRequest{FinalizedBy: "店長", OverrideReason: strings.Repeat("あ", 501)}, "override_reason は500文字以内で指定してください"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateFinalizeRequest(tt.req)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) || err.Error() != tt.wantErr {
+				t.Errorf("error = %v, want ValidationError %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHardViolations(t *testing.T) {
+	violations := []model.Violation{
+		{Type: "soft", Constraint: "最低人数", Message: "soft"},
+		{Type: "hard", Constraint: "出勤不可日チェック", Message: "hard 1"},
+		{Type: "hard", Constraint: "重複チェック", Message: "hard 2"},
+	}
+	got := hardViolations(violations)
+	if len(got) != 2 || got[0].Message != "hard 1" || got[1].Message != "hard 2" {
+		t.Errorf("hardViolations = %+v, want the two hard violations", got)
+	}
+	if hardViolations(violations[:1]) != nil {
+		t.Error("hardViolations of soft violations should be nil")
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *StatusError
+		wantCode string
+		wantMsg  string
+	}{
+		{"transition", transitionError(model.PatternStatusDraft, model.PatternStatusFinalized), CodeInvalidTransition, "下書きのパターンは確定済みにできません"},
+		{"locked", lockedError(model.PatternStatusFinalized), CodePatternLocked, "確定済みのパターンは編集できません。確定を取り消すか修正を開始してから編集してください"},
+		{"archived", lockedError(model.PatternStatusArchived), CodePatternLocked, "アーカイブ済みのパターンは編集できません"},
+		{"month finalized", monthFinalizedError("2025-02"), CodeMonthFinalized, "2025-02にはすでに確定済みのパターンがあります。確定を取り消すか修正を開始してから変更してください"},
+		{"regenerating", regeneratingError(), CodeRegenerating, "このパターンは再生成中です。再生成が終わるかキャンセルしてから変更してください"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.Code != tt.wantCode || tt.err.Error() != tt.wantMsg {
+				t.Errorf("error = %s %q, want %s %q", tt.err.Code, tt.err.Error(), tt.wantCode, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestTrimTransitionRequest(t *testing.T) {
+	req, err := trimTransitionRequest(model.PatternTransitionRequest{ChangedBy: " 店長 ", Reason: " 欠員の差し替え "})
+	if err != nil || req.ChangedBy != "店長" || req.Reason != "欠員の差し替え" {
+		t.Errorf("trimTransitionRequest = %+v, %v", req, err)
+	}
+	_, err = trimTransitionRequest(model.PatternTransitionRequest{Reason: strings.Repeat("あ", 501)})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || err.Error() != "reason は500文字以内で指定してください" {
+		t.Errorf("error = %v, want the reason length error", err)
+	}
+}
@@ -0,0 +1,141 @@
+package solver
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"shift-app/internal/model"
+)
+
+// Repair mechanically fixes the hard violations of a generated response
+// instead of asking the LLM again. It is a no-op when violations has no hard
+// violation.
+func (s *Solver) Repair(ctx context.Context, yearMonth string, response *model.LLMResponse, violations []model.Violation) (*model.RepairResult, error) {
+	hasHard := false
+	for _, v := range violations {
+		if v.Type == "hard" {
+			hasHard = true
+			break
+		}
+	}
+	if !hasHard {
+		return &model.RepairResult{Response: response, Actions: []model.RepairAction{}}, nil
+	}
+
+	p, err := s.loadProblem(ctx, yearMonth)
+	if err != nil {
+		return nil, err
+	}
+	return Repair(p, response, 0), nil
+}
+
+// Repair rebuilds a response under the hard rules only. Entries are kept in
+// date order while they fit: entries on closed, unavailable or fixed days off
+// and entries that would break max staff, monthly max hours, consecutive
+// days or rest hours are removed (the latter two in effect inserting rest
+// days), and entries outside business hours are trimmed to them. Days below
+// min_staff are then topped up with eligible staff. Added and trimmed entries
+// are flagged AutoRepaired. Staff compatibility and coverage are not repaired.
+func Repair(p *Problem, response *model.LLMResponse, seed int64) *model.RepairResult {
+	r := buildRules(p, true)
+	st := newState(p, r)
+	actions := []model.RepairAction{}
+	remove := func(e model.LLMShiftEntry, reason string) {
+		actions = append(actions, model.RepairAction{Action: "removed", StaffID: e.StaffID, Date: e.Date, Reason: reason})
+	}
+
+	dayIdx := make(map[string]int, len(p.Days))
+	for i, day := range p.Days {
+		dayIdx[day.Date] = i
+	}
+
+	entries := make([]model.LLMShiftEntry, len(response.Entries))
+	copy(entries, response.Entries)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date < entries[j].Date })
+
+	for _, e := range entries {
+		di, ok := dayIdx[e.Date]
+		if !ok {
+			remove(e, "対象月の範囲外のため")
+			continue
+		}
+		if _, ok := st.assigned[e.StaffID]; !ok {
+			remove(e, "在籍スタッフではないため")
+			continue
+		}
+		day := p.Days[di]
+		if day.IsClosed {
+			remove(e, "休業日のため")
+			continue
+		}
+		fitted, ok := fitBusinessHours(e, day)
+		if !ok {
+			remove(e, "勤務時間が営業時間と重ならないため")
+			continue
+		}
+		if reason := st.rejectReason(e.StaffID, di, fitted); reason != "" {
+			remove(e, reason)
+			continue
+		}
+		if fitted != e {
+			fitted.AutoRepaired = true
+			actions = append(actions, model.RepairAction{Action: "adjusted", StaffID: e.StaffID, Date: e.Date, Reason: "営業時間内に収めるため"})
+		}
+		st.assign(e.StaffID, di, fitted)
+	}
+
+	if r.minStaff > 0 {
+		rng := newRand(seed)
+		for di, day := range p.Days {
+			if day.IsClosed {
+				continue
+			}
+			templates := shiftTemplates(day)
+			for k := st.counts[di]; k < r.minStaff; k++ {
+				staffID, entry, ok := st.pick(di, templates[k%len(templates)], rng)
+				if !ok {
+					break
+				}
+				entry.AutoRepaired = true
+				st.assign(staffID, di, entry)
+				actions = append(actions, model.RepairAction{Action: "added", StaffID: staffID, Date: day.Date, Reason: "最低人数を満たすため"})
+			}
+		}
+	}
+
+	reasoning := response.Reasoning
+	if len(actions) > 0 {
+		reasoning = strings.TrimSpace(reasoning + "\n（ハード制約違反をローカルで自動修正しました）")
+	}
+	return &model.RepairResult{
+		Response: &model.LLMResponse{
+			Reasoning:            reasoning,
+			Entries:              st.entries(),
+			ConstraintViolations: response.ConstraintViolations,
+		},
+		Actions: actions,
+	}
+}
+
+// fitBusinessHours trims an entry to the day's business hours. It reports
+// false when the entry has invalid times or doesn't overlap the hours at all.
+func fitBusinessHours(e model.LLMShiftEntry, day model.BusinessDay) (model.LLMShiftEntry, bool) {
+	if model.ClockMinutes(e.StartTime) < 0 || model.ClockMinutes(e.EndTime) < 0 || model.ClockMinutes(e.StartTime) == model.ClockMinutes(e.EndTime) {
+		return e, false
+	}
+	open, closeAt := model.ShiftSpan(day.OpenTime, day.CloseTime)
+	start, end := model.ShiftSpan(e.StartTime, e.EndTime)
+	if start >= open && end <= closeAt {
+		return e, true
+	}
+	start, end = max(start, open), min(end, closeAt)
+	if end <= start {
+		return e, false
+	}
+	e.StartTime, e.EndTime = model.FormatClock(start), model.FormatClock(end)
+	if e.BreakMinutes >= end-start {
+		e.BreakMinutes = breakMinutesFor(end - start)
+	}
+	return e, true
+}
@@ -0,0 +1,93 @@
+package solver
+
+import (
+	"testing"
+
+	"shift-app/internal/model"
+)
+
+func TestRepair(t *testing.T) {
+	p := testProblem(
+		constraint("min_staff", map[string]int{"min_count": 1}),
+		constraint("max_staff", map[string]int{"max_count": 2}),
+		constraint("max_consecutive_days", map[string]int{"max_days": 2}),
+	)
+	// Only one day matters here; other days are topped up to min_staff
+	draft := &model.LLMResponse{
+		Reasoning: "LLMの案",
+		Entries: []model.LLMShiftEntry{
+			// s1 is unavailable on 02-03
+			{StaffID: "s1", Date: "2025-02-03", StartTime: "09:00", EndTime: "17:00", BreakMinutes: 60},
+			// 3 staff on 02-04 with max_staff 2
+			{StaffID: "s2", Date: "2025-02-04", StartTime: "09:00", EndTime: "17:00", BreakMinutes: 60},
+			{StaffID: "s3", Date: "2025-02-04", StartTime: "09:00", EndTime: "17:00", BreakMinutes: 60},
+			{StaffID: "s4", Date: "2025-02-04", StartTime: "09:00", EndTime: "17:00", BreakMinutes: 60},
+			// s2 works 3 days in a row with max 2
+			{StaffID: "s2", Date: "2025-02-05", StartTime: "09:00", EndTime: "17:00", BreakMinutes: 60},
+			{StaffID: "s2", Date: "2025-02-06", StartTime: "09:00", EndTime: "17:00", BreakMinutes: 60},
+			// outside default business hours (09:00-22:00)
+			{StaffID: "s3", Date: "2025-02-07", StartTime: "07:00", EndTime: "15:00", BreakMinutes: 60},
+		},
+	}
+
+	result := Repair(p, draft, 1)
+
+	type key struct{ staffID, date string }
+	got := make(map[key]model.LLMShiftEntry)
+	perDay := make(map[string]int)
+	for _, e := range result.Response.Entries {
+		got[key{e.StaffID, e.Date}] = e
+		perDay[e.Date]++
+	}
+
+	if _, ok := got[key{"s1", "2025-02-03"}]; ok {
+		t.Error("entry on unavailable date was kept")
+	}
+	if e, ok := got[key{"s2", "2025-02-04"}]; !ok || e.AutoRepaired {
+		t.Errorf("valid entry should be kept untouched: %+v", e)
+	}
+	if n := perDay["2025-02-04"]; n != 2 {
+		t.Errorf("2025-02-04 has %d staff, want 2", n)
+	}
+	if _, ok := got[key{"s2", "2025-02-06"}]; ok {
+		t.Error("third consecutive day was kept")
+	}
+	if e := got[key{"s3", "2025-02-07"}]; e.StartTime != "09:00" || !e.AutoRepaired {
+		t.Errorf("entry before opening should be trimmed: %+v", e)
+	}
+	for _, day := range p.Days {
+		if perDay[day.Date] < 1 {
+			t.Errorf("%s below min_staff", day.Date)
+		}
+	}
+
+	actions := make(map[string]int)
+	for _, a := range result.Actions {
+		actions[a.Action]++
+	}
+	if actions["removed"] != 3 || actions["adjusted"] != 1 || actions["added"] == 0 {
+		t.Errorf("unexpected actions: %v", actions)
+	}
+	for _, e := range result.Response.Entries {
+		if e.Date == "2025-02-01" && !e.AutoRepaired {
+			t.Errorf("entry added for min_staff should be flagged: %+v", e)
+		}
+	}
+}
+
+func TestRepair_IgnoresSoftConstraints(t *testing.T) {
+	soft := constraint("max_staff", map[string]int{"max_count": 1})
+	soft.Type = "soft"
+	p := testProblem(soft)
+	draft := &model.LLMResponse{
+		Entries: []model.LLMShiftEntry{
+			{StaffID: "s2", Date: "2025-02-04", StartTime: "09:00", EndTime: "17:00", BreakMinutes: 60},
+			{StaffID: "s3", Date: "2025-02-04", StartTime: "09:00", EndTime: "17:00", BreakMinutes: 60},
+		},
+	}
+
+	result := Repair(p, draft, 1)
+	if len(result.Response.Entries) != 2 || len(result.Actions) != 0 {
+		t.Errorf("soft constraint should not be repaired: entries=%d actions=%+v", len(result.Response.Entries), result.Actions)
+	}
+}
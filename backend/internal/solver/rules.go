@@ -0,0 +1,203 @@
+package solver
+
+import (
+	"math"
+	"time"
+
+	"shift-app/internal/model"
+)
+
+// hourLimit is a staff's monthly hour range; max < 0 means no upper limit
+type hourLimit struct {
+	min float64
+	max float64
+}
+
+// rules is the Problem flattened into what the solver checks per assignment.
+// Configs that fail to decode are ignored, as the validator reports them.
+type rules struct {
+	minStaff       int // 0 = not set
+	maxStaff       int // 0 = unlimited
+	maxConsecutive int // 0 = unlimited
+	minRestMinutes int
+	hours          map[string]hourLimit
+	daysOff        map[string]map[string]bool   // staffID -> date
+	preferred      map[string]map[string]bool   // staffID -> date
+	windows        map[string]map[string][2]int // staffID -> date -> requested span
+}
+
+// buildRules applies hard and soft constraints alike when building a new
+// schedule. With hardOnly, soft constraints and monthly preferred hours are
+// left out so that repair only touches what makes a pattern infeasible.
+func buildRules(p *Problem, hardOnly bool) *rules {
+	r := &rules{
+		hours:     make(map[string]hourLimit),
+		daysOff:   make(map[string]map[string]bool),
+		preferred: make(map[string]map[string]bool),
+		windows:   make(map[string]map[string][2]int),
+	}
+	for _, s := range p.Staffs {
+		r.hours[s.ID] = hourLimit{max: -1}
+		r.daysOff[s.ID] = make(map[string]bool)
+		r.preferred[s.ID] = make(map[string]bool)
+		r.windows[s.ID] = make(map[string][2]int)
+	}
+
+	for _, req := range p.Requests {
+		if _, ok := r.daysOff[req.StaffID]; !ok {
+			continue
+		}
+		switch req.RequestType {
+		case "unavailable":
+			r.daysOff[req.StaffID][req.Date] = true
+		case "preferred", "available":
+			if req.RequestType == "preferred" {
+				r.preferred[req.StaffID][req.Date] = true
+			}
+			if req.StartTime != nil && req.EndTime != nil && model.ClockMinutes(*req.StartTime) >= 0 && model.ClockMinutes(*req.EndTime) >= 0 {
+				start, end := model.ShiftSpan(*req.StartTime, *req.EndTime)
+				r.windows[req.StaffID][req.Date] = [2]int{start, end}
+			}
+		}
+	}
+
+	employment := make(map[string]string)
+	for _, s := range p.Staffs {
+		employment[s.ID] = s.EmploymentType
+	}
+
+	for _, c := range p.Constraints {
+		if hardOnly && c.Type != "hard" {
+			continue
+		}
+		typed, details := model.DecodeConstraintConfig(c.Category, c.Config)
+		if len(details) > 0 {
+			continue
+		}
+		switch cfg := typed.(type) {
+		case *model.MinStaffConfig:
+			if cfg.MinCount != nil && *cfg.MinCount > r.minStaff {
+				r.minStaff = *cfg.MinCount
+			}
+		case *model.MaxStaffConfig:
+			if r.maxStaff == 0 || *cfg.MaxCount < r.maxStaff {
+				r.maxStaff = *cfg.MaxCount
+			}
+		case *model.MaxConsecutiveDaysConfig:
+			if r.maxConsecutive == 0 || *cfg.MaxDays < r.maxConsecutive {
+				r.maxConsecutive = *cfg.MaxDays
+			}
+		case *model.RestHoursConfig:
+			r.minRestMinutes = max(r.minRestMinutes, cfg.MinRestMinutes())
+		case *model.FixedDayOffConfig:
+			r.addFixedDayOff(p.Days, cfg)
+		case *model.MonthlyHoursConfig:
+			for id, limit := range r.hours {
+				r.hours[id] = limit.tighten(resolveHourLimit(cfg, id, employment[id]))
+			}
+		}
+	}
+
+	if !hardOnly {
+		for _, s := range p.Settings {
+			if limit, ok := r.hours[s.StaffID]; ok {
+				setting := model.HourLimitConfig{}
+				minHours, maxHours := float64(s.MinPreferredHours), float64(s.MaxPreferredHours)
+				if minHours > 0 {
+					setting.MinHours = &minHours
+				}
+				if maxHours > 0 {
+					setting.MaxHours = &maxHours
+				}
+				r.hours[s.StaffID] = limit.tighten(setting)
+			}
+		}
+	}
+	return r
+}
+
+func (r *rules) addFixedDayOff(days []model.BusinessDay, cfg *model.FixedDayOffConfig) {
+	off, ok := r.daysOff[cfg.StaffID]
+	if !ok {
+		return
+	}
+	weekdays := make(map[time.Weekday]bool)
+	for _, wd := range cfg.Weekdays {
+		weekdays[time.Weekday(wd)] = true
+	}
+	if cfg.DayOfWeek != nil {
+		weekdays[time.Weekday(*cfg.DayOfWeek)] = true
+	}
+	except := make(map[string]bool)
+	for _, d := range cfg.ExceptDates {
+		except[d] = true
+	}
+	for _, day := range days {
+		if weekdays[time.Weekday(day.Weekday)] && !except[day.Date] {
+			off[day.Date] = true
+		}
+	}
+}
+
+// resolveHourLimit picks by_staff > by_employment_type > global, field by field
+func resolveHourLimit(cfg *model.MonthlyHoursConfig, staffID, employmentType string) model.HourLimitConfig {
+	l := cfg.HourLimitConfig
+	for _, o := range []model.HourLimitConfig{cfg.ByEmploymentType[employmentType], cfg.ByStaff[staffID]} {
+		if o.MinHours != nil {
+			l.MinHours = o.MinHours
+		}
+		if o.MaxHours != nil {
+			l.MaxHours = o.MaxHours
+		}
+	}
+	return l
+}
+
+// tighten narrows the range with another limit: the larger minimum and the
+// smaller maximum win, and the minimum never exceeds the maximum
+func (l hourLimit) tighten(o model.HourLimitConfig) hourLimit {
+	if o.MinHours != nil && *o.MinHours > l.min {
+		l.min = *o.MinHours
+	}
+	if o.MaxHours != nil && (l.max < 0 || *o.MaxHours < l.max) {
+		l.max = *o.MaxHours
+	}
+	if l.max >= 0 && l.min > l.max {
+		l.min = l.max
+	}
+	return l
+}
+
+func (r *rules) underMax(count int) bool {
+	return r.maxStaff == 0 || count < r.maxStaff
+}
+
+// dailyTarget is how many staff pass 1 puts on each open day: enough to
+// reach everyone's monthly minimum, at least min_staff, at most max_staff
+func (r *rules) dailyTarget(p *Problem) int {
+	openDays, shiftHours := 0, 0.0
+	for _, day := range p.Days {
+		if day.IsClosed {
+			continue
+		}
+		openDays++
+		tmpl := shiftTemplates(day)[0]
+		shiftHours += float64(tmpl[1]-tmpl[0]-breakMinutesFor(tmpl[1]-tmpl[0])) / 60.0
+	}
+	totalMin := 0.0
+	for _, limit := range r.hours {
+		totalMin += limit.min
+	}
+
+	target := r.minStaff
+	if openDays > 0 && shiftHours > 0 && totalMin > 0 {
+		target = max(target, int(math.Ceil(totalMin/shiftHours)))
+	}
+	if target == 0 {
+		target = defaultDailyStaff
+	}
+	if r.maxStaff > 0 {
+		target = min(target, r.maxStaff)
+	}
+	return min(target, len(p.Staffs))
+}
@@ -0,0 +1,363 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"shift-app/internal/model"
+	"shift-app/internal/repository"
+)
+
+const (
+	// maxShiftMinutes is the longest single shift (including break) the
+	// solver creates from business hours
+	maxShiftMinutes = 9 * 60
+	// defaultDailyStaff is used when neither min_staff nor hour targets say
+	// how many staff a day needs (same default as the validator's min_staff)
+	defaultDailyStaff = 2
+	// patternSeedStride separates the random streams of each pattern
+	patternSeedStride = 1_000_003
+)
+
+// Solver is a heuristic shift generator that runs without network access.
+// Given the same data and seed it always returns the same entries.
+type Solver struct {
+	db *pgxpool.Pool
+}
+
+func NewSolver(db *pgxpool.Pool) *Solver {
+	return &Solver{db: db}
+}
+
+// Problem is everything the solver needs to build one month's shifts
+type Problem struct {
+	YearMonth   string
+	Days        []model.BusinessDay
+	Staffs      []model.Staff
+	Settings    []model.StaffMonthlySetting
+	Requests    []model.ShiftRequest
+	Constraints []model.Constraint
+	// Scope, when set, limits the solver to these staff and dates; Locked
+	// entries are kept as they are and not returned
+	Scope  *model.RegenerationScope
+	Locked []model.LLMShiftEntry
+}
+
+func (s *Solver) Generate(ctx context.Context, params model.GenerationParams) (*model.LLMResponse, error) {
+	p, err := s.loadProblem(ctx, params.YearMonth)
+	if err != nil {
+		return nil, err
+	}
+	p.Scope, p.Locked = params.Scope, params.LockedEntries
+	return Solve(p, params.Seed+int64(params.PatternIdx)*patternSeedStride), nil
+}
+
+func (s *Solver) loadProblem(ctx context.Context, yearMonth string) (*Problem, error) {
+	active := true
+	staffs, err := repository.NewStaffRepository(s.db).List(ctx, &active)
+	if err != nil {
+		return nil, fmt.Errorf("スタッフ取得エラー: %w", err)
+	}
+	settings, err := repository.NewStaffMonthlySettingRepository(s.db).List(ctx, yearMonth, nil)
+	if err != nil {
+		return nil, fmt.Errorf("月間設定取得エラー: %w", err)
+	}
+	requests, err := repository.NewShiftRequestRepository(s.db).List(ctx, yearMonth, nil)
+	if err != nil {
+		return nil, fmt.Errorf("シフト希望取得エラー: %w", err)
+	}
+	constraints, err := repository.NewConstraintRepository(s.db).List(ctx, &active, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("制約条件取得エラー: %w", err)
+	}
+	calendar, err := repository.NewBusinessCalendarRepository(s.db).GetCalendar(ctx, yearMonth)
+	if err != nil {
+		return nil, fmt.Errorf("営業カレンダー取得エラー: %w", err)
+	}
+	return &Problem{
+		YearMonth:   yearMonth,
+		Days:        calendar.Month(yearMonth),
+		Staffs:      staffs,
+		Settings:    settings,
+		Requests:    requests,
+		Constraints: constraints,
+	}, nil
+}
+
+// Solve assigns shifts day by day. Each day is filled up to a daily target
+// from the staff who are allowed to work it, preferring those furthest below
+// their monthly minimum; a second pass then adds shifts for staff still short
+// of their minimum. Unavailable dates, fixed days off, max staff, max
+// consecutive days, rest hours and monthly maximum hours are never broken;
+// the daily target and monthly minimums are best effort. With a scope only
+// in-scope shifts are added, around the locked entries.
+func Solve(p *Problem, seed int64) *model.LLMResponse {
+	r := buildRules(p, false)
+	st := newState(p, r)
+	rng := newRand(seed)
+	st.lock(p.Locked)
+
+	target := r.dailyTarget(p)
+	var shortDays []string
+
+	// Pass 1: fill each open day up to the target
+	for di, day := range p.Days {
+		if day.IsClosed || (p.Scope != nil && !p.Scope.ContainsDate(day.Date)) {
+			continue
+		}
+		templates := shiftTemplates(day)
+		for k := st.counts[di]; k < target; k++ {
+			staffID, entry, ok := st.pick(di, templates[k%len(templates)], rng)
+			if !ok {
+				shortDays = append(shortDays, day.Date)
+				break
+			}
+			st.assign(staffID, di, entry)
+		}
+	}
+
+	// Pass 2: top up staff still below their monthly minimum
+	for _, staff := range st.staffOrder(rng) {
+		limit := r.hours[staff.ID]
+		if limit.min <= 0 {
+			continue
+		}
+		for _, di := range rng.Perm(len(p.Days)) {
+			if st.hours[staff.ID] >= limit.min {
+				break
+			}
+			day := p.Days[di]
+			if day.IsClosed || !r.underMax(st.counts[di]) || !st.inScope(staff.ID, di) {
+				continue
+			}
+			templates := shiftTemplates(day)
+			entry := st.entryFor(staff.ID, di, templates[st.counts[di]%len(templates)])
+			if st.canAssign(staff.ID, di, entry) {
+				st.assign(staff.ID, di, entry)
+			}
+		}
+	}
+
+	reasoning := fmt.Sprintf("ローカルソルバーで生成しました（seed: %d）。1日%d人を目安に、出勤不可日・固定休・最大人数・連勤上限・勤務間インターバル・月間労働時間上限を守って割り当てています。", seed, target)
+	if len(shortDays) > 0 {
+		reasoning += fmt.Sprintf("人員が不足した日: %s", strings.Join(shortDays, ", "))
+	}
+	return &model.LLMResponse{
+		Reasoning:            reasoning,
+		Entries:              st.entries(),
+		ConstraintViolations: []model.ConstraintViolation{},
+	}
+}
+
+// shiftTemplates returns the shift(s) that cover a day's business hours:
+// one shift when the day fits in maxShiftMinutes, otherwise an opening and
+// a closing shift.
+func shiftTemplates(day model.BusinessDay) [][2]int {
+	open, closeAt := model.ShiftSpan(day.OpenTime, day.CloseTime)
+	if closeAt-open <= maxShiftMinutes {
+		return [][2]int{{open, closeAt}}
+	}
+	return [][2]int{{open, open + maxShiftMinutes}, {closeAt - maxShiftMinutes, closeAt}}
+}
+
+// breakMinutesFor gives 60 minutes of break to shifts of 6 hours or more
+func breakMinutesFor(spanMinutes int) int {
+	if spanMinutes >= 6*60 {
+		return 60
+	}
+	return 0
+}
+
+// state is the assignment being built
+type state struct {
+	p        *Problem
+	r        *rules
+	assigned map[string][]*model.LLMShiftEntry // staffID -> day index -> entry
+	counts   []int
+	hours    map[string]float64
+	locked   map[string]bool // staffID|date of locked entries
+}
+
+func newState(p *Problem, r *rules) *state {
+	st := &state{
+		p:        p,
+		r:        r,
+		assigned: make(map[string][]*model.LLMShiftEntry),
+		counts:   make([]int, len(p.Days)),
+		hours:    make(map[string]float64),
+		locked:   make(map[string]bool),
+	}
+	for _, s := range p.Staffs {
+		st.assigned[s.ID] = make([]*model.LLMShiftEntry, len(p.Days))
+	}
+	return st
+}
+
+// lock places entries that must stay as they are. They count toward daily
+// staff, hours and consecutive days like any other shift.
+func (st *state) lock(entries []model.LLMShiftEntry) {
+	dayIndex := make(map[string]int, len(st.p.Days))
+	for di, day := range st.p.Days {
+		dayIndex[day.Date] = di
+	}
+	for _, e := range entries {
+		di, ok := dayIndex[e.Date]
+		if !ok || st.assigned[e.StaffID] == nil || st.assigned[e.StaffID][di] != nil {
+			continue
+		}
+		st.assign(e.StaffID, di, e)
+		st.locked[e.StaffID+"|"+e.Date] = true
+	}
+}
+
+// inScope reports whether the solver may add a shift for a staff on day di
+func (st *state) inScope(staffID string, di int) bool {
+	return st.p.Scope == nil || st.p.Scope.Contains(staffID, st.p.Days[di].Date)
+}
+
+// entryFor builds the entry for a staff on a day, using the staff's
+// requested time window when it fits inside business hours
+func (st *state) entryFor(staffID string, di int, tmpl [2]int) model.LLMShiftEntry {
+	day := st.p.Days[di]
+	start, end := tmpl[0], tmpl[1]
+	if w, ok := st.r.windows[staffID][day.Date]; ok {
+		open, closeAt := model.ShiftSpan(day.OpenTime, day.CloseTime)
+		if w[0] >= open && w[1] <= closeAt {
+			start, end = w[0], w[1]
+		}
+	}
+	return model.LLMShiftEntry{
+		StaffID:      staffID,
+		Date:         day.Date,
+		StartTime:    model.FormatClock(start),
+		EndTime:      model.FormatClock(end),
+		BreakMinutes: breakMinutesFor(end - start),
+	}
+}
+
+// pick chooses the best eligible staff for a shift on day di
+func (st *state) pick(di int, tmpl [2]int, rng *rand.Rand) (string, model.LLMShiftEntry, bool) {
+	if !st.r.underMax(st.counts[di]) {
+		return "", model.LLMShiftEntry{}, false
+	}
+	date := st.p.Days[di].Date
+	bestScore := math.Inf(-1)
+	var bestID string
+	var bestEntry model.LLMShiftEntry
+	for _, staff := range st.p.Staffs {
+		// Draw for every staff so the stream doesn't depend on eligibility
+		jitter := rng.Float64() * 0.3
+		entry := st.entryFor(staff.ID, di, tmpl)
+		if !st.inScope(staff.ID, di) || !st.canAssign(staff.ID, di, entry) {
+			continue
+		}
+		score := jitter
+		if st.r.preferred[staff.ID][date] {
+			score += 2
+		}
+		if limit := st.r.hours[staff.ID]; limit.min > 0 {
+			score += (limit.min - st.hours[staff.ID]) / limit.min
+		} else {
+			score -= st.hours[staff.ID] / 160
+		}
+		if score > bestScore {
+			bestScore, bestID, bestEntry = score, staff.ID, entry
+		}
+	}
+	return bestID, bestEntry, bestID != ""
+}
+
+func (st *state) canAssign(staffID string, di int, e model.LLMShiftEntry) bool {
+	return st.rejectReason(staffID, di, e) == ""
+}
+
+// rejectReason returns why e can't be added to the assignment, or "" if it can
+func (st *state) rejectReason(staffID string, di int, e model.LLMShiftEntry) string {
+	days := st.assigned[staffID]
+	if days[di] != nil {
+		return "同日に複数のシフトがあるため"
+	}
+	if st.r.daysOff[staffID][e.Date] {
+		return "出勤不可日・固定休のため"
+	}
+	if !st.r.underMax(st.counts[di]) {
+		return "最大人数を超えるため"
+	}
+	if limit := st.r.hours[staffID]; limit.max >= 0 && st.hours[staffID]+workHours(e) > limit.max {
+		return "月間労働時間の上限を超えるため"
+	}
+	if st.r.maxConsecutive > 0 {
+		run := 1
+		for i := di - 1; i >= 0 && days[i] != nil; i-- {
+			run++
+		}
+		for i := di + 1; i < len(days) && days[i] != nil; i++ {
+			run++
+		}
+		if run > st.r.maxConsecutive {
+			return "連勤上限を超えるため"
+		}
+	}
+	if st.r.minRestMinutes > 0 {
+		start, end := model.ShiftSpan(e.StartTime, e.EndTime)
+		if di > 0 && days[di-1] != nil {
+			_, prevEnd := model.ShiftSpan(days[di-1].StartTime, days[di-1].EndTime)
+			if start+model.MinutesPerDay-prevEnd < st.r.minRestMinutes {
+				return "勤務間インターバルが不足するため"
+			}
+		}
+		if di+1 < len(days) && days[di+1] != nil {
+			nextStart := model.ClockMinutes(days[di+1].StartTime) + model.MinutesPerDay
+			if nextStart-end < st.r.minRestMinutes {
+				return "勤務間インターバルが不足するため"
+			}
+		}
+	}
+	return ""
+}
+
+func (st *state) assign(staffID string, di int, e model.LLMShiftEntry) {
+	st.assigned[staffID][di] = &e
+	st.counts[di]++
+	st.hours[staffID] += workHours(e)
+}
+
+// staffOrder returns staff sorted by how far they are below their minimum,
+// with random tie-breaking
+func (st *state) staffOrder(rng *rand.Rand) []model.Staff {
+	staffs := make([]model.Staff, len(st.p.Staffs))
+	copy(staffs, st.p.Staffs)
+	rng.Shuffle(len(staffs), func(i, j int) { staffs[i], staffs[j] = staffs[j], staffs[i] })
+	sort.SliceStable(staffs, func(i, j int) bool {
+		return st.r.hours[staffs[i].ID].min-st.hours[staffs[i].ID] > st.r.hours[staffs[j].ID].min-st.hours[staffs[j].ID]
+	})
+	return staffs
+}
+
+// entries returns all assignments except locked ones, ordered by date, then
+// staff
+func (st *state) entries() []model.LLMShiftEntry {
+	result := []model.LLMShiftEntry{}
+	for di := range st.p.Days {
+		for _, s := range st.p.Staffs {
+			if e := st.assigned[s.ID][di]; e != nil && !st.locked[s.ID+"|"+e.Date] {
+				result = append(result, *e)
+			}
+		}
+	}
+	return result
+}
+
+func newRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+func workHours(e model.LLMShiftEntry) float64 {
+	return float64(model.WorkMinutes(e.StartTime, e.EndTime, e.BreakMinutes)) / 60.0
+}
@@ -0,0 +1,184 @@
+package solver
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"shift-app/internal/model"
+)
+
+func testProblem(constraints ...model.Constraint) *Problem {
+	cal := model.NewBusinessCalendar(nil, nil)
+	staffs := []model.Staff{
+		{ID: "s1", Name: "田中", EmploymentType: "full_time"},
+		{ID: "s2", Name: "鈴木", EmploymentType: "part_time"},
+		{ID: "s3", Name: "佐藤", EmploymentType: "part_time"},
+		{ID: "s4", Name: "高橋", EmploymentType: "part_time"},
+	}
+	return &Problem{
+		YearMonth: "2025-02",
+		Days:      cal.Month("2025-02"),
+		Staffs:    staffs,
+		Settings: []model.StaffMonthlySetting{
+			{StaffID: "s1", MinPreferredHours: 120, MaxPreferredHours: 160},
+			{StaffID: "s2", MinPreferredHours: 40, MaxPreferredHours: 80},
+		},
+		Requests: []model.ShiftRequest{
+			{StaffID: "s1", Date: "2025-02-03", RequestType: "unavailable"},
+			{StaffID: "s2", Date: "2025-02-10", RequestType: "unavailable"},
+		},
+		Constraints: constraints,
+	}
+}
+
+func constraint(category string, config interface{}) model.Constraint {
+	raw, _ := json.Marshal(config)
+	return model.Constraint{Name: category, Type: "hard", Category: category, Config: raw, IsActive: true}
+}
+
+func TestSolve_Deterministic(t *testing.T) {
+	p := testProblem(constraint("min_staff", map[string]int{"min_count": 2}))
+
+	a := Solve(p, 42)
+	b := Solve(p, 42)
+	if !reflect.DeepEqual(a.Entries, b.Entries) {
+		t.Error("same seed produced different entries")
+	}
+
+	c := Solve(p, 43)
+	if reflect.DeepEqual(a.Entries, c.Entries) {
+		t.Error("different seeds produced identical entries")
+	}
+}
+
+func TestSolve_RespectsConstraints(t *testing.T) {
+	p := testProblem(
+		constraint("min_staff", map[string]int{"min_count": 2}),
+		constraint("max_staff", map[string]int{"max_count": 3}),
+		constraint("max_consecutive_days", map[string]int{"max_days": 4}),
+		constraint("rest_hours", map[string]float64{"min_hours": 11}),
+		constraint("fixed_day_off", map[string]interface{}{"staff_id": "s3", "weekdays": []int{0, 6}}),
+	)
+
+	result := Solve(p, 7)
+	if len(result.Entries) == 0 {
+		t.Fatal("expected entries")
+	}
+
+	byDate := make(map[string]int)
+	byStaff := make(map[string][]model.LLMShiftEntry)
+	hours := make(map[string]float64)
+	for _, e := range result.Entries {
+		byDate[e.Date]++
+		byStaff[e.StaffID] = append(byStaff[e.StaffID], e)
+		hours[e.StaffID] += workHours(e)
+
+		if e.StaffID == "s1" && e.Date == "2025-02-03" || e.StaffID == "s2" && e.Date == "2025-02-10" {
+			t.Errorf("assigned on unavailable date: %+v", e)
+		}
+		if e.StaffID == "s3" && (e.Date == "2025-02-01" || e.Date == "2025-02-02") {
+			t.Errorf("assigned on fixed day off: %+v", e)
+		}
+	}
+
+	for _, day := range p.Days {
+		if n := byDate[day.Date]; n < 2 || n > 3 {
+			t.Errorf("%s: %d staff, want 2..3", day.Date, n)
+		}
+	}
+	if hours["s1"] > 160 || hours["s2"] > 80 {
+		t.Errorf("monthly max exceeded: %v", hours)
+	}
+
+	for staffID, entries := range byStaff {
+		run := 1
+		for i := 1; i < len(entries); i++ {
+			prev, curr := entries[i-1], entries[i]
+			prevIdx, _ := model.DayIndex(prev.Date)
+			currIdx, _ := model.DayIndex(curr.Date)
+			if currIdx != prevIdx+1 {
+				run = 1
+				continue
+			}
+			run++
+			if run > 4 {
+				t.Errorf("%s: more than 4 consecutive days ending %s", staffID, curr.Date)
+			}
+			_, prevEnd := model.ShiftSpan(prev.StartTime, prev.EndTime)
+			if rest := model.ClockMinutes(curr.StartTime) + model.MinutesPerDay - prevEnd; rest < 11*60 {
+				t.Errorf("%s: rest %d min before %s", staffID, rest, curr.Date)
+			}
+		}
+	}
+}
+
+func TestSolve_SkipsClosedDays(t *testing.T) {
+	p := testProblem()
+	note := "定休日"
+	cal := model.NewBusinessCalendar(
+		[]model.BusinessHours{{Weekday: 2, IsClosed: true}},
+		[]model.BusinessSpecialDay{{Date: "2025-02-14", IsClosed: true, Note: &note}},
+	)
+	p.Days = cal.Month("2025-02")
+
+	for _, e := range Solve(p, 1).Entries {
+		if e.Date == "2025-02-04" || e.Date == "2025-02-14" {
+			t.Errorf("assigned on closed day: %+v", e)
+		}
+		if e.StartTime != "09:00" && e.EndTime != "22:00" {
+			t.Errorf("entry doesn't follow default business hours: %+v", e)
+		}
+	}
+}
+
+func TestSolve_Scope(t *testing.T) {
+	p := testProblem(
+		constraint("min_staff", map[string]int{"min_count": 2}),
+		constraint("max_staff", map[string]int{"max_count": 2}),
+	)
+	p.Scope = &model.RegenerationScope{StartDate: "2025-02-10", EndDate: "2025-02-16", StaffIDs: []string{"s2", "s3"}}
+	p.Locked = []model.LLMShiftEntry{
+		{StaffID: "s1", Date: "2025-02-10", StartTime: "09:00", EndTime: "18:00", BreakMinutes: 60},
+		{StaffID: "s4", Date: "2025-02-10", StartTime: "13:00", EndTime: "22:00", BreakMinutes: 60},
+		{StaffID: "s2", Date: "2025-02-11", StartTime: "10:00", EndTime: "15:00"},
+	}
+
+	entries := Solve(p, 1).Entries
+	if len(entries) == 0 {
+		t.Fatal("no entries in scope")
+	}
+	for _, e := range entries {
+		if !p.Scope.Contains(e.StaffID, e.Date) {
+			t.Errorf("entry out of scope: %+v", e)
+		}
+		// 02-10 is full with locked entries; s2 is locked on 02-11
+		if e.Date == "2025-02-10" || (e.StaffID == "s2" && e.Date == "2025-02-11") {
+			t.Errorf("entry overlaps a locked entry: %+v", e)
+		}
+	}
+}
+
+func TestHourLimitTighten(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name  string
+		start hourLimit
+		with  model.HourLimitConfig
+		want  hourLimit
+	}{
+		{"unset takes both", hourLimit{max: -1}, model.HourLimitConfig{MinHours: f(40), MaxHours: f(80)}, hourLimit{min: 40, max: 80}},
+		{"larger min wins", hourLimit{min: 60, max: 100}, model.HourLimitConfig{MinHours: f(40)}, hourLimit{min: 60, max: 100}},
+		{"smaller max wins", hourLimit{min: 0, max: 100}, model.HourLimitConfig{MaxHours: f(80)}, hourLimit{min: 0, max: 80}},
+		{"min clamped to max", hourLimit{min: 90, max: -1}, model.HourLimitConfig{MaxHours: f(60)}, hourLimit{min: 60, max: 60}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.start.tighten(tt.with); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"shift-app/internal/model"
+)
+
+// Points taken off for violations
+const (
+	// hardViolationPoints comes off the total for each hard violation
+	hardViolationPoints = 10.0
+	// softViolationPoints comes off the soft constraint component for each
+	// violation of a priority 0 soft constraint; each priority level adds as
+	// much again
+	softViolationPoints = 5.0
+)
+
+// Weights of the requests counted by the preference component
+const (
+	preferredRequestWeight = 2.0
+	availableRequestWeight = 1.0
+)
+
+// scoreInput is what a schedule is scored on
+type scoreInput struct {
+	entries  []model.LLMShiftEntry
+	settings map[string]monthlySetting
+	// requests maps "staffID:date" to a preferred or available request
+	requests map[string]workRequest
+	// priorities maps a constraint name to its priority
+	priorities map[string]int
+	violations []model.Violation
+	// hoursPenalty is the penalty of the monthly_hours constraints
+	hoursPenalty float64
+}
+
+// scoreSchedule computes the score components, weighs them and takes the
+// hard violations off
+func scoreSchedule(in scoreInput, weights model.ObjectiveWeights) *model.ScoreBreakdown {
+	hours := computeStaffHours(in.entries)
+	breakdown := &model.ScoreBreakdown{
+		Components: []model.ScoreComponent{
+			hoursFitComponent(hours, in.settings, in.hoursPenalty),
+			preferenceComponent(in.entries, in.requests),
+			softConstraintComponent(in.violations, in.priorities),
+			fairnessComponent(in.entries),
+			costComponent(hours, in.settings),
+		},
+	}
+
+	total := weights.Total()
+	for i := range breakdown.Components {
+		c := &breakdown.Components[i]
+		c.Score = round2(c.Score)
+		if total > 0 {
+			c.Weight = round2(weights.Of(c.Name) / total)
+			breakdown.WeightedScore += c.Score * weights.Of(c.Name) / total
+		}
+	}
+	breakdown.WeightedScore = round2(breakdown.WeightedScore)
+
+	for _, v := range in.violations {
+		if v.Type == "hard" {
+			breakdown.HardViolations++
+		}
+	}
+	breakdown.HardPenalty = math.Min(100, float64(breakdown.HardViolations)*hardViolationPoints)
+	breakdown.Total = round2(math.Max(0, breakdown.WeightedScore-breakdown.HardPenalty))
+	return breakdown
+}
+
+// hoursFitComponent averages how well each staff with monthly settings fits
+// their preferred range: 100 inside it, less by the share of the limit they
+// miss it by. The monthly_hours constraint penalty comes off the result.
+func hoursFitComponent(hours map[string]float64, settings map[string]monthlySetting, constraintPenalty float64) model.ScoreComponent {
+	c := model.ScoreComponent{Name: model.ScoreHoursFit, Label: "月間労働時間の希望", Score: 100}
+
+	staffIDs := make([]string, 0, len(settings))
+	for staffID := range settings {
+		staffIDs = append(staffIDs, staffID)
+	}
+	sort.Strings(staffIDs)
+
+	fitting := 0
+	if len(staffIDs) > 0 {
+		sum := 0.0
+		for _, staffID := range staffIDs {
+			s, h := settings[staffID], hours[staffID]
+			fit := 100.0
+			if h > float64(s.MaxHours) && s.MaxHours > 0 {
+				fit = math.Max(0, 100*(1-(h-float64(s.MaxHours))/float64(s.MaxHours)))
+			} else if h < float64(s.MinHours) && s.MinHours > 0 {
+				fit = math.Max(0, 100*(1-(float64(s.MinHours)-h)/float64(s.MinHours)))
+			}
+			sum += fit
+			if fit == 100 {
+				fitting++
+				continue
+			}
+			c.Deductions = append(c.Deductions, model.ScoreDeduction{
+				Reason:  fmt.Sprintf("月間労働時間 %.1fh（希望 %d〜%dh）", h, s.MinHours, s.MaxHours),
+				StaffID: staffID,
+				Points:  round2((100 - fit) / float64(len(staffIDs))),
+			})
+		}
+		c.Score = sum / float64(len(staffIDs))
+	}
+	if constraintPenalty > 0 {
+		c.Score = math.Max(0, c.Score-constraintPenalty)
+		c.Deductions = append(c.Deductions, model.ScoreDeduction{
+			Reason: "月間労働時間の制約からの超過・不足",
+			Points: round2(constraintPenalty),
+		})
+	}
+	c.Detail = fmt.Sprintf("月間設定のあるスタッフ %d人中 %d人が希望の範囲内", len(staffIDs), fitting)
+	return c
+}
+
+// preferenceComponent is the weighted share of the preferred and available
+// requests that got a shift. It is 100 when there are no requests.
+func preferenceComponent(entries []model.LLMShiftEntry, requests map[string]workRequest) model.ScoreComponent {
+	c := model.ScoreComponent{Name: model.ScorePreference, Label: "シフト希望の充足", Score: 100}
+
+	assigned := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		assigned[e.StaffID+":"+e.Date] = true
+	}
+	var total, met float64
+	requested, granted := make(map[string]int), make(map[string]int)
+	for key, r := range requests {
+		weight := availableRequestWeight
+		if r.Type == "preferred" {
+			weight = preferredRequestWeight
+		}
+		total += weight
+		requested[r.Type]++
+		if assigned[key] {
+			met += weight
+			granted[r.Type]++
+		}
+	}
+	if total > 0 {
+		c.Score = 100 * met / total
+	}
+	c.Detail = fmt.Sprintf("◎ %d/%d件、○ %d/%d件に勤務を割り当て",
+		granted["preferred"], requested["preferred"], granted["available"], requested["available"])
+	return c
+}
+
+// softConstraintComponent takes points off 100 for each soft violation,
+// more for higher priority constraints, and groups the deductions by priority
+func softConstraintComponent(violations []model.Violation, priorities map[string]int) model.ScoreComponent {
+	c := model.ScoreComponent{Name: model.ScoreSoftConstraints, Label: "ソフト制約", Score: 100}
+
+	counts := make(map[int]int)
+	for _, v := range violations {
+		if v.Type == "soft" {
+			counts[priorities[v.Constraint]]++
+		}
+	}
+	levels := make([]int, 0, len(counts))
+	for p := range counts {
+		levels = append(levels, p)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(levels)))
+
+	violated := 0
+	for _, p := range levels {
+		points := float64(counts[p]) * softViolationPoints * float64(1+max(p, 0))
+		c.Score -= points
+		violated += counts[p]
+		c.Deductions = append(c.Deductions, model.ScoreDeduction{
+			Reason: fmt.Sprintf("優先度%dのソフト制約違反 %d件", p, counts[p]),
+			Points: points,
+		})
+	}
+	c.Score = math.Max(0, c.Score)
+	c.Detail = fmt.Sprintf("ソフト制約違反 %d件", violated)
+	return c
+}
+
+// fairnessComponent compares the number of weekend shifts of the staff who
+// work: 100 when they all work as many, less as the coefficient of variation
+// grows. It is 100 with fewer than two staff or no weekend shifts.
+func fairnessComponent(entries []model.LLMShiftEntry) model.ScoreComponent {
+	c := model.ScoreComponent{Name: model.ScoreFairness, Label: "週末出勤の公平性", Score: 100}
+
+	// Every staff with a shift counts, also with no weekend shift
+	weekends := make(map[string]int)
+	for _, e := range entries {
+		n := weekends[e.StaffID]
+		if d, err := time.Parse("2006-01-02", e.Date); err == nil {
+			if wd := d.Weekday(); wd == time.Saturday || wd == time.Sunday {
+				n++
+			}
+		}
+		weekends[e.StaffID] = n
+	}
+
+	counts := make([]float64, 0, len(weekends))
+	most, least := 0, -1
+	for _, n := range weekends {
+		counts = append(counts, float64(n))
+		most = max(most, n)
+		if least < 0 || n < least {
+			least = n
+		}
+	}
+	c.Detail = "週末の勤務なし"
+	if most == 0 {
+		return c
+	}
+	c.Detail = fmt.Sprintf("週末の出勤 最多%d回・最少%d回", most, least)
+	if len(counts) < 2 {
+		return c
+	}
+
+	mean := 0.0
+	for _, n := range counts {
+		mean += n
+	}
+	mean /= float64(len(counts))
+	variance := 0.0
+	for _, n := range counts {
+		variance += (n - mean) * (n - mean)
+	}
+	cv := math.Sqrt(variance/float64(len(counts))) / mean
+	c.Score = math.Max(0, 100*(1-cv))
+	return c
+}
+
+// costComponent is 100 while the total hours stay within the sum of the
+// staff's minimum preferred hours, and falls in proportion beyond it
+func costComponent(hours map[string]float64, settings map[string]monthlySetting) model.ScoreComponent {
+	c := model.ScoreComponent{Name: model.ScoreCost, Label: "人件費（総勤務時間）", Score: 100}
+
+	minimum := 0.0
+	for _, s := range settings {
+		minimum += float64(s.MinHours)
+	}
+	total := 0.0
+	for _, h := range hours {
+		total += h
+	}
+	if minimum > 0 && total > minimum {
+		c.Score = 100 * minimum / total
+	}
+	c.Detail = fmt.Sprintf("総勤務時間 %.1fh（希望下限の合計 %.0fh）", total, minimum)
+	return c
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// workRequest is a preferred or available request. StartTime and EndTime
+// are empty when the request has no time window.
+type workRequest struct {
+	Type      string
+	StartTime string
+	EndTime   string
+}
+
+// getWorkRequests returns the preferred and available requests of a month by
+// "staffID:date"
+func (v *ShiftValidator) getWorkRequests(ctx context.Context, yearMonth string) (map[string]workRequest, error) {
+	rows, err := v.db.Query(ctx,
+		`SELECT staff_id, date::text, request_type, COALESCE(to_char(start_time, 'HH24:MI'), ''), COALESCE(to_char(end_time, 'HH24:MI'), '')
+		 FROM shift_requests WHERE year_month = $1 AND request_type IN ('preferred', 'available')`, yearMonth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]workRequest)
+	for rows.Next() {
+		var staffID, date string
+		var r workRequest
+		if err := rows.Scan(&staffID, &date, &r.Type, &r.StartTime, &r.EndTime); err != nil {
+			return nil, err
+		}
+		result[staffID+":"+date] = r
+	}
+	return result, rows.Err()
+}
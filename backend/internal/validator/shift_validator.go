@@ -7,21 +7,32 @@ import (
 	"math"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"shift-app/internal/model"
 )
 
+// CalendarSource loads the business calendar of a month
+type CalendarSource interface {
+	GetCalendar(ctx context.Context, yearMonth string) (*model.BusinessCalendar, error)
+}
+
 type ShiftValidator struct {
-	db *pgxpool.Pool
+	db       *pgxpool.Pool
+	calendar CalendarSource
+	// weights are the score weights used when Validate gets none
+	weights model.ObjectiveWeights
 }
 
-func NewShiftValidator(db *pgxpool.Pool) *ShiftValidator {
-	return &ShiftValidator{db: db}
+func NewShiftValidator(db *pgxpool.Pool, calendar CalendarSource, weights model.ObjectiveWeights) *ShiftValidator {
+	return &ShiftValidator{db: db, calendar: calendar, weights: weights}
 }
 
-func (v *ShiftValidator) Validate(ctx context.Context, yearMonth string, response *model.LLMResponse) (*model.ValidationResult, error) {
+// Validate checks a schedule against the constraints and scores it. weights,
+// when set, replaces the validator's score weights.
+func (v *ShiftValidator) Validate(ctx context.Context, yearMonth string, response *model.LLMResponse, weights *model.ObjectiveWeights) (*model.ValidationResult, error) {
 	result := &model.ValidationResult{
 		IsValid:    true,
 		Violations: []model.Violation{},
@@ -45,6 +56,21 @@ func (v *ShiftValidator) Validate(ctx context.Context, yearMonth string, respons
 		return nil, err
 	}
 
+	staffTypes, err := v.getStaffEmploymentTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	workRequests, err := v.getWorkRequests(ctx, yearMonth)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar, err := v.calendar.GetCalendar(ctx, yearMonth)
+	if err != nil {
+		return nil, err
+	}
+
 	// 1. Check unavailable dates (hard)
 	for _, entry := range response.Entries {
 		key := entry.StaffID + ":" + entry.Date
@@ -68,7 +94,7 @@ func (v *ShiftValidator) Validate(ctx context.Context, yearMonth string, respons
 				Constraint: "時間整合性",
 				Date:       entry.Date,
 				StaffID:    entry.StaffID,
-				Message:    fmt.Sprintf("開始時刻(%s)が終了時刻(%s)以降です", entry.StartTime, entry.EndTime),
+				Message:    fmt.Sprintf("開始時刻(%s)・終了時刻(%s)が不正です", entry.StartTime, entry.EndTime),
 			})
 			result.IsValid = false
 		}
@@ -86,6 +112,9 @@ func (v *ShiftValidator) Validate(ctx context.Context, yearMonth string, respons
 		}
 	}
 
+	// 2b. Check business hours and closed days (hard)
+	checkBusinessHours(response.Entries, calendar, result)
+
 	// 3. Check duplicate shifts (hard)
 	seen := make(map[string]bool)
 	for _, entry := range response.Entries {
@@ -104,52 +133,92 @@ func (v *ShiftValidator) Validate(ctx context.Context, yearMonth string, respons
 	}
 
 	// 4. Check constraints
+	hoursPenalty := 0.0
+	priorities := make(map[string]int, len(constraints))
+	checkedWindows := false
 	for _, c := range constraints {
+		// Surface broken configs instead of silently skipping them
+		typed, details := model.DecodeConstraintConfig(c.Category, c.Config)
+		if len(details) > 0 {
+			result.Warnings = append(result.Warnings, model.Warning{
+				Type:       "config_error",
+				Constraint: c.Name,
+				Message:    fmt.Sprintf("制約設定が不正なためチェックをスキップしました: %s", details[0].Message),
+			})
+			continue
+		}
 		var config map[string]interface{}
 		if err := json.Unmarshal(c.Config, &config); err != nil {
 			continue
 		}
+		priorities[c.Name] = c.Priority
 
 		switch c.Category {
 		case "max_consecutive_days":
-			v.checkConsecutiveDays(response.Entries, config, c, result)
+			v.checkConsecutiveDays(response.Entries, typed.(*model.MaxConsecutiveDaysConfig), c, result)
 		case "min_staff":
-			v.checkMinStaff(response.Entries, config, c, result)
+			cfg := typed.(*model.MinStaffConfig)
+			// Without min_count, only the time ranges are required
+			if cfg.MinCount != nil {
+				v.checkMinStaff(response.Entries, cfg, c, result)
+			}
+			v.checkMinStaffRanges(response.Entries, cfg, c, result)
 		case "max_staff":
-			v.checkMaxStaff(response.Entries, config, c, result)
+			v.checkMaxStaff(response.Entries, typed.(*model.MaxStaffConfig), c, result)
 		case "rest_hours":
-			v.checkRestHours(response.Entries, config, c, result)
+			v.checkRestHours(response.Entries, typed.(*model.RestHoursConfig), c, result)
+		case "monthly_hours":
+			hoursPenalty += v.checkMonthlyHours(response.Entries, typed.(*model.MonthlyHoursConfig), c, staffTypes, result)
+		case "fixed_day_off":
+			v.checkFixedDayOff(response.Entries, config, c, result)
+		case "staff_compatibility":
+			v.checkStaffCompatibility(response.Entries, config, c, result)
+		case "coverage":
+			v.checkCoverage(response.Entries, typed.(*model.CoverageConfig), c, yearMonth, result)
+		case "request_window":
+			v.checkRequestWindows(response.Entries, typed.(*model.RequestWindowConfig), c, workRequests, result)
+			checkedWindows = true
 		}
 	}
+	if !checkedWindows {
+		v.checkRequestWindows(response.Entries, &model.RequestWindowConfig{}, defaultRequestWindow, workRequests, result)
+	}
+	checkPreferredDays(response.Entries, workRequests, result)
 
 	// 5. Check monthly hours (soft constraints / scoring)
 	staffHours := computeStaffHours(response.Entries)
-	penalty := 0.0
 	for staffID, hours := range staffHours {
 		if setting, ok := monthlySettings[staffID]; ok {
 			if hours > float64(setting.MaxHours) {
-				diff := hours - float64(setting.MaxHours)
-				p := diff / float64(setting.MaxHours) * 5.0
-				penalty += p
 				result.Warnings = append(result.Warnings, model.Warning{
 					Type:       "soft_constraint",
 					Constraint: "月間労働時間",
+					StaffID:    staffID,
 					Message:    fmt.Sprintf("月間労働時間(%.0fh)が上限(%dh)を超えています", hours, setting.MaxHours),
 				})
 			} else if hours < float64(setting.MinHours) {
-				diff := float64(setting.MinHours) - hours
-				p := diff / float64(setting.MinHours) * 5.0
-				penalty += p
 				result.Warnings = append(result.Warnings, model.Warning{
 					Type:       "soft_constraint",
 					Constraint: "月間労働時間",
+					StaffID:    staffID,
 					Message:    fmt.Sprintf("月間労働時間(%.0fh)が下限(%dh)を下回っています", hours, setting.MinHours),
 				})
 			}
 		}
 	}
 
-	result.Score = math.Max(0, 100.0-penalty)
+	if weights == nil {
+		weights = &v.weights
+	}
+	result.Breakdown = scoreSchedule(scoreInput{
+		entries:      response.Entries,
+		settings:     monthlySettings,
+		requests:     workRequests,
+		priorities:   priorities,
+		violations:   result.Violations,
+		hoursPenalty: hoursPenalty,
+	}, *weights)
+	result.Score = result.Breakdown.Total
 
 	if len(result.Violations) > 0 {
 		result.IsValid = false
@@ -158,13 +227,80 @@ func (v *ShiftValidator) Validate(ctx context.Context, yearMonth string, respons
 	return result, nil
 }
 
-func (v *ShiftValidator) checkConsecutiveDays(entries []model.LLMShiftEntry, config map[string]interface{}, c constraintData, result *model.ValidationResult) {
-	maxDays := 5
-	if md, ok := config["max_days"]; ok {
-		if mdFloat, ok := md.(float64); ok {
-			maxDays = int(mdFloat)
+// ValidateEntries checks and scores the stored entries of a pattern,
+// including manual edits
+func (v *ShiftValidator) ValidateEntries(ctx context.Context, yearMonth string, entries []model.ShiftEntry, weights *model.ObjectiveWeights) (*model.ValidationResult, error) {
+	return v.Validate(ctx, yearMonth, &model.LLMResponse{Entries: model.LLMEntries(entries)}, weights)
+}
+
+// checkBusinessHours flags entries on closed days or outside the day's
+// opening hours. Hours of the previous and next day that run past midnight
+// count too, so a shift after midnight that belongs to the previous night's
+// opening isn't flagged.
+func checkBusinessHours(entries []model.LLMShiftEntry, calendar *model.BusinessCalendar, result *model.ValidationResult) {
+	for _, e := range entries {
+		d, err := time.Parse("2006-01-02", e.Date)
+		if err != nil {
+			continue
+		}
+		day := calendar.Day(d)
+		start, end := model.ShiftSpan(e.StartTime, e.EndTime)
+		if withinOpenHours(calendar, d, start, end) {
+			continue
+		}
+		if day.IsClosed {
+			result.Violations = append(result.Violations, model.Violation{
+				Type:       "hard",
+				Constraint: "営業時間チェック",
+				Date:       e.Date,
+				StaffID:    e.StaffID,
+				Message:    fmt.Sprintf("休業日(%s)にシフトが割り当てられています", e.Date),
+			})
+			result.IsValid = false
+			continue
+		}
+		result.Violations = append(result.Violations, model.Violation{
+			Type:       "hard",
+			Constraint: "営業時間チェック",
+			Date:       e.Date,
+			StartTime:  e.StartTime,
+			EndTime:    e.EndTime,
+			StaffID:    e.StaffID,
+			Message:    fmt.Sprintf("シフト(%s〜%s)が営業時間(%s〜%s)外です", e.StartTime, e.EndTime, day.OpenTime, day.CloseTime),
+		})
+		result.IsValid = false
+	}
+}
+
+// withinOpenHours reports whether [start, end), in minutes from midnight of
+// date, lies inside the opening hours of date and the days around it. Both
+// may run past midnight (e.g. a bar open 18:00-02:00), and back-to-back
+// openings are joined.
+func withinOpenHours(calendar *model.BusinessCalendar, date time.Time, start, end int) bool {
+	var open [][2]int
+	for offset := -1; offset <= 1; offset++ {
+		day := calendar.Day(date.AddDate(0, 0, offset))
+		if day.IsClosed {
+			continue
+		}
+		openAt, closeAt := model.ShiftSpan(day.OpenTime, day.CloseTime)
+		base := offset * model.MinutesPerDay
+		if n := len(open); n > 0 && open[n-1][1] >= base+openAt {
+			open[n-1][1] = max(open[n-1][1], base+closeAt)
+			continue
+		}
+		open = append(open, [2]int{base + openAt, base + closeAt})
+	}
+	for _, span := range open {
+		if start >= span[0] && end <= span[1] {
+			return true
 		}
 	}
+	return false
+}
+
+func (v *ShiftValidator) checkConsecutiveDays(entries []model.LLMShiftEntry, cfg *model.MaxConsecutiveDaysConfig, c constraintData, result *model.ValidationResult) {
+	maxDays := *cfg.MaxDays
 
 	// Group dates by staff
 	staffDates := make(map[string][]string)
@@ -197,19 +333,9 @@ func (v *ShiftValidator) checkConsecutiveDays(entries []model.LLMShiftEntry, con
 	}
 }
 
-func (v *ShiftValidator) checkMinStaff(entries []model.LLMShiftEntry, config map[string]interface{}, c constraintData, result *model.ValidationResult) {
-	// Count staff per date
-	dateCounts := make(map[string]int)
-	for _, e := range entries {
-		dateCounts[e.Date]++
-	}
-
-	minCount := 2
-	if mc, ok := config["min_count"]; ok {
-		if mcFloat, ok := mc.(float64); ok {
-			minCount = int(mcFloat)
-		}
-	}
+func (v *ShiftValidator) checkMinStaff(entries []model.LLMShiftEntry, cfg *model.MinStaffConfig, c constraintData, result *model.ValidationResult) {
+	dateCounts := dailyHeadcounts(entries)
+	minCount := *cfg.MinCount
 
 	for date, count := range dateCounts {
 		if count < minCount {
@@ -226,18 +352,96 @@ func (v *ShiftValidator) checkMinStaff(entries []model.LLMShiftEntry, config map
 	}
 }
 
-func (v *ShiftValidator) checkMaxStaff(entries []model.LLMShiftEntry, config map[string]interface{}, c constraintData, result *model.ValidationResult) {
-	dateCounts := make(map[string]int)
+// dailyHeadcounts returns the number of staff working on each date with
+// shifts, each staff counted once per date. Hours worked after midnight on
+// an overnight shift count toward the next day too, as in checkCoverage.
+func dailyHeadcounts(entries []model.LLMShiftEntry) map[string]int {
+	staffByDate := make(map[string]map[string]bool)
 	for _, e := range entries {
-		dateCounts[e.Date]++
+		if staffByDate[e.Date] == nil {
+			staffByDate[e.Date] = make(map[string]bool)
+		}
+		staffByDate[e.Date][e.StaffID] = true
 	}
+	for _, e := range entries {
+		intervals := workIntervals(e)
+		if len(intervals) == 0 || intervals[len(intervals)-1][1] <= model.MinutesPerDay {
+			continue
+		}
+		d, err := time.Parse("2006-01-02", e.Date)
+		if err != nil {
+			continue
+		}
+		// Only dates with shifts of their own are counted, so the night of
+		// the last day of the month doesn't add a date of the next month
+		if next := staffByDate[d.AddDate(0, 0, 1).Format("2006-01-02")]; next != nil {
+			next[e.StaffID] = true
+		}
+	}
+
+	counts := make(map[string]int, len(staffByDate))
+	for date, staff := range staffByDate {
+		counts[date] = len(staff)
+	}
+	return counts
+}
 
-	maxCount := 5
-	if mc, ok := config["max_count"]; ok {
-		if mcFloat, ok := mc.(float64); ok {
-			maxCount = int(mcFloat)
+// checkMinStaffRanges checks the headcount of each time range of min_staff on
+// every date with shifts. The headcount of a range is the fewest staff
+// working at any moment of it, breaks cut out as in checkCoverage.
+func (v *ShiftValidator) checkMinStaffRanges(entries []model.LLMShiftEntry, cfg *model.MinStaffConfig, c constraintData, result *model.ValidationResult) {
+	if len(cfg.TimeRanges) == 0 {
+		return
+	}
+	var intervals [][2]int
+	dateSet := make(map[string]bool)
+	for _, e := range entries {
+		dateSet[e.Date] = true
+		dayIdx, ok := model.DayIndex(e.Date)
+		if !ok {
+			continue
+		}
+		base := dayIdx * model.MinutesPerDay
+		for _, iv := range workIntervals(e) {
+			intervals = append(intervals, [2]int{base + iv[0], base + iv[1]})
+		}
+	}
+	dates := make([]string, 0, len(dateSet))
+	for date := range dateSet {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	for _, date := range dates {
+		dayIdx, ok := model.DayIndex(date)
+		if !ok {
+			continue
+		}
+		base := dayIdx * model.MinutesPerDay
+		for _, tr := range cfg.TimeRanges {
+			start, end := model.ShiftSpan(tr.Start, tr.End)
+			count := minHeadcount(intervals, base+start, base+end)
+			if count >= tr.MinCount {
+				continue
+			}
+			result.Violations = append(result.Violations, model.Violation{
+				Type:       c.Type,
+				Constraint: c.Name,
+				Date:       date,
+				StartTime:  tr.Start,
+				EndTime:    tr.End,
+				Message:    fmt.Sprintf("%s %s〜%s のスタッフ数(最少%d人)が最低人数(%d人)未満", date, tr.Start, tr.End, count, tr.MinCount),
+			})
+			if c.Type == "hard" {
+				result.IsValid = false
+			}
 		}
 	}
+}
+
+func (v *ShiftValidator) checkMaxStaff(entries []model.LLMShiftEntry, cfg *model.MaxStaffConfig, c constraintData, result *model.ValidationResult) {
+	dateCounts := dailyHeadcounts(entries)
+	maxCount := *cfg.MaxCount
 
 	for date, count := range dateCounts {
 		if count > maxCount {
@@ -254,13 +458,8 @@ func (v *ShiftValidator) checkMaxStaff(entries []model.LLMShiftEntry, config map
 	}
 }
 
-func (v *ShiftValidator) checkRestHours(entries []model.LLMShiftEntry, config map[string]interface{}, c constraintData, result *model.ValidationResult) {
-	minRestHours := 11.0
-	if mh, ok := config["min_hours"]; ok {
-		if mhFloat, ok := mh.(float64); ok {
-			minRestHours = mhFloat
-		}
-	}
+func (v *ShiftValidator) checkRestHours(entries []model.LLMShiftEntry, cfg *model.RestHoursConfig, c constraintData, result *model.ValidationResult) {
+	minRestHours := *cfg.MinHours
 
 	// Group entries by staff, sorted by date
 	staffEntries := make(map[string][]model.LLMShiftEntry)
@@ -282,14 +481,11 @@ func (v *ShiftValidator) checkRestHours(entries []model.LLMShiftEntry, config ma
 				continue
 			}
 
-			// Calculate interval: prev end_time to curr start_time
-			var peh, pem, csh, csm int
-			fmt.Sscanf(prev.EndTime, "%d:%d", &peh, &pem)
-			fmt.Sscanf(curr.StartTime, "%d:%d", &csh, &csm)
-
-			// Interval in hours: (24 - prev_end) + curr_start
-			intervalMin := (24*60 - (peh*60 + pem)) + (csh*60 + csm)
-			intervalHours := float64(intervalMin) / 60.0
+			// Interval from prev end to curr start; prev may end after
+			// midnight, i.e. already on curr's date
+			_, prevEnd := model.ShiftSpan(prev.StartTime, prev.EndTime)
+			currStart := model.ClockMinutes(curr.StartTime) + model.MinutesPerDay
+			intervalHours := float64(currStart-prevEnd) / 60.0
 
 			if intervalHours < minRestHours {
 				result.Violations = append(result.Violations, model.Violation{
@@ -297,7 +493,7 @@ func (v *ShiftValidator) checkRestHours(entries []model.LLMShiftEntry, config ma
 					Constraint: c.Name,
 					StaffID:    staffID,
 					Date:       curr.Date,
-					Message:    fmt.Sprintf("勤務間インターバル(%.1fh)が最低%.0fh未満です（前日%s終了→当日%s開始）", intervalHours, minRestHours, prev.EndTime, curr.StartTime),
+					Message:    fmt.Sprintf("勤務間インターバル(%.1fh)が最低%.0fh未満です（%s %s〜%s終了→%s %s開始）", intervalHours, minRestHours, prev.Date, prev.StartTime, prev.EndTime, curr.Date, curr.StartTime),
 				})
 				if c.Type == "hard" {
 					result.IsValid = false
@@ -307,23 +503,504 @@ func (v *ShiftValidator) checkRestHours(entries []model.LLMShiftEntry, config ma
 	}
 }
 
+// checkMonthlyHours checks each staff's monthly total against the configured
+// min/max hours. Limits are resolved per staff in the order
+// by_staff > by_employment_type > global. Hard violations are reported as
+// Violations and soft ones as Warnings; the returned value is the score penalty.
+func (v *ShiftValidator) checkMonthlyHours(entries []model.LLMShiftEntry, cfg *model.MonthlyHoursConfig, c constraintData, staffTypes map[string]string, result *model.ValidationResult) float64 {
+
+	// Staff without any entries still count as 0h against a minimum
+	staffHours := computeStaffHours(entries)
+	staffIDs := make([]string, 0, len(staffTypes))
+	for staffID := range staffTypes {
+		staffIDs = append(staffIDs, staffID)
+	}
+	for staffID := range staffHours {
+		if _, ok := staffTypes[staffID]; !ok {
+			staffIDs = append(staffIDs, staffID)
+		}
+	}
+	sort.Strings(staffIDs)
+
+	weight := 5.0
+	if c.Type == "hard" {
+		weight = 10.0
+	}
+
+	penalty := 0.0
+	for _, staffID := range staffIDs {
+		limit := mergeHourLimit(mergeHourLimit(cfg.HourLimitConfig, cfg.ByEmploymentType[staffTypes[staffID]]), cfg.ByStaff[staffID])
+		hours := staffHours[staffID]
+
+		var message string
+		if limit.MaxHours != nil && hours > *limit.MaxHours {
+			penalty += (hours - *limit.MaxHours) / math.Max(*limit.MaxHours, 1) * weight
+			message = fmt.Sprintf("月間労働時間(%.1fh)が上限(%.0fh)を超えています", hours, *limit.MaxHours)
+		} else if limit.MinHours != nil && hours < *limit.MinHours {
+			penalty += (*limit.MinHours - hours) / math.Max(*limit.MinHours, 1) * weight
+			message = fmt.Sprintf("月間労働時間(%.1fh)が下限(%.0fh)を下回っています", hours, *limit.MinHours)
+		} else {
+			continue
+		}
+
+		if c.Type == "hard" {
+			result.Violations = append(result.Violations, model.Violation{
+				Type:       c.Type,
+				Constraint: c.Name,
+				StaffID:    staffID,
+				Message:    message,
+			})
+			result.IsValid = false
+		} else {
+			result.Warnings = append(result.Warnings, model.Warning{
+				Type:       "soft_constraint",
+				Constraint: c.Name,
+				StaffID:    staffID,
+				Message:    message,
+			})
+		}
+	}
+	return penalty
+}
+
+// checkFixedDayOff flags entries for the configured staff that fall on one of
+// their fixed weekdays off, unless the date is listed in except_dates.
+func (v *ShiftValidator) checkFixedDayOff(entries []model.LLMShiftEntry, config map[string]interface{}, c constraintData, result *model.ValidationResult) {
+	staffID, _ := config["staff_id"].(string)
+	if staffID == "" {
+		return
+	}
+
+	weekdays := make(map[time.Weekday]bool)
+	if list, ok := config["weekdays"].([]interface{}); ok {
+		for _, wd := range list {
+			if f, ok := wd.(float64); ok {
+				weekdays[time.Weekday(int(f))] = true
+			}
+		}
+	} else if f, ok := config["day_of_week"].(float64); ok {
+		weekdays[time.Weekday(int(f))] = true
+	}
+
+	exceptions := make(map[string]bool)
+	for _, d := range model.StringList(config["except_dates"]) {
+		exceptions[d] = true
+	}
+
+	for _, e := range entries {
+		if e.StaffID != staffID || exceptions[e.Date] {
+			continue
+		}
+		d, err := time.Parse("2006-01-02", e.Date)
+		if err != nil || !weekdays[d.Weekday()] {
+			continue
+		}
+		result.Violations = append(result.Violations, model.Violation{
+			Type:       c.Type,
+			Constraint: c.Name,
+			StaffID:    staffID,
+			Date:       e.Date,
+			Message:    fmt.Sprintf("固定休の%s曜日(%s)にシフトが割り当てられています", model.WeekdayLabel(d.Weekday()), e.Date),
+		})
+		if c.Type == "hard" {
+			result.IsValid = false
+		}
+	}
+}
+
+// checkStaffCompatibility checks pairing rules between staff.
+//   - avoid_together: no two of staff_ids may work the same day
+//     (scope "same_day", default) or overlapping hours (scope "overlap")
+//   - mentor_required: every entry of trainee_ids must overlap in time with
+//     at least one entry of mentor_ids on the same day
+func (v *ShiftValidator) checkStaffCompatibility(entries []model.LLMShiftEntry, config map[string]interface{}, c constraintData, result *model.ValidationResult) {
+	rule, _ := config["rule"].(string)
+
+	// Group entries by date, then by staff
+	byDate := make(map[string]map[string][]model.LLMShiftEntry)
+	for _, e := range entries {
+		if byDate[e.Date] == nil {
+			byDate[e.Date] = make(map[string][]model.LLMShiftEntry)
+		}
+		byDate[e.Date][e.StaffID] = append(byDate[e.Date][e.StaffID], e)
+	}
+	dates := make([]string, 0, len(byDate))
+	for date := range byDate {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	addViolation := func(staffID string, related []string, date, message string) {
+		result.Violations = append(result.Violations, model.Violation{
+			Type:            c.Type,
+			Constraint:      c.Name,
+			StaffID:         staffID,
+			RelatedStaffIDs: related,
+			Date:            date,
+			Message:         message,
+		})
+		if c.Type == "hard" {
+			result.IsValid = false
+		}
+	}
+
+	switch rule {
+	case "avoid_together":
+		staffIDs := model.StringList(config["staff_ids"])
+		scope, _ := config["scope"].(string)
+		for _, date := range dates {
+			day := byDate[date]
+			for i := 0; i < len(staffIDs); i++ {
+				for j := i + 1; j < len(staffIDs); j++ {
+					a, b := day[staffIDs[i]], day[staffIDs[j]]
+					if len(a) == 0 || len(b) == 0 {
+						continue
+					}
+					if scope == "overlap" {
+						if overlapMinutes(a, b) == 0 {
+							continue
+						}
+						addViolation(staffIDs[i], []string{staffIDs[j]}, date, fmt.Sprintf("%sに同時間帯で勤務してはいけないスタッフ(%s, %s)の勤務が重なっています", date, staffIDs[i], staffIDs[j]))
+					} else {
+						addViolation(staffIDs[i], []string{staffIDs[j]}, date, fmt.Sprintf("%sに同日勤務してはいけないスタッフ(%s, %s)が同日に勤務しています", date, staffIDs[i], staffIDs[j]))
+					}
+				}
+			}
+		}
+	case "prefer_together":
+		// A preference only: its violations are soft whatever the constraint type
+		staffIDs := model.StringList(config["staff_ids"])
+		scope, _ := config["scope"].(string)
+		for _, date := range dates {
+			day := byDate[date]
+			for i := 0; i < len(staffIDs); i++ {
+				for j := i + 1; j < len(staffIDs); j++ {
+					a, b := day[staffIDs[i]], day[staffIDs[j]]
+					staffID, related := staffIDs[i], staffIDs[j]
+					var message string
+					switch {
+					case len(a) == 0 && len(b) == 0:
+						continue
+					case len(a) == 0 || len(b) == 0:
+						if len(a) == 0 {
+							staffID, related = related, staffID
+						}
+						message = fmt.Sprintf("%sに一緒に勤務させたいスタッフ(%s, %s)の一方だけが勤務しています", date, staffIDs[i], staffIDs[j])
+					case scope == "overlap" && overlapMinutes(a, b) == 0:
+						message = fmt.Sprintf("%sに一緒に勤務させたいスタッフ(%s, %s)の勤務時間帯が重なっていません", date, staffIDs[i], staffIDs[j])
+					default:
+						continue
+					}
+					result.Violations = append(result.Violations, model.Violation{
+						Type:            "soft",
+						Constraint:      c.Name,
+						StaffID:         staffID,
+						RelatedStaffIDs: []string{related},
+						Date:            date,
+						Message:         message,
+					})
+				}
+			}
+		}
+	case "mentor_required":
+		mentorIDs := model.StringList(config["mentor_ids"])
+		for _, date := range dates {
+			day := byDate[date]
+			var mentorEntries []model.LLMShiftEntry
+			for _, id := range mentorIDs {
+				mentorEntries = append(mentorEntries, day[id]...)
+			}
+			for _, traineeID := range model.StringList(config["trainee_ids"]) {
+				for _, e := range day[traineeID] {
+					if overlapMinutes([]model.LLMShiftEntry{e}, mentorEntries) > 0 {
+						continue
+					}
+					addViolation(traineeID, mentorIDs, date, fmt.Sprintf("%s %s〜%sの勤務時間帯に指導担当スタッフがいません", date, e.StartTime, e.EndTime))
+				}
+			}
+		}
+	}
+}
+
+// checkCoverage checks slot-level headcount for every day of the month.
+// A staff member counts toward a slot only when working the whole slot; the
+// break is assumed to be taken in the middle of the shift. Hours worked after
+// midnight on an overnight shift count toward the next day, and requirement
+// ranges may themselves wrap past midnight (e.g. 22:00-02:00). Consecutive
+// understaffed slots are reported as one violation with its time range.
+func (v *ShiftValidator) checkCoverage(entries []model.LLMShiftEntry, cfg *model.CoverageConfig, c constraintData, yearMonth string, result *model.ValidationResult) {
+	first, err := time.Parse("2006-01", yearMonth)
+	if err != nil {
+		return
+	}
+	slotMinutes := cfg.SlotMinutes
+	if slotMinutes <= 0 {
+		slotMinutes = model.DefaultCoverageSlotMinutes
+	}
+	counts := slotCounts(entries, slotMinutes)
+
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		reqs := cfg.Weekday
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			reqs = cfg.Weekend
+		}
+		if len(reqs) == 0 {
+			continue
+		}
+		dayIdx, _ := model.DayIndex(date)
+		base := dayIdx * model.MinutesPerDay
+
+		for _, req := range reqs {
+			reqStart, reqEnd := model.ShiftSpan(req.Start, req.End)
+			runStart, runMin := -1, 0
+			flush := func(end int) {
+				if runStart < 0 {
+					return
+				}
+				result.Violations = append(result.Violations, model.Violation{
+					Type:       c.Type,
+					Constraint: c.Name,
+					Date:       date,
+					StartTime:  model.FormatClock(runStart),
+					EndTime:    model.FormatClock(end),
+					Message: fmt.Sprintf("%s %s〜%s の人数(最少%d人)が必要人数(%d人)未満",
+						date, model.FormatClock(runStart), model.FormatClock(end), runMin, req.MinCount),
+				})
+				if c.Type == "hard" {
+					result.IsValid = false
+				}
+				runStart = -1
+			}
+			// Only slots fully inside the requirement range are checked
+			slot := (reqStart + slotMinutes - 1) / slotMinutes
+			for ; (slot+1)*slotMinutes <= reqEnd; slot++ {
+				count := counts[(base+slot*slotMinutes)/slotMinutes]
+				if count >= req.MinCount {
+					flush(slot * slotMinutes)
+					continue
+				}
+				if runStart < 0 {
+					runStart, runMin = slot*slotMinutes, count
+				} else if count < runMin {
+					runMin = count
+				}
+			}
+			flush(slot * slotMinutes)
+		}
+	}
+}
+
+// defaultRequestWindow checks request time windows as a soft constraint when
+// no request_window constraint is active
+var defaultRequestWindow = constraintData{Name: "シフト希望の時間帯", Type: "soft", Category: "request_window"}
+
+// checkRequestWindows flags entries on a day with a preferred or available
+// request that has a time window, when the shift doesn't fit inside it
+func (v *ShiftValidator) checkRequestWindows(entries []model.LLMShiftEntry, cfg *model.RequestWindowConfig, c constraintData, requests map[string]workRequest, result *model.ValidationResult) {
+	for _, e := range entries {
+		r, ok := requests[e.StaffID+":"+e.Date]
+		if !ok || !cfg.Applies(r.Type) || !isValidTimeRange(r.StartTime, r.EndTime) || !isValidTimeRange(e.StartTime, e.EndTime) {
+			continue
+		}
+		windowStart, windowEnd := model.ShiftSpan(r.StartTime, r.EndTime)
+		start, end := model.ShiftSpan(e.StartTime, e.EndTime)
+		if start >= windowStart && end <= windowEnd {
+			continue
+		}
+		label := "出勤可能"
+		if r.Type == "preferred" {
+			label = "出勤希望"
+		}
+		result.Violations = append(result.Violations, model.Violation{
+			Type:       c.Type,
+			Constraint: c.Name,
+			StaffID:    e.StaffID,
+			Date:       e.Date,
+			StartTime:  e.StartTime,
+			EndTime:    e.EndTime,
+			Message:    fmt.Sprintf("シフト(%s〜%s)が%sの時間帯(%s〜%s)に収まっていません", e.StartTime, e.EndTime, label, r.StartTime, r.EndTime),
+		})
+		if c.Type == "hard" {
+			result.IsValid = false
+		}
+	}
+}
+
+// checkPreferredDays measures how many preferred days of each staff got a
+// shift and warns about each one that didn't
+func checkPreferredDays(entries []model.LLMShiftEntry, requests map[string]workRequest, result *model.ValidationResult) {
+	assigned := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		assigned[e.StaffID+":"+e.Date] = true
+	}
+
+	keys := make([]string, 0, len(requests))
+	for key, r := range requests {
+		if r.Type == "preferred" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	// keys are sorted, so staff come out in staff ID order
+	var fulfillment []*model.PreferenceFulfillment
+	byStaff := make(map[string]*model.PreferenceFulfillment)
+	for _, key := range keys {
+		staffID, date, _ := strings.Cut(key, ":")
+		f := byStaff[staffID]
+		if f == nil {
+			f = &model.PreferenceFulfillment{StaffID: staffID}
+			byStaff[staffID] = f
+			fulfillment = append(fulfillment, f)
+		}
+		f.Preferred++
+		if assigned[key] {
+			f.Assigned++
+			continue
+		}
+		result.Warnings = append(result.Warnings, model.Warning{
+			Type:       "unmet_preference",
+			Constraint: "シフト希望",
+			StaffID:    staffID,
+			Date:       date,
+			Message:    fmt.Sprintf("出勤希望日(%s)にシフトが割り当てられていません", date),
+		})
+	}
+	for _, f := range fulfillment {
+		f.Rate = round2(100 * float64(f.Assigned) / float64(f.Preferred))
+		result.PreferenceFulfillment = append(result.PreferenceFulfillment, *f)
+	}
+}
+
+// slotCounts returns the number of staff working the whole of each slot,
+// keyed by absolute slot index across dates
+func slotCounts(entries []model.LLMShiftEntry, slotMinutes int) map[int]int {
+	counts := make(map[int]int)
+	for _, e := range entries {
+		dayIdx, ok := model.DayIndex(e.Date)
+		if !ok {
+			continue
+		}
+		base := dayIdx * model.MinutesPerDay
+		for _, iv := range workIntervals(e) {
+			for slot := (base + iv[0] + slotMinutes - 1) / slotMinutes; (slot+1)*slotMinutes <= base+iv[1]; slot++ {
+				counts[slot]++
+			}
+		}
+	}
+	return counts
+}
+
+// workIntervals returns the [start, end) minute ranges actually worked in an
+// entry, relative to midnight of its date, with the break cut out of the
+// middle of the shift. Overnight shifts end past MinutesPerDay.
+func workIntervals(e model.LLMShiftEntry) [][2]int {
+	if !isValidTimeRange(e.StartTime, e.EndTime) {
+		return nil
+	}
+	start, end := model.ShiftSpan(e.StartTime, e.EndTime)
+	brk := e.BreakMinutes
+	if brk <= 0 {
+		return [][2]int{{start, end}}
+	}
+	if brk >= end-start {
+		return nil
+	}
+	breakStart := start + (end-start-brk)/2
+	return [][2]int{{start, breakStart}, {breakStart + brk, end}}
+}
+
+// minHeadcount returns the fewest intervals covering any minute of
+// [start, end). The count only changes where an interval starts or ends, so
+// it is enough to look at start and at those points inside the range.
+func minHeadcount(intervals [][2]int, start, end int) int {
+	var inRange [][2]int
+	points := []int{start}
+	for _, iv := range intervals {
+		if iv[1] <= start || iv[0] >= end {
+			continue
+		}
+		inRange = append(inRange, iv)
+		for _, p := range iv {
+			if p > start && p < end {
+				points = append(points, p)
+			}
+		}
+	}
+	fewest := len(inRange)
+	for _, p := range points {
+		count := 0
+		for _, iv := range inRange {
+			if iv[0] <= p && p < iv[1] {
+				count++
+			}
+		}
+		fewest = min(fewest, count)
+	}
+	return fewest
+}
+
+// entrySpan returns an entry's start and end in minutes on a single time axis
+// across dates, so overnight shifts compare correctly with the next day's.
+func entrySpan(e model.LLMShiftEntry) (int, int, bool) {
+	dayIdx, ok := model.DayIndex(e.Date)
+	if !ok || !isValidTimeRange(e.StartTime, e.EndTime) {
+		return 0, 0, false
+	}
+	start, end := model.ShiftSpan(e.StartTime, e.EndTime)
+	base := dayIdx * model.MinutesPerDay
+	return base + start, base + end, true
+}
+
+// overlapMinutes returns the total overlapping minutes between any entry in a
+// and any entry in b
+func overlapMinutes(a, b []model.LLMShiftEntry) int {
+	total := 0
+	for _, ea := range a {
+		aStart, aEnd, ok := entrySpan(ea)
+		if !ok {
+			continue
+		}
+		for _, eb := range b {
+			bStart, bEnd, ok := entrySpan(eb)
+			if !ok {
+				continue
+			}
+			start := max(aStart, bStart)
+			end := min(aEnd, bEnd)
+			if end > start {
+				total += end - start
+			}
+		}
+	}
+	return total
+}
+
+// mergeHourLimit returns l overridden by any bound set in o
+func mergeHourLimit(l, o model.HourLimitConfig) model.HourLimitConfig {
+	if o.MinHours != nil {
+		l.MinHours = o.MinHours
+	}
+	if o.MaxHours != nil {
+		l.MaxHours = o.MaxHours
+	}
+	return l
+}
+
 func computeStaffHours(entries []model.LLMShiftEntry) map[string]float64 {
 	hours := make(map[string]float64)
 	for _, e := range entries {
-		var sh, sm, eh, em int
-		fmt.Sscanf(e.StartTime, "%d:%d", &sh, &sm)
-		fmt.Sscanf(e.EndTime, "%d:%d", &eh, &em)
-		totalMin := (eh*60 + em) - (sh*60 + sm) - e.BreakMinutes
-		if totalMin < 0 {
-			totalMin = 0
-		}
-		hours[e.StaffID] += float64(totalMin) / 60.0
+		hours[e.StaffID] += float64(model.WorkMinutes(e.StartTime, e.EndTime, e.BreakMinutes)) / 60.0
 	}
 	return hours
 }
 
+// isValidTimeRange reports whether both times parse and differ. An end at or
+// before the start is an overnight shift ending the next day.
 func isValidTimeRange(start, end string) bool {
-	return start < end
+	startMin, endMin := model.ClockMinutes(start), model.ClockMinutes(end)
+	return startMin >= 0 && endMin >= 0 && startMin != endMin
 }
 
 func isConsecutiveDate(d1, d2 string) bool {
@@ -366,6 +1043,7 @@ type constraintData struct {
 	Name     string
 	Type     string
 	Category string
+	Priority int
 	Config   json.RawMessage
 }
 
@@ -393,9 +1071,28 @@ func (v *ShiftValidator) getUnavailableDates(ctx context.Context, yearMonth stri
 	return result, rows.Err()
 }
 
+func (v *ShiftValidator) getStaffEmploymentTypes(ctx context.Context) (map[string]string, error) {
+	rows, err := v.db.Query(ctx,
+		`SELECT id, employment_type FROM staffs WHERE is_active = true`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]string)
+	for rows.Next() {
+		var staffID, employmentType string
+		if err := rows.Scan(&staffID, &employmentType); err != nil {
+			return nil, err
+		}
+		result[staffID] = employmentType
+	}
+	return result, rows.Err()
+}
+
 func (v *ShiftValidator) getActiveConstraints(ctx context.Context) ([]constraintData, error) {
 	rows, err := v.db.Query(ctx,
-		`SELECT name, type, category, config FROM constraints WHERE is_active = true`)
+		`SELECT name, type, category, COALESCE(priority, 0), config FROM constraints WHERE is_active = true`)
 	if err != nil {
 		return nil, err
 	}
@@ -404,7 +1101,7 @@ func (v *ShiftValidator) getActiveConstraints(ctx context.Context) ([]constraint
 	var result []constraintData
 	for rows.Next() {
 		var c constraintData
-		if err := rows.Scan(&c.Name, &c.Type, &c.Category, &c.Config); err != nil {
+		if err := rows.Scan(&c.Name, &c.Type, &c.Category, &c.Priority, &c.Config); err != nil {
 			return nil, err
 		}
 		result = append(result, c)
@@ -2,6 +2,7 @@ package validator
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 
 	"shift-app/internal/model"
@@ -18,7 +19,8 @@ func TestIsValidTimeRange(t *testing.T) {
 	}{
 		{"normal range", "09:00", "17:00", true},
 		{"start equals end", "09:00", "09:00", false},
-		{"start after end", "17:00", "09:00", false},
+		{"overnight (end next day)", "17:00", "09:00", true},
+		{"invalid time", "25:00", "09:00", false},
 		{"one minute apart", "08:59", "09:00", true},
 		{"midnight to morning", "00:00", "06:00", true},
 	}
@@ -147,6 +149,13 @@ func TestComputeStaffHours(t *testing.T) {
 			},
 			want: map[string]float64{"s1": 0.0},
 		},
+		{
+			name: "overnight entry",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "21:00", EndTime: "03:00", BreakMinutes: 30},
+			},
+			want: map[string]float64{"s1": 5.5},
+		},
 	}
 
 	for _, tt := range tests {
@@ -251,7 +260,7 @@ func TestCheckConsecutiveDays(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			config := map[string]interface{}{"max_days": float64(tt.maxDays)}
+			config := &model.MaxConsecutiveDaysConfig{MaxDays: &tt.maxDays}
 			c := constraintData{
 				Name:     "連続勤務制限",
 				Type:     tt.constraintType,
@@ -331,11 +340,34 @@ func TestCheckMinStaff(t *testing.T) {
 			wantViolations: 1, // 01-02 has only 1 staff
 			wantIsValid:    false,
 		},
+		{
+			name: "overnight shift counts toward the next day",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "20:00", EndTime: "02:00"},
+				{StaffID: "s2", Date: "2025-01-01", StartTime: "10:00", EndTime: "15:00"},
+				{StaffID: "s2", Date: "2025-01-02", StartTime: "10:00", EndTime: "15:00"},
+			},
+			minCount:       2,
+			constraintType: "hard",
+			wantViolations: 0,
+			wantIsValid:    true,
+		},
+		{
+			name: "overnight shift on the last date adds no date",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-31", StartTime: "20:00", EndTime: "02:00"},
+				{StaffID: "s2", Date: "2025-01-31", StartTime: "10:00", EndTime: "15:00"},
+			},
+			minCount:       2,
+			constraintType: "hard",
+			wantViolations: 0,
+			wantIsValid:    true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			config := map[string]interface{}{"min_count": float64(tt.minCount)}
+			config := &model.MinStaffConfig{MinCount: &tt.minCount}
 			c := constraintData{
 				Name:     "最低人数",
 				Type:     tt.constraintType,
@@ -359,6 +391,73 @@ func TestCheckMinStaff(t *testing.T) {
 	}
 }
 
+func TestCheckMinStaffRanges(t *testing.T) {
+	v := &ShiftValidator{}
+	cfg := &model.MinStaffConfig{
+		TimeRanges: []model.TimeRangeCount{{Start: "11:00", End: "14:00", MinCount: 2}},
+	}
+
+	tests := []struct {
+		name           string
+		entries        []model.LLMShiftEntry
+		wantViolations int
+	}{
+		{
+			name: "range covered",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "09:00", EndTime: "15:00"},
+				{StaffID: "s2", Date: "2025-01-01", StartTime: "10:00", EndTime: "18:00"},
+			},
+			wantViolations: 0,
+		},
+		{
+			name: "one staff leaves inside the range",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "09:00", EndTime: "15:00"},
+				{StaffID: "s2", Date: "2025-01-01", StartTime: "09:00", EndTime: "13:00"},
+			},
+			wantViolations: 1,
+		},
+		{
+			name: "handover without a gap",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "09:00", EndTime: "15:00"},
+				{StaffID: "s2", Date: "2025-01-01", StartTime: "09:00", EndTime: "12:30"},
+				{StaffID: "s3", Date: "2025-01-01", StartTime: "12:30", EndTime: "18:00"},
+			},
+			wantViolations: 0,
+		},
+		{
+			name: "break leaves a gap",
+			entries: []model.LLMShiftEntry{
+				// break centered: work 10:00-12:00, break 12:00-13:00, work 13:00-15:00
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "10:00", EndTime: "15:00", BreakMinutes: 60},
+				{StaffID: "s2", Date: "2025-01-01", StartTime: "10:00", EndTime: "15:00"},
+			},
+			wantViolations: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := constraintData{Name: "ランチ最低人数", Type: "hard", Category: "min_staff"}
+			result := &model.ValidationResult{
+				IsValid:    true,
+				Violations: []model.Violation{},
+			}
+
+			v.checkMinStaffRanges(tt.entries, cfg, c, result)
+
+			if len(result.Violations) != tt.wantViolations {
+				t.Errorf("got %d violations, want %d: %+v", len(result.Violations), tt.wantViolations, result.Violations)
+			}
+			if result.IsValid != (tt.wantViolations == 0) {
+				t.Errorf("IsValid = %v, want %v", result.IsValid, tt.wantViolations == 0)
+			}
+		})
+	}
+}
+
 // --- checkMaxStaff tests ---
 
 func TestCheckMaxStaff(t *testing.T) {
@@ -408,11 +507,22 @@ func TestCheckMaxStaff(t *testing.T) {
 			wantViolations: 0,
 			wantIsValid:    true,
 		},
+		{
+			name: "overnight shift counts toward the next day",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "20:00", EndTime: "02:00"},
+				{StaffID: "s2", Date: "2025-01-02", StartTime: "10:00", EndTime: "15:00"},
+			},
+			maxCount:       1,
+			constraintType: "hard",
+			wantViolations: 1, // 01-02 has s2 and s1 after midnight
+			wantIsValid:    false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			config := map[string]interface{}{"max_count": float64(tt.maxCount)}
+			config := &model.MaxStaffConfig{MaxCount: &tt.maxCount}
 			c := constraintData{
 				Name:     "最大人数",
 				Type:     tt.constraintType,
@@ -504,6 +614,28 @@ func TestCheckRestHours(t *testing.T) {
 			wantViolations: 1,
 			wantIsValid:    true,
 		},
+		{
+			name: "overnight shift then evening shift (16h)",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "20:00", EndTime: "02:00"},
+				{StaffID: "s1", Date: "2025-01-02", StartTime: "18:00", EndTime: "23:00"},
+			},
+			minHours:       11,
+			constraintType: "hard",
+			wantViolations: 0,
+			wantIsValid:    true,
+		},
+		{
+			name: "overnight shift then morning shift (4h)",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "20:00", EndTime: "05:00"},
+				{StaffID: "s1", Date: "2025-01-02", StartTime: "09:00", EndTime: "17:00"},
+			},
+			minHours:       11,
+			constraintType: "hard",
+			wantViolations: 1,
+			wantIsValid:    false,
+		},
 		{
 			name: "different staff - independent",
 			entries: []model.LLMShiftEntry{
@@ -519,7 +651,7 @@ func TestCheckRestHours(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			config := map[string]interface{}{"min_hours": tt.minHours}
+			config := &model.RestHoursConfig{MinHours: &tt.minHours}
 			c := constraintData{
 				Name:     "勤務間インターバル",
 				Type:     tt.constraintType,
@@ -543,7 +675,728 @@ func TestCheckRestHours(t *testing.T) {
 	}
 }
 
+// --- checkMonthlyHours tests ---
+
+func TestCheckMonthlyHours(t *testing.T) {
+	v := &ShiftValidator{}
+
+	// 5 days x 8h = 40h for s1, 1 day x 8h = 8h for s2
+	entries := []model.LLMShiftEntry{
+		{StaffID: "s1", Date: "2025-01-01", StartTime: "09:00", EndTime: "17:00"},
+		{StaffID: "s1", Date: "2025-01-02", StartTime: "09:00", EndTime: "17:00"},
+		{StaffID: "s1", Date: "2025-01-03", StartTime: "09:00", EndTime: "17:00"},
+		{StaffID: "s1", Date: "2025-01-04", StartTime: "09:00", EndTime: "17:00"},
+		{StaffID: "s1", Date: "2025-01-05", StartTime: "09:00", EndTime: "17:00"},
+		{StaffID: "s2", Date: "2025-01-01", StartTime: "09:00", EndTime: "17:00"},
+	}
+	staffTypes := map[string]string{"s1": "part_time", "s2": "part_time", "s3": "full_time"}
+
+	tests := []struct {
+		name           string
+		config         map[string]interface{}
+		constraintType string
+		wantViolations int
+		wantWarnings   int
+		wantIsValid    bool
+		wantPenalty    bool
+	}{
+		{
+			name:           "within global limits",
+			config:         map[string]interface{}{"max_hours": float64(40)},
+			constraintType: "hard",
+			wantViolations: 0,
+			wantWarnings:   0,
+			wantIsValid:    true,
+		},
+		{
+			name:           "exceeds global max (hard)",
+			config:         map[string]interface{}{"max_hours": float64(32)},
+			constraintType: "hard",
+			wantViolations: 1,
+			wantWarnings:   0,
+			wantIsValid:    false,
+			wantPenalty:    true,
+		},
+		{
+			name:           "below global min counts staff without entries (soft)",
+			config:         map[string]interface{}{"min_hours": float64(16)},
+			constraintType: "soft",
+			wantViolations: 0,
+			wantWarnings:   2, // s2 (8h) and s3 (0h)
+			wantIsValid:    true,
+			wantPenalty:    true,
+		},
+		{
+			name: "employment type override",
+			config: map[string]interface{}{
+				"max_hours": float64(160),
+				"by_employment_type": map[string]interface{}{
+					"part_time": map[string]interface{}{"max_hours": float64(20)},
+				},
+			},
+			constraintType: "hard",
+			wantViolations: 1, // s1 only
+			wantWarnings:   0,
+			wantIsValid:    false,
+			wantPenalty:    true,
+		},
+		{
+			name: "staff override wins over employment type",
+			config: map[string]interface{}{
+				"by_employment_type": map[string]interface{}{
+					"part_time": map[string]interface{}{"max_hours": float64(20)},
+				},
+				"by_staff": map[string]interface{}{
+					"s1": map[string]interface{}{"max_hours": float64(40)},
+				},
+			},
+			constraintType: "hard",
+			wantViolations: 0,
+			wantWarnings:   0,
+			wantIsValid:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := constraintData{
+				Name:     "月間労働時間上限",
+				Type:     tt.constraintType,
+				Category: "monthly_hours",
+				Config:   mustMarshalJSON(tt.config),
+			}
+			// Decode the same way Validate does
+			typed, details := model.DecodeConstraintConfig(c.Category, c.Config)
+			if len(details) > 0 {
+				t.Fatalf("decode config: %v", details)
+			}
+			result := &model.ValidationResult{
+				IsValid:    true,
+				Violations: []model.Violation{},
+				Warnings:   []model.Warning{},
+			}
+
+			penalty := v.checkMonthlyHours(entries, typed.(*model.MonthlyHoursConfig), c, staffTypes, result)
+
+			if len(result.Violations) != tt.wantViolations {
+				t.Errorf("got %d violations, want %d", len(result.Violations), tt.wantViolations)
+			}
+			if len(result.Warnings) != tt.wantWarnings {
+				t.Errorf("got %d warnings, want %d", len(result.Warnings), tt.wantWarnings)
+			}
+			if result.IsValid != tt.wantIsValid {
+				t.Errorf("IsValid = %v, want %v", result.IsValid, tt.wantIsValid)
+			}
+			if (penalty > 0) != tt.wantPenalty {
+				t.Errorf("penalty = %f, want penalty > 0: %v", penalty, tt.wantPenalty)
+			}
+			for _, viol := range result.Violations {
+				if viol.StaffID == "" {
+					t.Error("violation has empty StaffID")
+				}
+			}
+			for _, w := range result.Warnings {
+				if w.StaffID == "" {
+					t.Error("warning has empty StaffID")
+				}
+			}
+		})
+	}
+}
+
+// --- checkFixedDayOff tests ---
+
+func TestCheckFixedDayOff(t *testing.T) {
+	v := &ShiftValidator{}
+
+	// 2025-01-01 is a Wednesday
+	tests := []struct {
+		name           string
+		entries        []model.LLMShiftEntry
+		config         map[string]interface{}
+		wantViolations int
+	}{
+		{
+			name: "entry on fixed weekday",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01"},
+				{StaffID: "s1", Date: "2025-01-02"},
+				{StaffID: "s1", Date: "2025-01-08"},
+			},
+			config:         map[string]interface{}{"staff_id": "s1", "weekdays": []interface{}{float64(3)}},
+			wantViolations: 2,
+		},
+		{
+			name: "other staff not affected",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s2", Date: "2025-01-01"},
+			},
+			config:         map[string]interface{}{"staff_id": "s1", "weekdays": []interface{}{float64(3)}},
+			wantViolations: 0,
+		},
+		{
+			name: "exception date allowed",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01"},
+				{StaffID: "s1", Date: "2025-01-08"},
+			},
+			config: map[string]interface{}{
+				"staff_id":     "s1",
+				"weekdays":     []interface{}{float64(3)},
+				"except_dates": []interface{}{"2025-01-08"},
+			},
+			wantViolations: 1,
+		},
+		{
+			name: "legacy day_of_week key",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-05"},
+			},
+			config:         map[string]interface{}{"staff_id": "s1", "day_of_week": float64(0)},
+			wantViolations: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := constraintData{
+				Name:     "固定休",
+				Type:     "hard",
+				Category: "fixed_day_off",
+				Config:   mustMarshalJSON(tt.config),
+			}
+			result := &model.ValidationResult{
+				IsValid:    true,
+				Violations: []model.Violation{},
+			}
+
+			v.checkFixedDayOff(tt.entries, tt.config, c, result)
+
+			if len(result.Violations) != tt.wantViolations {
+				t.Errorf("got %d violations, want %d", len(result.Violations), tt.wantViolations)
+			}
+			if result.IsValid != (tt.wantViolations == 0) {
+				t.Errorf("IsValid = %v, want %v", result.IsValid, tt.wantViolations == 0)
+			}
+		})
+	}
+}
+
+// --- checkStaffCompatibility tests ---
+
+func TestCheckStaffCompatibility(t *testing.T) {
+	v := &ShiftValidator{}
+
+	tests := []struct {
+		name           string
+		entries        []model.LLMShiftEntry
+		config         map[string]interface{}
+		wantViolations int
+	}{
+		{
+			name: "avoid_together same day",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "09:00", EndTime: "13:00"},
+				{StaffID: "s2", Date: "2025-01-01", StartTime: "17:00", EndTime: "22:00"},
+				{StaffID: "s1", Date: "2025-01-02", StartTime: "09:00", EndTime: "13:00"},
+			},
+			config: map[string]interface{}{
+				"rule":      "avoid_together",
+				"staff_ids": []interface{}{"s1", "s2"},
+			},
+			wantViolations: 1,
+		},
+		{
+			name: "avoid_together overlap scope allows non-overlapping hours",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "09:00", EndTime: "13:00"},
+				{StaffID: "s2", Date: "2025-01-01", StartTime: "13:00", EndTime: "22:00"},
+			},
+			config: map[string]interface{}{
+				"rule":      "avoid_together",
+				"staff_ids": []interface{}{"s1", "s2"},
+				"scope":     "overlap",
+			},
+			wantViolations: 0,
+		},
+		{
+			name: "avoid_together overlap scope flags overlapping hours",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "09:00", EndTime: "14:00"},
+				{StaffID: "s2", Date: "2025-01-01", StartTime: "13:00", EndTime: "22:00"},
+			},
+			config: map[string]interface{}{
+				"rule":      "avoid_together",
+				"staff_ids": []interface{}{"s1", "s2"},
+				"scope":     "overlap",
+			},
+			wantViolations: 1,
+		},
+		{
+			name: "mentor_required satisfied",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "t1", Date: "2025-01-01", StartTime: "10:00", EndTime: "15:00"},
+				{StaffID: "m1", Date: "2025-01-01", StartTime: "09:00", EndTime: "12:00"},
+			},
+			config: map[string]interface{}{
+				"rule":        "mentor_required",
+				"trainee_ids": []interface{}{"t1"},
+				"mentor_ids":  []interface{}{"m1", "m2"},
+			},
+			wantViolations: 0,
+		},
+		{
+			name: "mentor_required without overlapping mentor",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "t1", Date: "2025-01-01", StartTime: "17:00", EndTime: "22:00"},
+				{StaffID: "m1", Date: "2025-01-01", StartTime: "09:00", EndTime: "17:00"},
+				{StaffID: "t1", Date: "2025-01-02", StartTime: "17:00", EndTime: "22:00"},
+			},
+			config: map[string]interface{}{
+				"rule":        "mentor_required",
+				"trainee_ids": []interface{}{"t1"},
+				"mentor_ids":  []interface{}{"m1"},
+			},
+			wantViolations: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := constraintData{
+				Name:     "スタッフ相性",
+				Type:     "hard",
+				Category: "staff_compatibility",
+				Config:   mustMarshalJSON(tt.config),
+			}
+			result := &model.ValidationResult{
+				IsValid:    true,
+				Violations: []model.Violation{},
+			}
+
+			v.checkStaffCompatibility(tt.entries, tt.config, c, result)
+
+			if len(result.Violations) != tt.wantViolations {
+				t.Errorf("got %d violations, want %d", len(result.Violations), tt.wantViolations)
+			}
+			// The other staff involved is recorded so the finding reaches them too
+			for _, violation := range result.Violations {
+				if len(violation.RelatedStaffIDs) == 0 {
+					t.Errorf("violation %q has no related staff", violation.Message)
+				}
+			}
+			if result.IsValid != (tt.wantViolations == 0) {
+				t.Errorf("IsValid = %v, want %v", result.IsValid, tt.wantViolations == 0)
+			}
+		})
+	}
+}
+
+func TestCheckStaffCompatibilityPreferTogether(t *testing.T) {
+	v := &ShiftValidator{}
+
+	tests := []struct {
+		name           string
+		entries        []model.LLMShiftEntry
+		scope          string
+		wantViolations int
+	}{
+		{
+			name: "working together",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "09:00", EndTime: "13:00"},
+				{StaffID: "s2", Date: "2025-01-01", StartTime: "17:00", EndTime: "22:00"},
+			},
+			wantViolations: 0,
+		},
+		{
+			name: "only one of the pair works",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "09:00", EndTime: "13:00"},
+				{StaffID: "s2", Date: "2025-01-01", StartTime: "17:00", EndTime: "22:00"},
+				{StaffID: "s2", Date: "2025-01-02", StartTime: "17:00", EndTime: "22:00"},
+			},
+			wantViolations: 1,
+		},
+		{
+			name:  "overlap scope flags non-overlapping hours",
+			scope: "overlap",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "09:00", EndTime: "13:00"},
+				{StaffID: "s2", Date: "2025-01-01", StartTime: "13:00", EndTime: "22:00"},
+			},
+			wantViolations: 1,
+		},
+		{
+			name:  "overlap scope allows overlapping hours",
+			scope: "overlap",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-01-01", StartTime: "09:00", EndTime: "14:00"},
+				{StaffID: "s2", Date: "2025-01-01", StartTime: "13:00", EndTime: "22:00"},
+			},
+			wantViolations: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]interface{}{
+				"rule":      "prefer_together",
+				"staff_ids": []interface{}{"s1", "s2"},
+			}
+			if tt.scope != "" {
+				config["scope"] = tt.scope
+			}
+			// Even a hard constraint only yields soft violations for a preference
+			c := constraintData{
+				Name:     "スタッフ相性",
+				Type:     "hard",
+				Category: "staff_compatibility",
+				Config:   mustMarshalJSON(config),
+			}
+			result := &model.ValidationResult{
+				IsValid:    true,
+				Violations: []model.Violation{},
+			}
+
+			v.checkStaffCompatibility(tt.entries, config, c, result)
+
+			if len(result.Violations) != tt.wantViolations {
+				t.Errorf("got %d violations, want %d", len(result.Violations), tt.wantViolations)
+			}
+			for _, violation := range result.Violations {
+				if violation.Type != "soft" {
+					t.Errorf("violation type = %q, want soft", violation.Type)
+				}
+			}
+			if !result.IsValid {
+				t.Error("IsValid = false, want true")
+			}
+		})
+	}
+}
+
+// --- checkCoverage tests ---
+
+func TestCheckCoverage(t *testing.T) {
+	v := &ShiftValidator{}
+
+	// Every weekday of the month is checked, so only violations on
+	// 2025-02-03 (Mon) are compared below.
+	cfg := &model.CoverageConfig{
+		SlotMinutes: 30,
+		Weekday: []model.CoverageRequirement{
+			{Start: "11:00", End: "14:00", MinCount: 2},
+			{Start: "18:00", End: "22:00", MinCount: 1},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		entries   []model.LLMShiftEntry
+		wantSlots [][2]string // start/end of violations on 2025-02-03
+	}{
+		{
+			name: "fully covered",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-02-03", StartTime: "10:00", EndTime: "15:00"},
+				{StaffID: "s2", Date: "2025-02-03", StartTime: "11:00", EndTime: "22:00", BreakMinutes: 60},
+			},
+			wantSlots: nil,
+		},
+		{
+			name: "morning heavy, nobody at dinner",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-02-03", StartTime: "09:00", EndTime: "13:00"},
+				{StaffID: "s2", Date: "2025-02-03", StartTime: "09:00", EndTime: "13:00"},
+				{StaffID: "s3", Date: "2025-02-03", StartTime: "09:00", EndTime: "13:00"},
+			},
+			wantSlots: [][2]string{{"13:00", "14:00"}, {"18:00", "22:00"}},
+		},
+		{
+			name: "break leaves a gap",
+			entries: []model.LLMShiftEntry{
+				// break centered: work 11:00-12:00, break 12:00-13:00, work 13:00-14:00
+				{StaffID: "s1", Date: "2025-02-03", StartTime: "11:00", EndTime: "14:00", BreakMinutes: 60},
+				{StaffID: "s2", Date: "2025-02-03", StartTime: "11:00", EndTime: "14:00"},
+				{StaffID: "s3", Date: "2025-02-03", StartTime: "18:00", EndTime: "22:00"},
+			},
+			wantSlots: [][2]string{{"12:00", "13:00"}},
+		},
+		{
+			name: "overnight shift from the previous day covers the morning",
+			entries: []model.LLMShiftEntry{
+				{StaffID: "s1", Date: "2025-02-02", StartTime: "22:00", EndTime: "14:00"},
+				{StaffID: "s2", Date: "2025-02-03", StartTime: "11:00", EndTime: "22:00"},
+			},
+			wantSlots: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := constraintData{Name: "時間帯別人数", Type: "hard", Category: "coverage"}
+			result := &model.ValidationResult{
+				IsValid:    true,
+				Violations: []model.Violation{},
+			}
+
+			v.checkCoverage(tt.entries, cfg, c, "2025-02", result)
+
+			var got [][2]string
+			for _, viol := range result.Violations {
+				if viol.Date == "2025-02-03" {
+					got = append(got, [2]string{viol.StartTime, viol.EndTime})
+				}
+			}
+			if len(got) != len(tt.wantSlots) {
+				t.Fatalf("got violations %v, want %v", got, tt.wantSlots)
+			}
+			for i := range got {
+				if got[i] != tt.wantSlots[i] {
+					t.Errorf("violation[%d] = %v, want %v", i, got[i], tt.wantSlots[i])
+				}
+			}
+			// 2025-02-01 is a Saturday and has no weekend template
+			for _, viol := range result.Violations {
+				if viol.Date == "2025-02-01" {
+					t.Errorf("unexpected violation on weekend: %+v", viol)
+				}
+			}
+		})
+	}
+}
+
+// --- checkBusinessHours tests ---
+
+func TestCheckBusinessHours(t *testing.T) {
+	open, closeAt := "10:00", "20:00"
+	lateOpen, lateClose := "18:00", "03:00"
+	note := "棚卸し"
+	calendar := model.NewBusinessCalendar(
+		[]model.BusinessHours{
+			{Weekday: 1, OpenTime: &open, CloseTime: &closeAt},       // Mon 10-20
+			{Weekday: 2, IsClosed: true},                             // Tue closed
+			{Weekday: 5, OpenTime: &lateOpen, CloseTime: &lateClose}, // Fri 18-03
+		},
+		[]model.BusinessSpecialDay{
+			{Date: "2025-02-10", IsClosed: true, Note: &note}, // Mon, special closure
+		},
+	)
+
+	tests := []struct {
+		name           string
+		entry          model.LLMShiftEntry
+		wantViolations int
+	}{
+		{"within hours", model.LLMShiftEntry{StaffID: "s1", Date: "2025-02-03", StartTime: "10:00", EndTime: "20:00"}, 0},
+		{"starts before open", model.LLMShiftEntry{StaffID: "s1", Date: "2025-02-03", StartTime: "09:00", EndTime: "15:00"}, 1},
+		{"ends after close", model.LLMShiftEntry{StaffID: "s1", Date: "2025-02-03", StartTime: "15:00", EndTime: "21:00"}, 1},
+		{"regular closed weekday", model.LLMShiftEntry{StaffID: "s1", Date: "2025-02-04", StartTime: "10:00", EndTime: "15:00"}, 1},
+		{"special closed date", model.LLMShiftEntry{StaffID: "s1", Date: "2025-02-10", StartTime: "10:00", EndTime: "15:00"}, 1},
+		{"weekday without row uses default hours", model.LLMShiftEntry{StaffID: "s1", Date: "2025-02-05", StartTime: "09:00", EndTime: "22:00"}, 0},
+		{"overnight shift past close", model.LLMShiftEntry{StaffID: "s1", Date: "2025-02-03", StartTime: "18:00", EndTime: "01:00"}, 1},
+		{"overnight shift within late hours", model.LLMShiftEntry{StaffID: "s1", Date: "2025-02-07", StartTime: "20:00", EndTime: "02:00"}, 0},
+		{"after midnight within the previous night's hours", model.LLMShiftEntry{StaffID: "s1", Date: "2025-02-08", StartTime: "00:00", EndTime: "02:00"}, 0},
+		{"after midnight past the previous night's close", model.LLMShiftEntry{StaffID: "s1", Date: "2025-02-08", StartTime: "01:00", EndTime: "04:00"}, 1},
+		{"after midnight on a closed day", model.LLMShiftEntry{StaffID: "s1", Date: "2025-02-04", StartTime: "00:00", EndTime: "02:00"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.ValidationResult{
+				IsValid:    true,
+				Violations: []model.Violation{},
+			}
+
+			checkBusinessHours([]model.LLMShiftEntry{tt.entry}, calendar, result)
+
+			if len(result.Violations) != tt.wantViolations {
+				t.Errorf("got %d violations, want %d", len(result.Violations), tt.wantViolations)
+			}
+			if result.IsValid != (tt.wantViolations == 0) {
+				t.Errorf("IsValid = %v, want %v", result.IsValid, tt.wantViolations == 0)
+			}
+		})
+	}
+}
+
 func mustMarshalJSON(v interface{}) json.RawMessage {
 	b, _ := json.Marshal(v)
 	return b
 }
+
+// --- checkRequestWindows tests ---
+
+func TestCheckRequestWindows(t *testing.T) {
+	v := &ShiftValidator{}
+	requests := map[string]workRequest{
+		"s1:2025-01-06": {Type: "preferred", StartTime: "09:00", EndTime: "15:00"},
+		"s1:2025-01-07": {Type: "available", StartTime: "17:00", EndTime: "02:00"},
+		"s1:2025-01-08": {Type: "available"},
+	}
+
+	tests := []struct {
+		name           string
+		cfg            model.RequestWindowConfig
+		constraintType string
+		entry          model.LLMShiftEntry
+		wantViolations int
+		wantIsValid    bool
+	}{
+		{"inside window", model.RequestWindowConfig{}, "soft", model.LLMShiftEntry{StaffID: "s1", Date: "2025-01-06", StartTime: "09:00", EndTime: "15:00"}, 0, true},
+		{"ends after window", model.RequestWindowConfig{}, "soft", model.LLMShiftEntry{StaffID: "s1", Date: "2025-01-06", StartTime: "10:00", EndTime: "18:00"}, 1, true},
+		{"hard constraint", model.RequestWindowConfig{}, "hard", model.LLMShiftEntry{StaffID: "s1", Date: "2025-01-06", StartTime: "08:00", EndTime: "12:00"}, 1, false},
+		{"inside overnight window", model.RequestWindowConfig{}, "soft", model.LLMShiftEntry{StaffID: "s1", Date: "2025-01-07", StartTime: "18:00", EndTime: "01:00"}, 0, true},
+		{"request type not checked", model.RequestWindowConfig{RequestTypes: []string{"available"}}, "soft", model.LLMShiftEntry{StaffID: "s1", Date: "2025-01-06", StartTime: "10:00", EndTime: "18:00"}, 0, true},
+		{"request without window", model.RequestWindowConfig{}, "soft", model.LLMShiftEntry{StaffID: "s1", Date: "2025-01-08", StartTime: "06:00", EndTime: "22:00"}, 0, true},
+		{"no request", model.RequestWindowConfig{}, "soft", model.LLMShiftEntry{StaffID: "s2", Date: "2025-01-06", StartTime: "06:00", EndTime: "22:00"}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &model.ValidationResult{IsValid: true}
+			c := constraintData{Name: "希望時間帯", Type: tt.constraintType, Category: "request_window"}
+			v.checkRequestWindows([]model.LLMShiftEntry{tt.entry}, &tt.cfg, c, requests, result)
+			if len(result.Violations) != tt.wantViolations {
+				t.Errorf("violations = %d, want %d: %+v", len(result.Violations), tt.wantViolations, result.Violations)
+			}
+			if result.IsValid != tt.wantIsValid {
+				t.Errorf("IsValid = %v, want %v", result.IsValid, tt.wantIsValid)
+			}
+		})
+	}
+}
+
+// --- checkPreferredDays tests ---
+
+func TestCheckPreferredDays(t *testing.T) {
+	entries := []model.LLMShiftEntry{
+		{StaffID: "s1", Date: "2025-01-06", StartTime: "09:00", EndTime: "17:00"},
+		{StaffID: "s2", Date: "2025-01-06", StartTime: "09:00", EndTime: "17:00"},
+	}
+	requests := map[string]workRequest{
+		"s2:2025-01-06": {Type: "preferred"},
+		"s1:2025-01-06": {Type: "preferred"},
+		"s1:2025-01-07": {Type: "preferred"},
+		"s1:2025-01-08": {Type: "available"},
+	}
+
+	result := &model.ValidationResult{IsValid: true}
+	checkPreferredDays(entries, requests, result)
+
+	wantFulfillment := []model.PreferenceFulfillment{
+		{StaffID: "s1", Preferred: 2, Assigned: 1, Rate: 50},
+		{StaffID: "s2", Preferred: 1, Assigned: 1, Rate: 100},
+	}
+	if !reflect.DeepEqual(result.PreferenceFulfillment, wantFulfillment) {
+		t.Errorf("fulfillment = %+v, want %+v", result.PreferenceFulfillment, wantFulfillment)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].StaffID != "s1" || result.Warnings[0].Date != "2025-01-07" {
+		t.Errorf("warnings = %+v, want one for s1 on 2025-01-07", result.Warnings)
+	}
+	if !result.IsValid {
+		t.Error("unmet preferences must not make the result invalid")
+	}
+}
+
+// --- scoring tests ---
+
+func TestScoreComponents(t *testing.T) {
+	settings := map[string]monthlySetting{
+		"s1": {MinHours: 8, MaxHours: 24},
+		"s2": {MinHours: 8, MaxHours: 24},
+	}
+	shift := func(staffID, date string) model.LLMShiftEntry {
+		return model.LLMShiftEntry{StaffID: staffID, Date: date, StartTime: "09:00", EndTime: "17:00"}
+	}
+	// 2025-01-04 and 2025-01-05 are a Saturday and a Sunday
+	tests := []struct {
+		name     string
+		entries  []model.LLMShiftEntry
+		requests map[string]workRequest
+		want     map[string]float64
+	}{
+		{
+			name:    "no shifts",
+			entries: nil,
+			want:    map[string]float64{model.ScoreHoursFit: 0, model.ScorePreference: 100, model.ScoreFairness: 100, model.ScoreCost: 100},
+		},
+		{
+			name:     "even and within minimum",
+			entries:  []model.LLMShiftEntry{shift("s1", "2025-01-04"), shift("s2", "2025-01-05")},
+			requests: map[string]workRequest{"s1:2025-01-04": {Type: "preferred"}, "s2:2025-01-06": {Type: "available"}},
+			want:     map[string]float64{model.ScoreHoursFit: 100, model.ScorePreference: 66.67, model.ScoreFairness: 100, model.ScoreCost: 100},
+		},
+		{
+			name: "uneven and over minimum",
+			entries: []model.LLMShiftEntry{
+				shift("s1", "2025-01-04"), shift("s1", "2025-01-05"), shift("s1", "2025-01-06"), shift("s1", "2025-01-07"),
+				shift("s2", "2025-01-06"),
+			},
+			want: map[string]float64{model.ScoreHoursFit: 83.33, model.ScorePreference: 100, model.ScoreFairness: 0, model.ScoreCost: 40},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown := scoreSchedule(scoreInput{entries: tt.entries, settings: settings, requests: tt.requests}, model.DefaultObjectiveWeights)
+			for _, c := range breakdown.Components {
+				want, ok := tt.want[c.Name]
+				if ok && c.Score != want {
+					t.Errorf("%s = %v, want %v", c.Name, c.Score, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSoftConstraintComponent(t *testing.T) {
+	violations := []model.Violation{
+		{Type: "soft", Constraint: "連勤"},
+		{Type: "soft", Constraint: "連勤"},
+		{Type: "soft", Constraint: "相性"},
+		{Type: "hard", Constraint: "最低人数"},
+	}
+	priorities := map[string]int{"連勤": 0, "相性": 3}
+
+	c := softConstraintComponent(violations, priorities)
+	// 2 x 5 at priority 0, 1 x 20 at priority 3
+	if c.Score != 70 {
+		t.Errorf("score = %v, want 70", c.Score)
+	}
+	want := []model.ScoreDeduction{
+		{Reason: "優先度3のソフト制約違反 1件", Points: 20},
+		{Reason: "優先度0のソフト制約違反 2件", Points: 10},
+	}
+	if !reflect.DeepEqual(c.Deductions, want) {
+		t.Errorf("deductions = %+v, want %+v", c.Deductions, want)
+	}
+}
+
+func TestScoreSchedule_Total(t *testing.T) {
+	in := scoreInput{violations: []model.Violation{{Type: "hard"}, {Type: "soft"}}}
+
+	tests := []struct {
+		name    string
+		weights model.ObjectiveWeights
+		want    float64
+	}{
+		// Every component is 100 except soft constraints at 95
+		{"soft constraints only", model.ObjectiveWeights{SoftConstraints: 1}, 85},
+		{"soft constraints and cost", model.ObjectiveWeights{SoftConstraints: 1, Cost: 1}, 87.5},
+		{"no weights", model.ObjectiveWeights{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown := scoreSchedule(in, tt.weights)
+			if breakdown.HardPenalty != hardViolationPoints {
+				t.Errorf("hard penalty = %v, want %v", breakdown.HardPenalty, hardViolationPoints)
+			}
+			if breakdown.Total != tt.want {
+				t.Errorf("total = %v, want %v", breakdown.Total, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS business_special_days;
+DROP TABLE IF EXISTS business_hours;
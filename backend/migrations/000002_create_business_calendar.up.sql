@@ -0,0 +1,23 @@
+-- business_hours: regular opening hours per weekday (0=Sunday .. 6=Saturday)
+CREATE TABLE business_hours (
+    weekday SMALLINT PRIMARY KEY CHECK (weekday BETWEEN 0 AND 6),
+    open_time TIME,
+    close_time TIME,
+    is_closed BOOLEAN NOT NULL DEFAULT false,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Default: open every day 9:00-22:00
+INSERT INTO business_hours (weekday, open_time, close_time)
+SELECT d, '09:00', '22:00' FROM generate_series(0, 6) AS d;
+
+-- business_special_days: holidays and special hours for specific dates
+CREATE TABLE business_special_days (
+    date DATE PRIMARY KEY,
+    is_closed BOOLEAN NOT NULL DEFAULT false,
+    open_time TIME,
+    close_time TIME,
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
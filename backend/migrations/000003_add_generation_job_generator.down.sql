@@ -0,0 +1,3 @@
+ALTER TABLE generation_jobs
+    DROP COLUMN IF EXISTS seed,
+    DROP COLUMN IF EXISTS generator;
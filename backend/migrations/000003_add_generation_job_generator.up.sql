@@ -0,0 +1,4 @@
+-- generation_jobs: which generator produced the patterns and the seed used
+ALTER TABLE generation_jobs
+    ADD COLUMN generator VARCHAR(20) NOT NULL DEFAULT 'llm',
+    ADD COLUMN seed BIGINT;
@@ -0,0 +1,2 @@
+ALTER TABLE shift_patterns DROP COLUMN IF EXISTS repairs;
+ALTER TABLE shift_entries DROP COLUMN IF EXISTS is_auto_repaired;
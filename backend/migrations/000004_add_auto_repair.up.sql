@@ -0,0 +1,7 @@
+-- shift_entries: entries added or changed by the local repair stage
+ALTER TABLE shift_entries
+    ADD COLUMN is_auto_repaired BOOLEAN NOT NULL DEFAULT false;
+
+-- shift_patterns: log of changes made by the local repair stage
+ALTER TABLE shift_patterns
+    ADD COLUMN repairs JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,23 @@
+-- generation_attempts: every generate call of a job (pattern × retry) with
+-- its prompts, raw LLM output and validation result, for debugging
+CREATE TABLE generation_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job_id UUID NOT NULL REFERENCES generation_jobs(id) ON DELETE CASCADE,
+    pattern_index INTEGER NOT NULL,
+    retry INTEGER NOT NULL,
+    generator VARCHAR(20) NOT NULL,
+    outcome VARCHAR(20) NOT NULL,
+    system_prompt TEXT,
+    llm_calls JSONB NOT NULL DEFAULT '[]',
+    response JSONB,
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    parse_errors JSONB NOT NULL DEFAULT '[]',
+    error_message TEXT,
+    validation JSONB,
+    repairs JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_generation_attempts_job_id ON generation_attempts(job_id, pattern_index, retry);
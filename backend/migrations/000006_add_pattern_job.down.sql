@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_shift_patterns_job_id;
+ALTER TABLE shift_patterns
+    DROP COLUMN IF EXISTS pattern_index,
+    DROP COLUMN IF EXISTS job_id;
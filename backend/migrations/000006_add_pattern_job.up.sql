@@ -0,0 +1,7 @@
+-- shift_patterns: the job and pattern index that produced the pattern, so a
+-- resumed job can skip patterns already saved
+ALTER TABLE shift_patterns
+    ADD COLUMN job_id UUID REFERENCES generation_jobs(id) ON DELETE SET NULL,
+    ADD COLUMN pattern_index INTEGER;
+
+CREATE INDEX idx_shift_patterns_job_id ON shift_patterns(job_id);
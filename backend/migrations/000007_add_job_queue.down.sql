@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_generation_jobs_queue;
+ALTER TABLE generation_jobs
+    DROP COLUMN IF EXISTS heartbeat_at,
+    DROP COLUMN IF EXISTS locked_by;
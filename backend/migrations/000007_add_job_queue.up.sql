@@ -0,0 +1,8 @@
+-- generation_jobs: queue columns. Workers claim pending jobs with
+-- FOR UPDATE SKIP LOCKED and keep heartbeat_at fresh while running; a
+-- processing job with a stale heartbeat belongs to a dead worker.
+ALTER TABLE generation_jobs
+    ADD COLUMN locked_by TEXT,
+    ADD COLUMN heartbeat_at TIMESTAMPTZ;
+
+CREATE INDEX idx_generation_jobs_queue ON generation_jobs(status, created_at);
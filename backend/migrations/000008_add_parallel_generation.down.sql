@@ -0,0 +1,6 @@
+ALTER TABLE shift_patterns
+    DROP COLUMN IF EXISTS diversity_score,
+    DROP COLUMN IF EXISTS strategy;
+
+ALTER TABLE generation_jobs
+    DROP COLUMN IF EXISTS parallel;
@@ -0,0 +1,10 @@
+-- generation_jobs: generate the patterns of a job concurrently, each with
+-- its own strategy hint instead of the previous patterns
+ALTER TABLE generation_jobs
+    ADD COLUMN parallel BOOLEAN NOT NULL DEFAULT false;
+
+-- shift_patterns: the strategy hint the pattern was generated with, and how
+-- much it differs from the other patterns of its job (0-100)
+ALTER TABLE shift_patterns
+    ADD COLUMN strategy VARCHAR(30),
+    ADD COLUMN diversity_score DECIMAL(5,2);
@@ -0,0 +1,3 @@
+ALTER TABLE generation_jobs
+    DROP COLUMN IF EXISTS scope,
+    DROP COLUMN IF EXISTS target_pattern_id;
@@ -0,0 +1,5 @@
+-- generation_jobs: a job with target_pattern_id regenerates part of that
+-- pattern (the entries matching scope) instead of creating new patterns
+ALTER TABLE generation_jobs
+    ADD COLUMN target_pattern_id UUID REFERENCES shift_patterns(id) ON DELETE CASCADE,
+    ADD COLUMN scope JSONB;
@@ -0,0 +1,4 @@
+ALTER TABLE generation_jobs
+    DROP COLUMN IF EXISTS weights,
+    DROP COLUMN IF EXISTS strategies,
+    DROP COLUMN IF EXISTS instruction;
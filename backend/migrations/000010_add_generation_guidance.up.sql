@@ -0,0 +1,7 @@
+-- generation_jobs: what the manager asked of the job besides the month and
+-- pattern count: a free-text instruction, a strategy preset per pattern and
+-- the weights of the scoring objectives
+ALTER TABLE generation_jobs
+    ADD COLUMN instruction TEXT NOT NULL DEFAULT '',
+    ADD COLUMN strategies JSONB,
+    ADD COLUMN weights JSONB;
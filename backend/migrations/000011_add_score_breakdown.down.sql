@@ -0,0 +1,2 @@
+ALTER TABLE shift_patterns
+    DROP COLUMN IF EXISTS score_breakdown;
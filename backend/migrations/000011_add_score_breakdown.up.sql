@@ -0,0 +1,4 @@
+-- shift_patterns: how the score was made up (components, weights and
+-- deductions) so it can be explained
+ALTER TABLE shift_patterns
+    ADD COLUMN score_breakdown JSONB;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS shift_pattern_finalizations;
+DROP INDEX IF EXISTS idx_shift_patterns_one_finalized;
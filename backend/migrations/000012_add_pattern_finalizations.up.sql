@@ -0,0 +1,26 @@
+-- At most one finalized pattern per month. Older duplicates from before
+-- the rule go back to draft.
+UPDATE shift_patterns p SET status = 'draft', updated_at = NOW()
+WHERE p.status = 'finalized'
+  AND EXISTS (
+    SELECT 1 FROM shift_patterns newer
+    WHERE newer.year_month = p.year_month
+      AND newer.status = 'finalized'
+      AND (newer.updated_at, newer.id) > (p.updated_at, p.id)
+  );
+
+CREATE UNIQUE INDEX idx_shift_patterns_one_finalized
+    ON shift_patterns(year_month) WHERE status = 'finalized';
+
+-- shift_pattern_finalizations: who finalized a pattern and when, and the
+-- hard violations they overrode with a reason
+CREATE TABLE shift_pattern_finalizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pattern_id UUID NOT NULL REFERENCES shift_patterns(id) ON DELETE CASCADE,
+    finalized_by VARCHAR(100) NOT NULL DEFAULT '',
+    override_reason TEXT NOT NULL DEFAULT '',
+    overridden_violations JSONB NOT NULL DEFAULT '[]',
+    finalized_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_shift_pattern_finalizations_pattern ON shift_pattern_finalizations(pattern_id);
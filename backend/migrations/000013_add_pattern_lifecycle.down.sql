@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS shift_pattern_status_history;
+
+DROP INDEX IF EXISTS idx_shift_patterns_one_locked;
+UPDATE shift_patterns SET status = 'finalized' WHERE status = 'published';
+UPDATE shift_patterns SET status = 'draft' WHERE status = 'archived';
+CREATE UNIQUE INDEX idx_shift_patterns_one_finalized
+    ON shift_patterns(year_month) WHERE status = 'finalized';
+
+ALTER TABLE shift_patterns DROP CONSTRAINT IF EXISTS chk_shift_patterns_status;
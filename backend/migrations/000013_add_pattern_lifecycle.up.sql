@@ -0,0 +1,24 @@
+-- shift_patterns.status follows the lifecycle
+-- draft → selected → finalized → published → archived
+ALTER TABLE shift_patterns
+    ADD CONSTRAINT chk_shift_patterns_status
+    CHECK (status IN ('draft', 'selected', 'finalized', 'published', 'archived'));
+
+-- A month has at most one locked (finalized or published) pattern
+DROP INDEX IF EXISTS idx_shift_patterns_one_finalized;
+CREATE UNIQUE INDEX idx_shift_patterns_one_locked
+    ON shift_patterns(year_month) WHERE status IN ('finalized', 'published');
+
+-- shift_pattern_status_history: every status change of a pattern, so it
+-- shows when a schedule was locked and reopened
+CREATE TABLE shift_pattern_status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pattern_id UUID NOT NULL REFERENCES shift_patterns(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    changed_by VARCHAR(100) NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_shift_pattern_status_history_pattern ON shift_pattern_status_history(pattern_id, changed_at);
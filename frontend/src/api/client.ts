@@ -7,4 +7,18 @@ const apiClient = axios.create({
   },
 });
 
+// ApiError is the error body of the API ({"error": {...}})
+export interface ApiError {
+  code: string;
+  message: string;
+  details?: Array<{ field: string; message: string }>;
+}
+
+export function apiErrorOf(e: unknown): ApiError | null {
+  if (axios.isAxiosError(e) && e.response?.data?.error) {
+    return e.response.data.error as ApiError;
+  }
+  return null;
+}
+
 export default apiClient;
@@ -1,5 +1,5 @@
 import apiClient from './client';
-import type { GenerationJob, ShiftPattern, ShiftEntry } from '../types';
+import type { EntryValidation, GenerationJob, ShiftPattern, ShiftEntry } from '../types';
 
 export const shiftApi = {
   generate: (yearMonth: string, patternCount: number) =>
@@ -14,10 +14,10 @@ export const shiftApi = {
     apiClient.get<{ pattern: ShiftPattern }>(`/shifts/patterns/${id}`),
   selectPattern: (id: string) =>
     apiClient.put<{ pattern: ShiftPattern }>(`/shifts/patterns/${id}/select`),
-  finalizePattern: (id: string) =>
-    apiClient.put<{ pattern: ShiftPattern }>(`/shifts/patterns/${id}/finalize`),
+  finalizePattern: (id: string, data: { finalized_by?: string; override_reason?: string } = {}) =>
+    apiClient.put<{ pattern: ShiftPattern }>(`/shifts/patterns/${id}/finalize`, data),
   updateEntry: (id: string, data: { start_time: string; end_time: string; break_minutes: number }) =>
-    apiClient.put<{ entry: ShiftEntry; validation: { is_valid: boolean; warnings: Array<{ type: string; message: string }> } }>(
+    apiClient.put<{ entry: ShiftEntry; validation: EntryValidation }>(
       `/shifts/entries/${id}`, data
     ),
   createEntry: (data: {
@@ -27,6 +27,7 @@ export const shiftApi = {
     start_time: string;
     end_time: string;
     break_minutes: number;
-  }) => apiClient.post<ShiftEntry>('/shifts/entries', data),
-  deleteEntry: (id: string) => apiClient.delete(`/shifts/entries/${id}`),
+  }) => apiClient.post<{ entry: ShiftEntry; validation: EntryValidation }>('/shifts/entries', data),
+  deleteEntry: (id: string) =>
+    apiClient.delete<{ validation: EntryValidation }>(`/shifts/entries/${id}`),
 };
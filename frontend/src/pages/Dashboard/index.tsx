@@ -51,6 +51,8 @@ const statusLabels: Record<string, string> = {
   generated: '生成済み',
   selected: '選択済み',
   finalized: '確定済み',
+  published: '公開済み',
+  archived: 'アーカイブ済み',
 };
 
 function heatColor(count: number): string {
@@ -100,8 +102,8 @@ export default function DashboardPage() {
           <div style={styles.cardValue}>
             <span style={{
               ...styles.statusBadge,
-              background: summary?.shift_status === 'finalized' ? '#D1FAE5' : '#E0E7FF',
-              color: summary?.shift_status === 'finalized' ? '#065F46' : '#3730A3',
+              background: summary?.shift_status === 'finalized' || summary?.shift_status === 'published' ? '#D1FAE5' : '#E0E7FF',
+              color: summary?.shift_status === 'finalized' || summary?.shift_status === 'published' ? '#065F46' : '#3730A3',
             }}>
               {statusLabels[summary?.shift_status ?? 'not_started']}
             </span>
@@ -143,7 +145,7 @@ export default function DashboardPage() {
       <div style={styles.actions}>
         <Button onClick={() => navigate('/requests')}>シフト希望を入力</Button>
         <Button onClick={() => navigate('/generate')}>シフトを生成</Button>
-        {summary?.shift_status && ['generated', 'selected', 'finalized'].includes(summary.shift_status) && (
+        {summary?.shift_status && ['generated', 'selected', 'finalized', 'published'].includes(summary.shift_status) && (
           <Button variant="secondary" onClick={() => navigate('/generate')}>PDFを出力</Button>
         )}
       </div>
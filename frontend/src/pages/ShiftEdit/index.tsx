@@ -1,6 +1,7 @@
 import { useEffect, useState, useMemo, useCallback } from 'react';
 import { useParams } from 'react-router-dom';
 import { useShiftStore } from '../../stores/shiftStore';
+import { apiErrorOf } from '../../api/client';
 import type { ShiftEntry, ShiftPattern } from '../../types';
 import Button from '../../components/Common/Button';
 import LoadingSpinner from '../../components/Common/LoadingSpinner';
@@ -46,6 +47,13 @@ const styles: Record<string, React.CSSProperties> = {
 
 const DAY_LABELS = ['日', '月', '火', '水', '木', '金', '土'];
 
+// Statuses whose entries the API refuses to change (409 PATTERN_LOCKED)
+const LOCKED_LABELS: Partial<Record<ShiftPattern['status'], string>> = {
+  finalized: '確定済み',
+  published: '公開済み',
+  archived: 'アーカイブ済み',
+};
+
 function getWeeks(yearMonth: string): string[][] {
   const [y, m] = yearMonth.split('-').map(Number);
   const daysInMonth = new Date(y, m, 0).getDate();
@@ -84,6 +92,7 @@ export default function ShiftEditPage() {
   }, [patternId, fetchPattern]);
 
   const pattern: ShiftPattern | null = currentPattern;
+  const lockedLabel = pattern ? LOCKED_LABELS[pattern.status] : undefined;
   const entries: ShiftEntry[] = pattern?.entries || [];
   const yearMonth = pattern?.year_month || '';
   const weeks = useMemo(() => yearMonth ? getWeeks(yearMonth) : [], [yearMonth]);
@@ -100,7 +109,7 @@ export default function ShiftEditPage() {
   }, [entries]);
 
   const handleCellClick = (entry: ShiftEntry | undefined) => {
-    if (!entry) return;
+    if (!entry || lockedLabel) return;
     setEditing({
       entryId: entry.id,
       start_time: entry.start_time,
@@ -132,8 +141,26 @@ export default function ShiftEditPage() {
     try {
       await finalizePattern(patternId);
       await fetchPattern(patternId);
-    } catch {
-      setWarnings([{ type: 'error', message: '確定に失敗しました' }]);
+    } catch (e: unknown) {
+      const apiError = apiErrorOf(e);
+      if (apiError?.code !== 'HARD_CONSTRAINT_VIOLATIONS') {
+        setWarnings([{ type: 'error', message: apiError?.message || '確定に失敗しました' }]);
+        return;
+      }
+      // Hard violations remain: finalize anyway only with who and why
+      const violations = (apiError.details || []).map((d) => ({ type: 'hard', message: `${d.field}: ${d.message}` }));
+      setWarnings(violations);
+      const finalizedBy = prompt(`ハード制約違反が${violations.length}件あります。違反を承知で確定する場合は確定者を入力してください`);
+      if (!finalizedBy?.trim()) return;
+      const overrideReason = prompt('違反があっても確定する理由を入力してください');
+      if (!overrideReason?.trim()) return;
+      try {
+        await finalizePattern(patternId, { finalized_by: finalizedBy.trim(), override_reason: overrideReason.trim() });
+        setWarnings([]);
+        await fetchPattern(patternId);
+      } catch (e: unknown) {
+        setWarnings([{ type: 'error', message: apiErrorOf(e)?.message || '確定に失敗しました' }]);
+      }
     }
   };
 
@@ -149,7 +176,10 @@ export default function ShiftEditPage() {
       if (!e.start_time || !e.end_time) return;
       const [sh, sm] = e.start_time.split(':').map(Number);
       const [eh, em] = e.end_time.split(':').map(Number);
-      const worked = (eh * 60 + em - sh * 60 - sm - (e.break_minutes || 0)) / 60;
+      let span = eh * 60 + em - (sh * 60 + sm);
+      // An end at or before the start is an overnight shift ending the next day
+      if (span <= 0) span += 24 * 60;
+      const worked = (span - (e.break_minutes || 0)) / 60;
       hours[e.staff_id] = (hours[e.staff_id] || 0) + Math.max(0, worked);
     });
     return hours;
@@ -170,8 +200,8 @@ export default function ShiftEditPage() {
         </div>
         <div style={styles.headerActions}>
           <Button variant="secondary" onClick={handlePDF}>PDF出力</Button>
-          <Button onClick={handleFinalize} disabled={pattern.status === 'finalized'}>
-            {pattern.status === 'finalized' ? '確定済み' : '確定'}
+          <Button onClick={handleFinalize} disabled={!!lockedLabel}>
+            {lockedLabel ?? '確定'}
           </Button>
         </div>
       </div>
@@ -203,7 +233,7 @@ export default function ShiftEditPage() {
                   return (
                     <td
                       key={date}
-                      style={{ ...styles.td, ...(isManual ? styles.tdManual : {}) }}
+                      style={{ ...styles.td, ...(isManual ? styles.tdManual : {}), ...(lockedLabel ? { cursor: 'default' } : {}) }}
                       onClick={() => handleCellClick(entry)}
                     >
                       {entry ? `${entry.start_time}-${entry.end_time}` : '休み'}
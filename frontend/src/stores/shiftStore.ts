@@ -1,5 +1,5 @@
 import { create } from 'zustand';
-import type { ShiftPattern, GenerationJob } from '../types';
+import type { ShiftPattern, GenerationJob, EntryValidation } from '../types';
 import { shiftApi } from '../api/shiftApi';
 
 interface ShiftState {
@@ -13,10 +13,10 @@ interface ShiftState {
   startGeneration: (yearMonth: string, patternCount: number) => Promise<string>;
   pollJobStatus: (jobId: string) => Promise<GenerationJob>;
   selectPattern: (id: string) => Promise<void>;
-  finalizePattern: (id: string) => Promise<void>;
-  updateEntry: (entryId: string, data: { start_time: string; end_time: string; break_minutes: number }) => Promise<{ is_valid: boolean; warnings: Array<{ type: string; message: string }> }>;
-  deleteEntry: (entryId: string) => Promise<void>;
-  createEntry: (data: { pattern_id: string; staff_id: string; date: string; start_time: string; end_time: string; break_minutes: number }) => Promise<void>;
+  finalizePattern: (id: string, data?: { finalized_by?: string; override_reason?: string }) => Promise<void>;
+  updateEntry: (entryId: string, data: { start_time: string; end_time: string; break_minutes: number }) => Promise<EntryValidation>;
+  deleteEntry: (entryId: string) => Promise<EntryValidation>;
+  createEntry: (data: { pattern_id: string; staff_id: string; date: string; start_time: string; end_time: string; break_minutes: number }) => Promise<EntryValidation>;
 }
 
 export const useShiftStore = create<ShiftState>((set) => ({
@@ -59,17 +59,19 @@ export const useShiftStore = create<ShiftState>((set) => ({
   selectPattern: async (id) => {
     await shiftApi.selectPattern(id);
   },
-  finalizePattern: async (id) => {
-    await shiftApi.finalizePattern(id);
+  finalizePattern: async (id, data) => {
+    await shiftApi.finalizePattern(id, data);
   },
   updateEntry: async (entryId, data) => {
     const res = await shiftApi.updateEntry(entryId, data);
     return res.data.validation;
   },
   deleteEntry: async (entryId) => {
-    await shiftApi.deleteEntry(entryId);
+    const res = await shiftApi.deleteEntry(entryId);
+    return res.data.validation;
   },
   createEntry: async (data) => {
-    await shiftApi.createEntry(data);
+    const res = await shiftApi.createEntry(data);
+    return res.data.validation;
   },
 }));
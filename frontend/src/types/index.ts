@@ -66,10 +66,27 @@ export interface ShiftEntry {
   updated_at: string;
 }
 
+export interface ValidationWarning {
+  type: string;
+  constraint?: string;
+  staff_id?: string;
+  date?: string;
+  message: string;
+}
+
+// Findings of a manual entry change that concern its staff and date, and
+// the pattern's score after the change
+export interface EntryValidation {
+  is_valid: boolean;
+  warnings: ValidationWarning[];
+  pattern_score: number;
+  score_breakdown: Record<string, unknown> | null;
+}
+
 export interface ShiftPattern {
   id: string;
   year_month: string;
-  status: 'draft' | 'selected' | 'finalized';
+  status: 'draft' | 'selected' | 'finalized' | 'published' | 'archived';
   reasoning: string;
   score: number;
   constraint_violations: ConstraintViolation[];
@@ -105,7 +122,7 @@ export interface DashboardSummary {
   active_staff_count: number;
   request_submitted_count: number;
   monthly_settings_count: number;
-  shift_status: 'not_started' | 'requests_submitted' | 'generating' | 'generated' | 'selected' | 'finalized';
+  shift_status: 'not_started' | 'requests_submitted' | 'generating' | 'generated' | 'selected' | 'finalized' | 'published' | 'archived';
   constraint_count: number;
   daily_staff_counts: DailyStaffCount[];
 }